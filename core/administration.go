@@ -0,0 +1,37 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"wellness/core/model"
+)
+
+func (app *Application) getDeletionAudits(appID string, orgID string, offset *int64, limit *int64) ([]model.DeletionAudit, error) {
+	audits, err := app.storage.GetDeletionAudits(&appID, &orgID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	//the audits stored before the errors were initialized have none
+	for i := range audits {
+		if audits[i].Errors == nil {
+			audits[i].Errors = []string{}
+		}
+	}
+	return audits, nil
+}
+
+func (app *Application) runDeleteData() error {
+	return app.scheduler.runNow(deleteDataJobName)
+}
@@ -15,9 +15,15 @@
 package core
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
 	"sync"
+	"wellness/core/model"
+	"wellness/driven/storage"
 
+	"github.com/google/uuid"
 	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
 )
 
@@ -29,47 +35,144 @@ type Application struct {
 	logger *logs.Logger
 
 	cacheLock *sync.Mutex
+	//the configs of the apps/orgs by app and org ids, guarded by cacheLock
+	configs map[string]model.AppOrgConfig
+	//the notification templates of the apps/orgs by app and org ids loaded on the first use, guarded by cacheLock
+	templates map[string][]model.NotificationTemplate
+	//changed when the templates cache is cleared, so a load started before does not fill it, guarded by cacheLock
+	templatesGeneration int
 
-	Services Services //expose to the drivers adapters
+	Services       Services       //expose to the drivers adapters
+	Administration Administration //expose to the drivers adapters
 
 	storage       Storage
 	core          Core
 	notifications Notifications
 
-	//TODO - remove this when applied to all environemnts
+	//the app and org the legacy data - created before the multi-tenancy - is assigned to
 	multiTenancyAppID string
 	multiTenancyOrgID string
 
-	deleteDataLogic deleteDataLogic
+	//only report the pending migrations without applying them
+	migrationsDryRun bool
+
+	//probe the reachability of the Core and Notifications BBs on the readiness check
+	readinessProbeBBs bool
+
+	deleteDataLogic *deleteDataLogic
+
+	//cron-like expression of the daily digest checks
+	digestSchedule string
+	//cron-like expression of the ring nudges checks
+	ringNudgesSchedule string
+	//cron-like expression of the overdue todo entries sweeps
+	overdueSchedule string
+
+	locks     *lockManager
+	scheduler *jobScheduler
 }
 
 // Start starts the core part of the application
 func (app *Application) Start() {
-	err := app.deleteDataLogic.start()
+	app.storage.RegisterStorageListener(&storageListener{app: app})
+	err := app.loadConfigs()
+	if err != nil {
+		log.Fatalf("error on loading the configs - %s", err)
+	}
+
+	//the migration tasks reschedule the notifications, so they need the configs
+	err = app.applyMigrations()
+	if err != nil {
+		log.Fatalf("error on applying the migrations - %s", err)
+	}
+
+	err = app.deleteDataLogic.start(app.scheduler)
 	if err != nil {
 		log.Fatalf("error on starting the delete data logic - %s", err)
 	}
+	err = app.scheduler.addJob(digestJobName, app.digestSchedule, "UTC", app.sendDigests)
+	if err != nil {
+		log.Fatalf("error on starting the daily digest - %s", err)
+	}
+	err = app.scheduler.addJob(ringNudgesJobName, app.ringNudgesSchedule, "UTC", app.sendRingNudges)
+	if err != nil {
+		log.Fatalf("error on starting the ring nudges - %s", err)
+	}
+	err = app.scheduler.addJob(overdueJobName, app.overdueSchedule, "UTC", app.sweepOverdueTodoEntries)
+	if err != nil {
+		log.Fatalf("error on starting the overdue sweeper - %s", err)
+	}
+	app.scheduler.start()
+}
 
-	err = app.MigrateMessageIDs()
+func (app *Application) applyMigrations() error {
+	migrated, err := app.locks.runExclusively("migrations", func() error {
+		tasks := map[string]model.MigrationTask{storage.MessageIDsMigrationName: app.migrateMessageIDs}
+		migrations, err := app.storage.ApplyMigrations(app.migrationsDryRun, tasks)
+		for _, item := range migrations {
+			app.logger.Infof("migration %d %s (dry run:%t) - %s", item.Version, item.Name, item.DryRun, item.Summary)
+		}
+		return err
+	})
 	if err != nil {
-		log.Printf("error on migrate message ids - %s", err)
+		return err
+	}
+	if !migrated {
+		app.logger.Info("migrations are being applied by another instance, skipping")
+	}
+	return nil
+}
+
+// Stop stops the background jobs of the application. It waits for the running jobs to finish or until the context is done.
+func (app *Application) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		app.scheduler.stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("error waiting for the background jobs to finish - %s", ctx.Err())
 	}
 }
 
 // NewApplication creates new Application
 func NewApplication(version string, build string,
 	logger *logs.Logger, storage Storage,
-	core Core, notifications Notifications, mtAppID string, mtOrgID string) *Application {
+	core Core, notifications Notifications, mtAppID string, mtOrgID string,
+	deleteDataConfig model.DeleteDataConfig, digestConfig model.DigestConfig,
+	ringNudgesConfig model.RingNudgesConfig, overdueConfig model.OverdueConfig, migrationsDryRun bool, readinessProbeBBs bool) *Application {
 	cacheLock := &sync.Mutex{}
 
-	deleteDataLogic := deleteDataLogic{logger: logger, coreAdapter: core, storage: storage}
+	deleteDataLogic := &deleteDataLogic{logger: logger, coreAdapter: core, storage: storage, notifications: notifications,
+		schedule: deleteDataConfig.Schedule, timezone: deleteDataConfig.Timezone, dryRun: deleteDataConfig.DryRun}
+
+	locks := newLockManager(logger, storage, instanceID())
+	scheduler := newJobScheduler(logger, locks)
 
-	application := Application{version: version, build: build, logger: logger, cacheLock: cacheLock, storage: storage,
+	application := Application{version: version, build: build, logger: logger, cacheLock: cacheLock, configs: map[string]model.AppOrgConfig{},
+		templates: map[string][]model.NotificationTemplate{}, storage: storage,
 		core: core, notifications: notifications, multiTenancyAppID: mtAppID, multiTenancyOrgID: mtOrgID,
-		deleteDataLogic: deleteDataLogic}
+		migrationsDryRun: migrationsDryRun, readinessProbeBBs: readinessProbeBBs, deleteDataLogic: deleteDataLogic,
+		digestSchedule: digestConfig.Schedule, ringNudgesSchedule: ringNudgesConfig.Schedule,
+		overdueSchedule: overdueConfig.Schedule,
+		locks:           locks, scheduler: scheduler}
 
 	// add the drivers ports/interfaces
 	application.Services = &servicesImpl{app: &application}
+	application.Administration = &administrationImpl{app: &application}
 
 	return &application
 }
+
+// instanceID identifies the running service instance, eg. as a lock owner
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "wellness"
+	}
+	return hostname + "-" + uuid.NewString()
+}
@@ -20,116 +20,63 @@ package core
 import (
 	"time"
 	"wellness/core/model"
+	"wellness/utils"
 
+	"github.com/google/uuid"
 	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
 )
 
+const (
+	notificationDeleteAttempts = 3
+	notificationDeleteBackoff  = 2 * time.Second
+
+	deleteDataJobName = "delete_data"
+)
+
 type deleteDataLogic struct {
 	logger *logs.Logger
 
-	storage     Storage
-	coreAdapter Core
+	storage       Storage
+	coreAdapter   Core
+	notifications Notifications
 
-	//delete data timer
-	dailyDeleteTimer *time.Timer
-	timerDone        chan bool
-}
-
-func (d deleteDataLogic) start() error {
+	//cron-like expression and timezone of the processing
+	schedule string
+	timezone string
 
-	// set up web tools timer
-	go d.setupTimerForDelete()
+	//report what would be deleted without deleting anything
+	dryRun bool
 
-	return nil
+	scheduler *jobScheduler
 }
 
-func (d deleteDataLogic) setupTimerForDelete() {
-	d.logger.Info("Delete data timer")
-
-	//cancel if active
-	if d.dailyDeleteTimer != nil {
-		d.logger.Info("setupTimerForDelete -> there is active timer, so cancel it")
-
-		d.timerDone <- true
-		d.dailyDeleteTimer.Stop()
-	}
-
-	//wait until it is the correct moment from the day
-	location, err := time.LoadLocation("America/Chicago")
-	if err != nil {
-		d.logger.Errorf("Error getting location:%s\n", err.Error())
-	}
-	now := time.Now().In(location)
-	d.logger.Infof("setupTimerForDelete -> now - hours:%d minutes:%d seconds:%d\n", now.Hour(), now.Minute(), now.Second())
-
-	nowSecondsInDay := 60*60*now.Hour() + 60*now.Minute() + now.Second()
-	desiredMoment := 14400 //4 AM
-
-	var durationInSeconds int
-	d.logger.Infof("setupTimerForDelete -> nowSecondsInDay:%d desiredMoment:%d\n", nowSecondsInDay, desiredMoment)
-	if nowSecondsInDay <= desiredMoment {
-		d.logger.Infof("setupTimerForDelete -> not delete process today, so the first process will be today")
-		durationInSeconds = desiredMoment - nowSecondsInDay
-	} else {
-		d.logger.Infof("setupTimerForDelete -> the delete process has already been processed today, so the first process will be tomorrow")
-		leftToday := 86400 - nowSecondsInDay
-		durationInSeconds = leftToday + desiredMoment // the time which left today + desired moment from tomorrow
-	}
-	//log.Println(durationInSeconds)
-	//duration := time.Second * time.Duration(3)
-	duration := time.Second * time.Duration(durationInSeconds)
-	d.logger.Infof("setupTimerForDelete -> first call after %s", duration)
-
-	d.dailyDeleteTimer = time.NewTimer(duration)
-	select {
-	case <-d.dailyDeleteTimer.C:
-		d.logger.Info("setupTimerForDelete -> delete timer expired")
-		d.dailyDeleteTimer = nil
-
-		d.process()
-	case <-d.timerDone:
-		// timer aborted
-		d.logger.Info("setupTimerForDelete -> delete timer aborted")
-		d.dailyDeleteTimer = nil
-	}
+func (d *deleteDataLogic) start(scheduler *jobScheduler) error {
+	d.scheduler = scheduler
+	return scheduler.addJob(deleteDataJobName, d.schedule, d.timezone, d.processDelete)
 }
 
-func (d deleteDataLogic) process() {
+// processDelete deletes the data of the users whose memberships were deleted in the Core BB
+func (d *deleteDataLogic) processDelete() {
 	d.logger.Info("Deleting data process")
 
-	//process work
-	d.processDelete()
-
-	//generate new processing after 24 hours
-	duration := time.Hour * 24
-	d.logger.Infof("Deleting data process -> next call after %s", duration)
-	d.dailyDeleteTimer = time.NewTimer(duration)
-	select {
-	case <-d.dailyDeleteTimer.C:
-		d.logger.Info("Deleting data process -> timer expired")
-		d.dailyDeleteTimer = nil
-
-		d.process()
-	case <-d.timerDone:
-		// timer aborted
-		d.logger.Info("Deleting data process -> timer aborted")
-		d.dailyDeleteTimer = nil
-	}
-}
-
-func (d deleteDataLogic) processDelete() {
-
 	//load deleted accounts
 	deletedMemberships, err := d.coreAdapter.LoadDeletedMemberships()
 
 	if err != nil {
 		d.logger.Errorf("error on loading deleted accounts - %s", err)
+		utils.ObserveDeleteDataRun(nil, true)
 		return
 	}
 
 	//process by app org
 
 	for _, appOrgSection := range deletedMemberships {
+		if d.scheduler.stopping() {
+			//do not start a new app/org on shutdown, the remaining ones are processed on the next run
+			d.logger.Info("the service is stopping, the remaining apps/orgs are left for the next run")
+			return
+		}
+
 		d.logger.Infof("delete - [app-id:%s org-id:%s]", appOrgSection.AppID, appOrgSection.OrgID)
 
 		accountsIDs := d.getAccountsIDs(appOrgSection.Memberships)
@@ -138,54 +85,146 @@ func (d deleteDataLogic) processDelete() {
 			continue
 		}
 
-		d.logger.Infof("accounts for deletion - %s", accountsIDs)
+		d.logger.Infof("accounts for deletion - %d (dry run:%t)", len(accountsIDs), d.dryRun)
 
 		//delete the data
-		d.deleteAppOrgUsersData(appOrgSection.AppID, appOrgSection.OrgID, accountsIDs)
+		audit := d.deleteAppOrgUsersData(appOrgSection.AppID, appOrgSection.OrgID, accountsIDs)
+		if d.dryRun {
+			//nothing is deleted in dry run mode
+			utils.ObserveDeleteDataRun(nil, len(audit.Errors) > 0)
+		} else {
+			utils.ObserveDeleteDataRun(audit.DeletedCounts, len(audit.Errors) > 0)
+		}
+
+		//keep a durable record for the run
+		err = d.storage.CreateDeletionAudit(audit)
+		if err != nil {
+			d.logger.Errorf("error storing deletion audit for [app-id:%s org-id:%s] - %s", appOrgSection.AppID, appOrgSection.OrgID, err)
+		}
 	}
 }
 
-func (d deleteDataLogic) deleteAppOrgUsersData(appID string, orgID string, accountsIDs []string) {
-
-	// delete the todo categories
-	err := d.storage.DeleteTodoCategoriesForUsers(appID, orgID, accountsIDs)
+func (d *deleteDataLogic) deleteAppOrgUsersData(appID string, orgID string, accountsIDs []string) (audit model.DeletionAudit) {
+	started := time.Now().UTC()
+	audit = model.DeletionAudit{ID: uuid.NewString(), AppID: appID, OrgID: orgID, DryRun: d.dryRun,
+		AccountsCount: len(accountsIDs), DeletedCounts: map[string]int64{}, Errors: []string{}, DateCreated: started}
+	defer func() {
+		//the audit is the named result, so the duration is set on the returned value
+		audit.Duration = time.Since(started).Milliseconds()
+	}()
+
+	// cancel the pending notifications before the todo entries which keep their message ids are removed
+	cancelled, failed, err := d.cancelPendingNotifications(appID, orgID, accountsIDs)
+	audit.DeletedCounts[model.DeletionAuditNotifications] = cancelled
+	audit.NotificationFailures = failed
 	if err != nil {
-		d.logger.Errorf("error deleting todo categories for users - %s", err)
-		return
+		d.logger.Errorf("error cancelling pending notifications for users - %s", err)
+		audit.Errors = append(audit.Errors, err.Error())
+		return audit
 	}
 
-	// delete the todo entries
-	err = d.storage.DeleteTodoEntriesForUsers(appID, orgID, accountsIDs)
-	if err != nil {
-		d.logger.Errorf("error deleting todo entries for users - %s", err)
-		return
+	steps := []struct {
+		collection string
+		delete     func(appID string, orgID string, accountsIDs []string) (int64, error)
+		count      func(appID string, orgID string, accountsIDs []string) (int64, error)
+	}{
+		{model.DeletionAuditTodoCategories, d.storage.DeleteTodoCategoriesForUsers, d.storage.CountTodoCategoriesForUsers},
+		{model.DeletionAuditTodoEntries, d.storage.DeleteTodoEntriesForUsers, d.storage.CountTodoEntriesForUsers},
+		{model.DeletionAuditTodoTemplates, d.storage.DeleteTodoTemplatesForUsers, d.storage.CountTodoTemplatesForUsers},
+		{model.DeletionAuditRings, d.storage.DeleteRingsForUsers, d.storage.CountRingsForUsers},
+		{model.DeletionAuditRingsRecords, d.storage.DeleteRingsRecordsForUsers, d.storage.CountRingsRecordsForUsers},
+		{model.DeletionAuditRingNudges, d.storage.DeleteRingNudgesForUsers, d.storage.CountRingNudgesForUsers},
+		{model.DeletionAuditUserPreferences, d.storage.DeleteUserPreferencesForUsers, d.storage.CountUserPreferencesForUsers},
 	}
+	for _, step := range steps {
+		action := step.delete
+		if d.dryRun {
+			action = step.count
+		}
 
-	// delete the rings
-	err = d.storage.DeleteRingsForUsers(appID, orgID, accountsIDs)
-	if err != nil {
-		d.logger.Errorf("error deleting rings for users - %s", err)
-		return
+		count, err := action(appID, orgID, accountsIDs)
+		if err != nil {
+			d.logger.Errorf("error deleting %s for users - %s", step.collection, err)
+			audit.Errors = append(audit.Errors, err.Error())
+			return audit
+		}
+		audit.DeletedCounts[step.collection] = count
 	}
 
-	// delete the rings records
-	err = d.storage.DeleteRingsForUsers(appID, orgID, accountsIDs)
+	return audit
+}
+
+// cancelPendingNotifications deletes all scheduled reminder messages of the users' todo entries.
+// A message which cannot be deleted after all attempts does not block the data deletion - it is stored as a failure instead.
+// It gives the number of the cancelled messages (the ones which would be cancelled in dry run mode) and the number of the failures.
+func (d *deleteDataLogic) cancelPendingNotifications(appID string, orgID string, accountsIDs []string) (int64, int, error) {
+	todoEntries, err := d.storage.GetTodoEntriesForUsers(appID, orgID, accountsIDs)
 	if err != nil {
-		d.logger.Errorf("error deleting rings records for users - %s", err)
-		return
+		return 0, 0, err
 	}
+
+	now := time.Now()
+	var cancelled int64
+	var failures []model.NotificationDeleteFailure
+	for _, todo := range todoEntries {
+		scheduled := []struct {
+			messageID *string
+			sendTime  *time.Time
+		}{
+			{todo.MessageIDs.DueDateMessageID, todo.SendTime(model.ReminderKindDue)},
+			{todo.MessageIDs.ReminderDateMessageID, todo.SendTime(model.ReminderKindReminder)},
+		}
+		for _, item := range scheduled {
+			messageID := item.messageID
+			if messageID == nil || item.sendTime == nil || !item.sendTime.After(now) {
+				//not scheduled or already sent
+				continue
+			}
+
+			if d.dryRun {
+				cancelled++
+				continue
+			}
+
+			err = d.deleteNotificationWithRetry(appID, orgID, *messageID)
+			if err != nil {
+				d.logger.Errorf("error deleting notification %s for todo entry %s - %s", *messageID, todo.ID, err)
+				failures = append(failures, model.NotificationDeleteFailure{ID: uuid.NewString(), AppID: appID, OrgID: orgID,
+					UserID: todo.UserID, TodoEntryID: todo.ID, MessageID: *messageID, Error: err.Error(), DateCreated: time.Now().UTC()})
+				continue
+			}
+			cancelled++
+		}
+	}
+
+	if len(failures) > 0 {
+		d.logger.Infof("%d notifications could not be deleted for [app-id:%s org-id:%s]", len(failures), appID, orgID)
+		err = d.storage.CreateNotificationDeleteFailures(failures)
+		if err != nil {
+			d.logger.Errorf("error storing notification delete failures - %s", err)
+		}
+	}
+	return cancelled, len(failures), nil
 }
 
-func (d deleteDataLogic) getAccountsIDs(memberships []model.DeletedMembership) []string {
+func (d *deleteDataLogic) deleteNotificationWithRetry(appID string, orgID string, messageID string) error {
+	var err error
+	for attempt := 1; attempt <= notificationDeleteAttempts; attempt++ {
+		err = d.notifications.DeleteNotification(appID, orgID, messageID)
+		if err == nil {
+			return nil
+		}
+		if attempt < notificationDeleteAttempts {
+			time.Sleep(notificationDeleteBackoff * time.Duration(attempt))
+		}
+	}
+	return err
+}
+
+func (d *deleteDataLogic) getAccountsIDs(memberships []model.DeletedMembership) []string {
 	res := make([]string, len(memberships))
 	for i, item := range memberships {
 		res[i] = item.AccountID
 	}
 	return res
 }
-
-// deleteLogic creates new deleteLogic
-func deleteLogic( /*coreAdapter Core,*/ logger *logs.Logger) deleteDataLogic {
-	timerDone := make(chan bool)
-	return deleteDataLogic{ /*coreAdapter: coreAdapter,*/ timerDone: timerDone, logger: logger}
-}
@@ -0,0 +1,193 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"log"
+	"slices"
+	"time"
+	"wellness/core/model"
+	"wellness/driven/storage"
+
+	"github.com/google/uuid"
+)
+
+// notificationBatch collects the notifications changes of the todo entries changed in a transaction. The replaced
+// messages are deleted once the transaction is committed and the new messages are deleted if it fails, so a failed
+// transaction keeps the reminders of its entries. A nil batch deletes the messages right away.
+type notificationBatch struct {
+	deletes []batchMessage
+	creates []batchMessage
+}
+
+// batchMessage a message of a todo entry
+type batchMessage struct {
+	todoEntryID string
+	messageID   string
+}
+
+// deleteNotification deletes the message now, or when the batch is committed
+func (b *notificationBatch) deleteNotification(notifications Notifications, appID string, orgID string, todoEntryID string, messageID string) error {
+	if b == nil {
+		return notifications.DeleteNotification(appID, orgID, messageID)
+	}
+	b.deletes = append(b.deletes, batchMessage{todoEntryID: todoEntryID, messageID: messageID})
+	return nil
+}
+
+// created records a message scheduled in the transaction of the batch
+func (b *notificationBatch) created(todoEntryID string, messageID *string) {
+	if b == nil || messageID == nil {
+		return
+	}
+	b.creates = append(b.creates, batchMessage{todoEntryID: todoEntryID, messageID: *messageID})
+}
+
+// finish deletes the replaced messages when the transaction is committed, otherwise the messages scheduled in it. The
+// replaced messages which cannot be deleted are stored as failures.
+func (b *notificationBatch) finish(app *Application, appID string, orgID string, userID string, committed bool) {
+	if !committed {
+		for _, item := range b.creates {
+			err := app.notifications.DeleteNotification(appID, orgID, item.messageID)
+			if err != nil {
+				log.Printf("Error on deleting the notification %s of the rolled back todo entry %s - %s", item.messageID, item.todoEntryID, err)
+			}
+		}
+		return
+	}
+
+	var failures []model.NotificationDeleteFailure
+	for _, item := range b.deletes {
+		err := app.notifications.DeleteNotification(appID, orgID, item.messageID)
+		if err != nil {
+			log.Printf("Error on deleting the notification %s of the todo entry %s - %s", item.messageID, item.todoEntryID, err)
+			failures = append(failures, model.NotificationDeleteFailure{ID: uuid.NewString(), AppID: appID, OrgID: orgID,
+				UserID: userID, TodoEntryID: item.todoEntryID, MessageID: item.messageID, Error: err.Error(), DateCreated: time.Now().UTC()})
+		}
+	}
+	if len(failures) > 0 {
+		err := app.storage.CreateNotificationDeleteFailures(failures)
+		if err != nil {
+			log.Printf("Error on storing the notification delete failures - %s", err)
+		}
+	}
+}
+
+// bulkTodoEntries applies the action to all the todo entries in one transaction. The entries which are not found or to
+// which the action cannot be applied are reported in their results without failing the others.
+func (app *Application) bulkTodoEntries(appID string, orgID string, userID string, operation model.BulkTodoOperation) ([]model.BulkTodoResult, error) {
+	var category *model.CategoryRef
+	switch operation.Action {
+	case model.BulkTodoActionMove:
+		if operation.CategoryID != nil {
+			item, err := app.getTodoCategory(appID, orgID, userID, *operation.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			ref := item.ToCategoryRef()
+			category = &ref
+		}
+	case model.BulkTodoActionReschedule:
+		if operation.DueDateTime == nil {
+			return nil, model.NewFieldsValidationError(model.FieldError{Field: "due_date_time", Message: "is required"})
+		}
+	}
+
+	var results []model.BulkTodoResult
+	batch := &notificationBatch{}
+	preferences := app.userPreferences(appID, orgID, userID)
+	_, location := app.preferencesLocale(appID, orgID, userID, preferences)
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		results = make([]model.BulkTodoResult, 0, len(operation.IDs))
+		now := time.Now()
+		for i, id := range operation.IDs {
+			if slices.Contains(operation.IDs[:i], id) {
+				continue
+			}
+			result := model.BulkTodoResult{ID: id, Status: model.BulkTodoStatusOK}
+			current, err := app.storage.GetTodoEntry(context, appID, orgID, userID, id)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				result.Status = model.BulkTodoStatusNotFound
+				results = append(results, result)
+				continue
+			}
+
+			if operation.Action == model.BulkTodoActionDelete {
+				for _, messageID := range []*string{current.MessageIDs.DueDateMessageID, current.MessageIDs.ReminderDateMessageID} {
+					if messageID != nil {
+						batch.deleteNotification(app.notifications, appID, orgID, id, *messageID)
+					}
+				}
+				err = app.storage.DeleteTodoEntry(context, appID, orgID, userID, id)
+				if err != nil {
+					return err
+				}
+				results = append(results, result)
+				continue
+			}
+
+			todo := *current
+			switch operation.Action {
+			case model.BulkTodoActionComplete:
+				todo.Completed = true
+			case model.BulkTodoActionUncomplete:
+				todo.Completed = false
+			case model.BulkTodoActionMove:
+				todo.Category = category
+			case model.BulkTodoActionReschedule:
+				rescheduleTodo(&todo, *operation.DueDateTime, operation.ReminderDateTime)
+				err = validateReminderDateTime(todo.ReminderDateTime, current.ReminderDateTime)
+				if err != nil {
+					result.Status = model.BulkTodoStatusInvalid
+					result.Error = err.Error()
+					results = append(results, result)
+					continue
+				}
+				err = app.rescheduleTodoEntryReminders(appID, orgID, userID, preferences, id, current, &todo, batch)
+				if err != nil {
+					return err
+				}
+			}
+			todo.Overdue = todo.IsOverdue(now, location)
+
+			result.Entry, err = app.storage.UpdateTodoEntry(context, appID, orgID, userID, &todo, id)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	batch.finish(app, appID, orgID, userID, err == nil)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// rescheduleTodo sets the due time of the entry. Without a reminder time the reminder keeps its offset to the due time.
+func rescheduleTodo(todo *model.TodoEntry, due time.Time, reminder *time.Time) {
+	if reminder == nil && todo.ReminderDateTime != nil && todo.DueDateTime != nil {
+		moved := todo.ReminderDateTime.Add(due.Sub(*todo.DueDateTime))
+		reminder = &moved
+	}
+	if reminder != nil {
+		todo.ReminderDateTime = reminder
+	}
+	todo.DueDateTime = &due
+}
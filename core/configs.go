@@ -0,0 +1,158 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"strings"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+// storageListener reloads the configs cache when the storage notifies the configs changes
+type storageListener struct {
+	app *Application
+}
+
+// OnConfigsUpdated is called when the configs are changed - by this or another instance
+func (l *storageListener) OnConfigsUpdated() {
+	err := l.app.loadConfigs()
+	if err != nil {
+		l.app.logger.Errorf("error on reloading the configs - %s", err)
+	}
+}
+
+// OnNotificationTemplatesUpdated is called when the notification templates are changed - by this or another instance
+func (l *storageListener) OnNotificationTemplatesUpdated() {
+	l.app.clearNotificationTemplates()
+}
+
+// loadConfigs replaces the configs cache with the stored configs
+func (app *Application) loadConfigs() error {
+	configs, err := app.storage.GetAppOrgConfigs()
+	if err != nil {
+		return err
+	}
+
+	cache := make(map[string]model.AppOrgConfig, len(configs))
+	for _, config := range configs {
+		cache[configKey(config.AppID, config.OrgID)] = config
+	}
+
+	app.cacheLock.Lock()
+	defer app.cacheLock.Unlock()
+
+	app.configs = cache
+	app.logger.Infof("loaded %d app/org configs", len(cache))
+	return nil
+}
+
+// cacheConfig sets the config of an app/org in the cache without waiting for the change stream
+func (app *Application) cacheConfig(appID string, orgID string, config *model.AppOrgConfig) {
+	app.cacheLock.Lock()
+	defer app.cacheLock.Unlock()
+
+	if config == nil {
+		delete(app.configs, configKey(appID, orgID))
+		return
+	}
+	app.configs[configKey(appID, orgID)] = *config
+}
+
+// getConfigSettings gives the cached settings of an app/org, the defaults when the app/org has no config
+func (app *Application) getConfigSettings(appID string, orgID string) model.ConfigSettings {
+	app.cacheLock.Lock()
+	defer app.cacheLock.Unlock()
+
+	config, ok := app.configs[configKey(appID, orgID)]
+	if !ok {
+		return model.ConfigSettings{}
+	}
+	return config.Settings
+}
+
+// defaultReminderContent gives the title and the text of the reminders of a todo entry when the app/org has no template
+// for the language of the user
+func defaultReminderContent(settings model.ConfigSettings, todo *model.TodoEntry) (string, string) {
+	title := settings.NotificationTitle
+	if title == "" {
+		title = model.DefaultNotificationTitle
+	}
+	text := todo.Title
+	if settings.ReminderText != "" {
+		text = strings.ReplaceAll(settings.ReminderText, model.ReminderTextTitlePlaceholder, todo.Title)
+	}
+	return title, text
+}
+
+func (app *Application) getAppOrgConfig(appID string, orgID string) (*model.AppOrgConfig, error) {
+	config, err := app.storage.GetAppOrgConfig(appID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, model.NewNotFoundError("config", appID+"/"+orgID)
+	}
+	return config, nil
+}
+
+func (app *Application) createAppOrgConfig(appID string, orgID string, settings model.ConfigSettings) (*model.AppOrgConfig, error) {
+	config := model.AppOrgConfig{ID: uuid.NewString(), AppID: appID, OrgID: orgID, Settings: settings, DateCreated: time.Now().UTC()}
+	err := app.storage.InsertAppOrgConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	app.cacheConfig(appID, orgID, &config)
+	return &config, nil
+}
+
+func (app *Application) updateAppOrgConfig(appID string, orgID string, settings model.ConfigSettings) (*model.AppOrgConfig, error) {
+	config, err := app.getAppOrgConfig(appID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	config.Settings = settings
+	config.DateUpdated = &now
+	err = app.storage.UpdateAppOrgConfig(*config)
+	if err != nil {
+		return nil, err
+	}
+
+	app.cacheConfig(appID, orgID, config)
+	return config, nil
+}
+
+func (app *Application) deleteAppOrgConfig(appID string, orgID string) error {
+	_, err := app.getAppOrgConfig(appID, orgID)
+	if err != nil {
+		return err
+	}
+
+	err = app.storage.DeleteAppOrgConfig(appID, orgID)
+	if err != nil {
+		return err
+	}
+
+	app.cacheConfig(appID, orgID, nil)
+	return nil
+}
+
+func configKey(appID string, orgID string) string {
+	return appID + "_" + orgID
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"wellness/core/model"
+)
+
+const (
+	digestJobName = "daily_digest"
+
+	//how many users the daily digest reads at once
+	digestBatchSize = 500
+
+	//the title of the daily digest notification when the app/org has no digest template for the language of the user
+	digestTitle = "Your Daily Wellness Digest"
+)
+
+// sendDigests sends the daily digest to the users whose digest time has come today. A user gets one digest a day even
+// when the job runs many times or on many instances. The users are read in batches.
+func (app *Application) sendDigests() {
+	now := time.Now()
+	sent := 0
+	afterID := ""
+	for {
+		preferences, err := app.storage.GetUserPreferencesWithDigest(afterID, digestBatchSize)
+		if err != nil {
+			app.logger.Errorf("error on getting the users with a daily digest - %s", err)
+			break
+		}
+
+		for _, item := range preferences {
+			if app.scheduler.stopping() {
+				app.logger.Infof("daily digest - stopping after %d digests, the remaining users get the digest on the next run", sent)
+				return
+			}
+
+			ok, err := app.sendDigest(item, now)
+			if err != nil {
+				app.logger.Errorf("error on sending the daily digest to %s - %s", item.UserID, err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+
+		if int64(len(preferences)) < digestBatchSize {
+			break
+		}
+		afterID = preferences[len(preferences)-1].ID
+	}
+	app.logger.Infof("daily digest - sent %d digests", sent)
+}
+
+// sendDigest sends the digest of the day to the user if the digest is on for the app/org, the digest time has come and the
+// digest was not sent yet. It gives true if the digest was sent.
+func (app *Application) sendDigest(preferences model.UserPreferences, now time.Time) (bool, error) {
+	if !app.getConfigSettings(preferences.AppID, preferences.OrgID).IsFeatureEnabled(model.FeatureDigest) {
+		return false, nil
+	}
+	appID, orgID, userID := preferences.AppID, preferences.OrgID, preferences.UserID
+	language, location := app.preferencesLocale(appID, orgID, userID, preferences)
+	localNow := now.In(location)
+	today := localNow.Format(ringSummaryDateLayout)
+	if preferences.LastDigestDate == today {
+		return false, nil
+	}
+
+	digestTime, err := time.Parse(quietHoursLayout, preferences.DigestTime)
+	if err != nil {
+		return false, fmt.Errorf("invalid digest time %s", preferences.DigestTime)
+	}
+	dayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, location)
+	if localNow.Before(dayStart.Add(time.Duration(digestTime.Hour())*time.Hour + time.Duration(digestTime.Minute())*time.Minute)) {
+		return false, nil
+	}
+
+	data, err := app.digestData(preferences, localNow, dayStart)
+	if err != nil {
+		return false, err
+	}
+
+	//mark the digest before sending it, so a failed mark never leads to a second digest the same day
+	marked, err := app.storage.MarkDigestSent(preferences.ID, today)
+	if err != nil {
+		return false, err
+	}
+	if !marked || (data.DueToday == 0 && data.Overdue == 0 && len(data.RingsBehind) == 0) {
+		return false, nil
+	}
+	title, text := app.digestContent(appID, orgID, language, data)
+
+	topic := "daily digest"
+	notificationData := map[string]string{
+		"type":        "wellness_digest",
+		"operation":   "daily_digest",
+		"entity_type": "wellness_digest",
+		"entity_id":   today,
+	}
+	_, err = app.notifications.SendNotification([]model.NotificationRecipient{{UserID: userID}}, &topic,
+		title, text, appID, orgID, nil, notificationData)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// digestData counts the todo entries due today and the overdue ones, and gives the rings behind the goal today
+func (app *Application) digestData(preferences model.UserPreferences, localNow time.Time, dayStart time.Time) (model.DigestTemplateData, error) {
+	appID, orgID, userID := preferences.AppID, preferences.OrgID, preferences.UserID
+	data := model.DigestTemplateData{Date: localNow.Format(ringSummaryDateLayout)}
+
+	entries, err := app.storage.GetTodoEntriesDueBefore(appID, orgID, userID, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return data, err
+	}
+	for _, entry := range entries {
+		if entry.IsOverdue(localNow, localNow.Location()) {
+			data.Overdue++
+		} else {
+			data.DueToday++
+		}
+	}
+
+	data.RingsBehind, err = app.ringsBehindGoal(appID, orgID, userID, dayStart, localNow)
+	if err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+// digestContent gives the title and the text of the digest. It renders the digest template of the app/org in the language
+// of the user, falling back to the language of the app/org, to English and at last to the built-in English text.
+func (app *Application) digestContent(appID string, orgID string, language string, data model.DigestTemplateData) (string, string) {
+	subject, body, ok := app.renderTemplateOfKind(appID, orgID, model.TemplateKindDigest, language, data)
+	if !ok {
+		return digestTitle, defaultDigestText(data)
+	}
+	return subject, body
+}
+
+// defaultDigestText summarizes the todo entries due today, the overdue ones and the rings behind the goal today in English
+func defaultDigestText(data model.DigestTemplateData) string {
+	parts := []string{}
+	if data.DueToday > 0 {
+		noun := "to-dos"
+		if data.DueToday == 1 {
+			noun = "to-do"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s due today", data.DueToday, noun))
+	}
+	if data.Overdue > 0 {
+		parts = append(parts, fmt.Sprintf("%d overdue", data.Overdue))
+	}
+	if len(data.RingsBehind) > 0 {
+		parts = append(parts, "behind on "+strings.Join(data.RingsBehind, ", "))
+	}
+	return strings.Join(parts, "; ") + "."
+}
+
+// ringsBehindGoal gives the names of the rings whose total of the day is below the goal of the day
+func (app *Application) ringsBehindGoal(appID string, orgID string, userID string, dayStart time.Time, at time.Time) ([]string, error) {
+	rings, err := app.storage.GetRings(appID, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rings) == 0 {
+		return nil, nil
+	}
+
+	totals, err := app.ringTotals(appID, orgID, userID, dayStart)
+	if err != nil {
+		return nil, err
+	}
+
+	behind := []string{}
+	for i := range rings {
+		goal := rings[i].EffectiveHistoryEntry(at)
+		if goal != nil && totals[rings[i].ID] < goal.Value {
+			behind = append(behind, goal.Name)
+		}
+	}
+	return behind, nil
+}
+
+// ringTotals gives the totals of the rings of a user since the start of the day by ring id
+func (app *Application) ringTotals(appID string, orgID string, userID string, dayStart time.Time) (map[string]float64, error) {
+	startEpoch := dayStart.UnixMilli()
+	records, err := app.storage.GetRingsRecords(appID, orgID, userID, nil, &startEpoch, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	totals := map[string]float64{}
+	for _, record := range records {
+		totals[record.RingID] += record.Value
+	}
+	return totals, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sync"
+	"time"
+	"wellness/core/model"
+)
+
+// dependencyCheck checks a dependency of the service
+type dependencyCheck struct {
+	name  string
+	check func() error
+}
+
+// getReadiness checks all the dependencies of the service at the same time. The service is ready when all of them work.
+func (app *Application) getReadiness() model.Readiness {
+	checks := []dependencyCheck{
+		{"database", app.storage.Ping},
+		{"service_account", app.core.CheckServiceAccount},
+	}
+	if app.readinessProbeBBs {
+		checks = append(checks, dependencyCheck{"core_bb", app.core.CheckReachability},
+			dependencyCheck{"notifications_bb", app.notifications.CheckReachability})
+	}
+
+	dependencies := make([]model.DependencyStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, item := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dependencies[i] = runDependencyCheck(item)
+		}()
+	}
+	wg.Wait()
+
+	status := model.HealthStatusUp
+	for _, dependency := range dependencies {
+		if dependency.Status != model.HealthStatusUp {
+			status = model.HealthStatusDown
+			app.logger.Errorf("dependency %s is down - %s", dependency.Name, *dependency.Error)
+		}
+	}
+	return model.Readiness{Status: status, Dependencies: dependencies}
+}
+
+func runDependencyCheck(item dependencyCheck) model.DependencyStatus {
+	started := time.Now()
+	err := item.check()
+	result := model.DependencyStatus{Name: item.name, Status: model.HealthStatusUp, Duration: time.Since(started).Milliseconds()}
+	if err != nil {
+		message := err.Error()
+		result.Status = model.HealthStatusDown
+		result.Error = &message
+	}
+	return result
+}
@@ -15,6 +15,7 @@
 package core
 
 import (
+	"context"
 	"time"
 	"wellness/core/model"
 	"wellness/driven/storage"
@@ -23,17 +24,20 @@ import (
 // Services exposes APIs for the driver adapters
 type Services interface {
 	GetVersion() string
+	GetReadiness() model.Readiness
 
 	GetTodoCategories(appID string, orgID string, userID string) ([]model.TodoCategory, error)
 	GetTodoCategory(appID string, orgID string, userID string, id string) (*model.TodoCategory, error)
 	CreateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error)
 	UpdateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error)
+	PatchTodoCategory(appID string, orgID string, userID string, id string, patch func(current model.TodoCategory) (*model.TodoCategory, error)) (*model.TodoCategory, error)
 	DeleteTodoCategory(appID string, orgID string, userID string, id string) error
 
 	GetTodoEntries(appID string, orgID string, userID string) ([]model.TodoEntry, error)
 	GetTodoEntry(appID string, orgID string, userID string, id string) (*model.TodoEntry, error)
 	CreateTodoEntry(appID string, orgID string, userID string, todo *model.TodoEntry) (*model.TodoEntry, error)
 	UpdateTodoEntry(appID string, orgID string, userID string, todo *model.TodoEntry, id string) (*model.TodoEntry, error)
+	PatchTodoEntry(appID string, orgID string, userID string, id string, patch func(current model.TodoEntry) (*model.TodoEntry, error)) (*model.TodoEntry, error)
 	DeleteTodoEntry(appID string, orgID string, userID string, id string) error
 	DeleteCompletedTodoEntries(appID string, orgID string, userID string) error
 
@@ -51,6 +55,107 @@ type Services interface {
 	DeleteRingsRecords(appID string, orgID string, userID string, ringID *string, recordID *string) error
 
 	GetUserData(userID string) (*model.UserDataResponse, error)
+
+	GetUserPreferences(appID string, orgID string, userID string) (*model.UserPreferences, error)
+	UpdateUserPreferences(appID string, orgID string, userID string, preferences model.UserPreferences) (*model.UserPreferences, error)
+	DeleteUserPreferences(appID string, orgID string, userID string) error
+	GetRingSummary(appID string, orgID string, userID string, ringID string, date *string) (*model.RingSummary, error)
+	UpdateRingNudges(appID string, orgID string, userID string, id string, enabled bool) (*model.Ring, error)
+	GetOverdueSummary(appID string, orgID string, userID string) (*model.OverdueSummary, error)
+	SnoozeTodoEntry(appID string, orgID string, userID string, id string, until time.Time) (*model.TodoEntry, error)
+	BulkTodoEntries(appID string, orgID string, userID string, operation model.BulkTodoOperation) ([]model.BulkTodoResult, error)
+	MoveTodoEntry(appID string, orgID string, userID string, id string, afterID *string) (*model.TodoEntry, error)
+	MoveTodoCategory(appID string, orgID string, userID string, id string, afterID *string) (*model.TodoCategory, error)
+
+	GetTodoTemplates(appID string, orgID string, userID string) ([]model.TodoTemplate, error)
+	CreateTodoTemplate(appID string, orgID string, userID string, template model.TodoTemplate) (*model.TodoTemplate, error)
+	UpdateTodoTemplate(appID string, orgID string, userID string, id string, template model.TodoTemplate) (*model.TodoTemplate, error)
+	DeleteTodoTemplate(appID string, orgID string, userID string, id string) error
+	SaveTodoEntryAsTemplate(appID string, orgID string, userID string, todoEntryID string, name string) (*model.TodoTemplate, error)
+	InstantiateTodoTemplate(appID string, orgID string, userID string, id string, instance model.TodoTemplateInstance) ([]model.TodoEntry, error)
+
+	GetConfigSettings(appID string, orgID string) model.ConfigSettings
+}
+
+// Administration exposes administration APIs for the driver adapters
+type Administration interface {
+	GetDeletionAudits(appID string, orgID string, offset *int64, limit *int64) ([]model.DeletionAudit, error)
+	RunDeleteData() error
+
+	GetAppOrgConfig(appID string, orgID string) (*model.AppOrgConfig, error)
+	CreateAppOrgConfig(appID string, orgID string, settings model.ConfigSettings) (*model.AppOrgConfig, error)
+	UpdateAppOrgConfig(appID string, orgID string, settings model.ConfigSettings) (*model.AppOrgConfig, error)
+	DeleteAppOrgConfig(appID string, orgID string) error
+
+	GetNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error)
+	CreateNotificationTemplate(appID string, orgID string, template model.NotificationTemplate) (*model.NotificationTemplate, error)
+	UpdateNotificationTemplate(appID string, orgID string, id string, template model.NotificationTemplate) (*model.NotificationTemplate, error)
+	DeleteNotificationTemplate(appID string, orgID string, id string) error
+
+	GetTodoTemplates(appID string, orgID string) ([]model.TodoTemplate, error)
+	CreateTodoTemplate(appID string, orgID string, template model.TodoTemplate) (*model.TodoTemplate, error)
+	UpdateTodoTemplate(appID string, orgID string, id string, template model.TodoTemplate) (*model.TodoTemplate, error)
+	DeleteTodoTemplate(appID string, orgID string, id string) error
+}
+
+type administrationImpl struct {
+	app *Application
+}
+
+func (s *administrationImpl) GetDeletionAudits(appID string, orgID string, offset *int64, limit *int64) ([]model.DeletionAudit, error) {
+	return s.app.getDeletionAudits(appID, orgID, offset, limit)
+}
+
+func (s *administrationImpl) RunDeleteData() error {
+	return s.app.runDeleteData()
+}
+
+func (s *administrationImpl) GetAppOrgConfig(appID string, orgID string) (*model.AppOrgConfig, error) {
+	return s.app.getAppOrgConfig(appID, orgID)
+}
+
+func (s *administrationImpl) CreateAppOrgConfig(appID string, orgID string, settings model.ConfigSettings) (*model.AppOrgConfig, error) {
+	return s.app.createAppOrgConfig(appID, orgID, settings)
+}
+
+func (s *administrationImpl) UpdateAppOrgConfig(appID string, orgID string, settings model.ConfigSettings) (*model.AppOrgConfig, error) {
+	return s.app.updateAppOrgConfig(appID, orgID, settings)
+}
+
+func (s *administrationImpl) DeleteAppOrgConfig(appID string, orgID string) error {
+	return s.app.deleteAppOrgConfig(appID, orgID)
+}
+
+func (s *administrationImpl) GetNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error) {
+	return s.app.getNotificationTemplates(appID, orgID)
+}
+
+func (s *administrationImpl) CreateNotificationTemplate(appID string, orgID string, template model.NotificationTemplate) (*model.NotificationTemplate, error) {
+	return s.app.createNotificationTemplate(appID, orgID, template)
+}
+
+func (s *administrationImpl) UpdateNotificationTemplate(appID string, orgID string, id string, template model.NotificationTemplate) (*model.NotificationTemplate, error) {
+	return s.app.updateNotificationTemplate(appID, orgID, id, template)
+}
+
+func (s *administrationImpl) DeleteNotificationTemplate(appID string, orgID string, id string) error {
+	return s.app.deleteNotificationTemplate(appID, orgID, id)
+}
+
+func (s *administrationImpl) GetTodoTemplates(appID string, orgID string) ([]model.TodoTemplate, error) {
+	return s.app.getTodoTemplates(appID, orgID, "")
+}
+
+func (s *administrationImpl) CreateTodoTemplate(appID string, orgID string, template model.TodoTemplate) (*model.TodoTemplate, error) {
+	return s.app.createTodoTemplate(appID, orgID, "", template)
+}
+
+func (s *administrationImpl) UpdateTodoTemplate(appID string, orgID string, id string, template model.TodoTemplate) (*model.TodoTemplate, error) {
+	return s.app.updateTodoTemplate(appID, orgID, "", id, template)
+}
+
+func (s *administrationImpl) DeleteTodoTemplate(appID string, orgID string, id string) error {
+	return s.app.deleteTodoTemplate(appID, orgID, "", id)
 }
 
 type servicesImpl struct {
@@ -61,6 +166,10 @@ func (s *servicesImpl) GetVersion() string {
 	return s.app.getVersion()
 }
 
+func (s *servicesImpl) GetReadiness() model.Readiness {
+	return s.app.getReadiness()
+}
+
 func (s *servicesImpl) GetTodoCategories(appID string, orgID string, userID string) ([]model.TodoCategory, error) {
 	return s.app.getTodoCategories(appID, orgID, userID)
 }
@@ -77,6 +186,10 @@ func (s *servicesImpl) UpdateTodoCategory(appID string, orgID string, userID str
 	return s.app.updateTodoCategory(appID, orgID, userID, category)
 }
 
+func (s *servicesImpl) PatchTodoCategory(appID string, orgID string, userID string, id string, patch func(current model.TodoCategory) (*model.TodoCategory, error)) (*model.TodoCategory, error) {
+	return s.app.patchTodoCategory(appID, orgID, userID, id, patch)
+}
+
 func (s *servicesImpl) DeleteTodoCategory(appID string, orgID string, userID string, id string) error {
 	return s.app.deleteTodoCategory(appID, orgID, userID, id)
 }
@@ -97,6 +210,10 @@ func (s *servicesImpl) UpdateTodoEntry(appID string, orgID string, userID string
 	return s.app.updateTodoEntry(appID, orgID, userID, todo, id)
 }
 
+func (s *servicesImpl) PatchTodoEntry(appID string, orgID string, userID string, id string, patch func(current model.TodoEntry) (*model.TodoEntry, error)) (*model.TodoEntry, error) {
+	return s.app.patchTodoEntry(appID, orgID, userID, id, patch)
+}
+
 func (s *servicesImpl) DeleteTodoEntry(appID string, orgID string, userID string, id string) error {
 	return s.app.deleteTodoEntry(appID, orgID, userID, id)
 }
@@ -153,30 +270,111 @@ func (s *servicesImpl) GetUserData(userID string) (*model.UserDataResponse, erro
 	return s.app.getUserData(userID)
 }
 
+func (s *servicesImpl) GetUserPreferences(appID string, orgID string, userID string) (*model.UserPreferences, error) {
+	return s.app.getUserPreferences(appID, orgID, userID)
+}
+
+func (s *servicesImpl) UpdateUserPreferences(appID string, orgID string, userID string, preferences model.UserPreferences) (*model.UserPreferences, error) {
+	return s.app.updateUserPreferences(appID, orgID, userID, preferences)
+}
+
+func (s *servicesImpl) DeleteUserPreferences(appID string, orgID string, userID string) error {
+	return s.app.deleteUserPreferences(appID, orgID, userID)
+}
+
+func (s *servicesImpl) GetRingSummary(appID string, orgID string, userID string, ringID string, date *string) (*model.RingSummary, error) {
+	return s.app.getRingSummary(appID, orgID, userID, ringID, date)
+}
+
+func (s *servicesImpl) UpdateRingNudges(appID string, orgID string, userID string, id string, enabled bool) (*model.Ring, error) {
+	return s.app.updateRingNudges(appID, orgID, userID, id, enabled)
+}
+
+func (s *servicesImpl) GetOverdueSummary(appID string, orgID string, userID string) (*model.OverdueSummary, error) {
+	return s.app.getOverdueSummary(appID, orgID, userID)
+}
+
+func (s *servicesImpl) SnoozeTodoEntry(appID string, orgID string, userID string, id string, until time.Time) (*model.TodoEntry, error) {
+	return s.app.snoozeTodoEntry(appID, orgID, userID, id, until)
+}
+
+func (s *servicesImpl) BulkTodoEntries(appID string, orgID string, userID string, operation model.BulkTodoOperation) ([]model.BulkTodoResult, error) {
+	return s.app.bulkTodoEntries(appID, orgID, userID, operation)
+}
+
+func (s *servicesImpl) MoveTodoEntry(appID string, orgID string, userID string, id string, afterID *string) (*model.TodoEntry, error) {
+	return s.app.moveTodoEntry(appID, orgID, userID, id, afterID)
+}
+
+func (s *servicesImpl) MoveTodoCategory(appID string, orgID string, userID string, id string, afterID *string) (*model.TodoCategory, error) {
+	return s.app.moveTodoCategory(appID, orgID, userID, id, afterID)
+}
+
+func (s *servicesImpl) GetTodoTemplates(appID string, orgID string, userID string) ([]model.TodoTemplate, error) {
+	return s.app.getTodoTemplates(appID, orgID, userID)
+}
+
+func (s *servicesImpl) CreateTodoTemplate(appID string, orgID string, userID string, template model.TodoTemplate) (*model.TodoTemplate, error) {
+	return s.app.createTodoTemplate(appID, orgID, userID, template)
+}
+
+func (s *servicesImpl) UpdateTodoTemplate(appID string, orgID string, userID string, id string, template model.TodoTemplate) (*model.TodoTemplate, error) {
+	return s.app.updateTodoTemplate(appID, orgID, userID, id, template)
+}
+
+func (s *servicesImpl) DeleteTodoTemplate(appID string, orgID string, userID string, id string) error {
+	return s.app.deleteTodoTemplate(appID, orgID, userID, id)
+}
+
+func (s *servicesImpl) SaveTodoEntryAsTemplate(appID string, orgID string, userID string, todoEntryID string, name string) (*model.TodoTemplate, error) {
+	return s.app.saveTodoEntryAsTemplate(appID, orgID, userID, todoEntryID, name)
+}
+
+func (s *servicesImpl) InstantiateTodoTemplate(appID string, orgID string, userID string, id string, instance model.TodoTemplateInstance) ([]model.TodoEntry, error) {
+	return s.app.instantiateTodoTemplate(appID, orgID, userID, id, instance)
+}
+
+func (s *servicesImpl) GetConfigSettings(appID string, orgID string) model.ConfigSettings {
+	return s.app.getConfigSettings(appID, orgID)
+}
+
 // Storage is used by core to storage data - DB storage adapter, file storage adapter etc
 type Storage interface {
+	Ping() error
+	Stop(ctx context.Context) error
 	PerformTransaction(transaction func(context storage.TransactionContext) error) error
+	RegisterStorageListener(listener storage.Listener)
 
-	GetTodoCategories(appID string, orgID string, userID string) ([]model.TodoCategory, error)
+	GetTodoCategories(context storage.TransactionContext, appID string, orgID string, userID string) ([]model.TodoCategory, error)
 	GetTodoCategoriesByUserID(userID string) ([]model.TodoCategory, error)
-	GetTodoCategory(appID string, orgID string, userID string, id string) (*model.TodoCategory, error)
-	CreateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error)
-	UpdateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error)
+	GetTodoCategory(context storage.TransactionContext, appID string, orgID string, userID string, id string) (*model.TodoCategory, error)
+	GetLastTodoCategoryPosition(context storage.TransactionContext, appID string, orgID string, userID string) (float64, error)
+	CreateTodoCategory(context storage.TransactionContext, appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error)
+	UpdateTodoCategory(context storage.TransactionContext, appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error)
 	DeleteTodoCategory(appID string, orgID string, userID string, id string) error
-	DeleteTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) error
+	UpdateTodoCategoryPositions(context storage.TransactionContext, appID string, orgID string, userID string, positions map[string]float64) error
+	DeleteTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	CountTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
 
 	GetTodoEntriesWithCurrentReminderTime(context storage.TransactionContext, reminderTime time.Time) ([]model.TodoEntry, error)
 	GetTodoEntriesWithCurrentDueTime(context storage.TransactionContext, dueTime time.Time) ([]model.TodoEntry, error)
-	GetTodoEntries(appID string, orgID string, userID string) ([]model.TodoEntry, error)
+	GetTodoEntriesDueBefore(appID string, orgID string, userID string, dueTime time.Time) ([]model.TodoEntry, error)
+	GetTodoEntries(context storage.TransactionContext, appID string, orgID string, userID string) ([]model.TodoEntry, error)
 	GetTodoEntriesByUserID(userID string) ([]model.TodoEntry, error)
 	GetTodoEntriesForMigration() ([]model.TodoEntry, error)
 	GetTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string, id string) (*model.TodoEntry, error)
-	CreateTodoEntry(appID string, orgID string, userID string, todo *model.TodoEntry, messageIDs model.MessageIDs, entityID string) (*model.TodoEntry, error)
+	GetLastTodoEntryPosition(context storage.TransactionContext, appID string, orgID string, userID string) (float64, error)
+	CreateTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string, todo *model.TodoEntry, messageIDs model.MessageIDs, entityID string) (*model.TodoEntry, error)
 	UpdateTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string, todo *model.TodoEntry, id string) (*model.TodoEntry, error)
 	UpdateTodoEntriesTaskTime(context storage.TransactionContext, ids []string, taskTime time.Time) error
+	UpdateTodoEntryPositions(context storage.TransactionContext, appID string, orgID string, userID string, positions map[string]float64) error
+	GetTodoEntriesToMarkOverdue(context storage.TransactionContext, at time.Time, afterID string, limit int64) ([]model.TodoEntry, error)
+	MarkTodoEntriesOverdue(context storage.TransactionContext, ids []string) error
 	DeleteTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string, id string) error
 	DeleteCompletedTodoEntries(appID string, orgID string, userID string) error
-	DeleteTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) error
+	DeleteTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	CountTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	GetTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) ([]model.TodoEntry, error)
 
 	GetRings(appID string, orgID string, userID string) ([]model.Ring, error)
 	GetRingsByUserID(userID string) ([]model.Ring, error)
@@ -185,7 +383,15 @@ type Storage interface {
 	DeleteRing(appID string, orgID string, userID string, id string) error
 	CreateRingHistory(appID string, orgID string, userID string, ringID string, ringHistory *model.RingHistoryEntry) (*model.Ring, error)
 	DeleteRingHistory(appID string, orgID string, userID string, ringID string, ringHistoryID string) (*model.Ring, error)
-	DeleteRingsForUsers(appID string, orgID string, accountsIDs []string) error
+	DeleteRingsForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	CountRingsForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	GetRingsWithNudges(afterID string, limit int64, since time.Time, caps []model.RingNudgesCap, defaultMax int) ([]model.Ring, error)
+	UpdateRingNudges(appID string, orgID string, userID string, id string, enabled bool) (*model.Ring, error)
+
+	InsertRingNudge(nudge model.RingNudge) (bool, error)
+	CountRingNudges(appID string, orgID string, userID string, since time.Time) (int64, error)
+	DeleteRingNudgesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	CountRingNudgesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
 
 	GetRingsRecords(appID string, orgID string, userID string, ringID *string, startDateEpoch *int64, endDateEpoch *int64, offset *int64, limit *int64, order *string) ([]model.RingRecord, error)
 	GetRingsRecordsByUserID(userID string) ([]model.RingRecord, error)
@@ -193,16 +399,60 @@ type Storage interface {
 	CreateRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error)
 	UpdateRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error)
 	DeleteRingsRecords(appID string, orgID string, userID string, ringID *string, recordID *string) error
-	DeleteRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) error
+	DeleteRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	CountRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+
+	CreateNotificationDeleteFailures(failures []model.NotificationDeleteFailure) error
+
+	CreateDeletionAudit(audit model.DeletionAudit) error
+	GetDeletionAudits(appID *string, orgID *string, offset *int64, limit *int64) ([]model.DeletionAudit, error)
+
+	AcquireLock(name string, owner string, expiresAt time.Time) (bool, error)
+	ReleaseLock(name string, owner string) error
+
+	ApplyMigrations(dryRun bool, tasks map[string]model.MigrationTask) ([]model.Migration, error)
+
+	GetUserPreferences(appID string, orgID string, userID string) (*model.UserPreferences, error)
+	GetUserPreferencesByUserID(userID string) ([]model.UserPreferences, error)
+	SaveUserPreferences(appID string, orgID string, userID string, preferences model.UserPreferences) (*model.UserPreferences, error)
+	DeleteUserPreferences(appID string, orgID string, userID string) error
+	DeleteUserPreferencesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	GetUserPreferencesWithDigest(afterID string, limit int64) ([]model.UserPreferences, error)
+	MarkDigestSent(preferencesID string, date string) (bool, error)
+	CountUserPreferencesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+
+	GetNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error)
+	GetNotificationTemplate(appID string, orgID string, id string) (*model.NotificationTemplate, error)
+	InsertNotificationTemplate(template model.NotificationTemplate) error
+	UpdateNotificationTemplate(template model.NotificationTemplate) error
+	DeleteNotificationTemplate(appID string, orgID string, id string) error
+
+	GetTodoTemplates(appID string, orgID string, userIDs []string) ([]model.TodoTemplate, error)
+	GetTodoTemplatesByUserID(userID string) ([]model.TodoTemplate, error)
+	GetTodoTemplate(appID string, orgID string, id string) (*model.TodoTemplate, error)
+	InsertTodoTemplate(template model.TodoTemplate) error
+	UpdateTodoTemplate(template model.TodoTemplate) error
+	DeleteTodoTemplate(appID string, orgID string, userID string, id string) error
+	DeleteTodoTemplatesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+	CountTodoTemplatesForUsers(appID string, orgID string, accountsIDs []string) (int64, error)
+
+	GetAppOrgConfigs() ([]model.AppOrgConfig, error)
+	GetAppOrgConfig(appID string, orgID string) (*model.AppOrgConfig, error)
+	InsertAppOrgConfig(config model.AppOrgConfig) error
+	UpdateAppOrgConfig(config model.AppOrgConfig) error
+	DeleteAppOrgConfig(appID string, orgID string) error
 }
 
 // Notifications wrapper
 type Notifications interface {
 	SendNotification(recipients []model.NotificationRecipient, topic *string, title string, text string, appID string, orgID string, time *int64, data map[string]string) (*string, error)
 	DeleteNotification(appID string, orgID string, id string) error
+	CheckReachability() error
 }
 
 // Core exposes Core APIs for the driver adapters
 type Core interface {
 	LoadDeletedMemberships() ([]model.DeletedUserData, error)
+	CheckServiceAccount() error
+	CheckReachability() error
 }
@@ -0,0 +1,113 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+const (
+	lockTTL       = 2 * time.Minute
+	lockHeartbeat = 30 * time.Second
+)
+
+// lockManager gives cluster-wide exclusive access to background work through the storage locks.
+// A held lock is kept alive by heartbeats, so it expires soon after its owner instance dies.
+type lockManager struct {
+	logger  *logs.Logger
+	storage Storage
+
+	//identifies this service instance as a lock owner
+	owner string
+
+	//how long a lock is held without a heartbeat and how often the heartbeats renew it
+	ttl       time.Duration
+	heartbeat time.Duration
+}
+
+// heldLock is a lock acquired by this instance
+type heldLock struct {
+	manager *lockManager
+	name    string
+
+	stopHeartbeat chan struct{}
+	heartbeatDone chan struct{}
+}
+
+// acquire acquires the named lock. It gives nil if the lock is held by another instance.
+func (m *lockManager) acquire(name string) (*heldLock, error) {
+	acquired, err := m.storage.AcquireLock(name, m.owner, time.Now().Add(m.ttl))
+	if err != nil || !acquired {
+		return nil, err
+	}
+
+	lock := &heldLock{manager: m, name: name, stopHeartbeat: make(chan struct{}), heartbeatDone: make(chan struct{})}
+	go lock.heartbeat()
+	return lock, nil
+}
+
+// runExclusively runs the task if the named lock is not held by another instance. It gives false if the task was skipped.
+func (m *lockManager) runExclusively(name string, task func() error) (bool, error) {
+	lock, err := m.acquire(name)
+	if err != nil || lock == nil {
+		return false, err
+	}
+	defer lock.release(0)
+
+	return true, task()
+}
+
+func (l *heldLock) heartbeat() {
+	defer close(l.heartbeatDone)
+
+	ticker := time.NewTicker(l.manager.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			renewed, err := l.manager.storage.AcquireLock(l.name, l.manager.owner, time.Now().Add(l.manager.ttl))
+			if err != nil {
+				l.manager.logger.Errorf("error renewing %s lock - %s", l.name, err)
+			} else if !renewed {
+				l.manager.logger.Errorf("%s lock was lost - it expired and another instance acquired it", l.name)
+			}
+		case <-l.stopHeartbeat:
+			return
+		}
+	}
+}
+
+// release stops the heartbeats and releases the lock. When keepFor is positive the lock is kept
+// for that duration instead, so other instances cannot take over right after the release.
+func (l *heldLock) release(keepFor time.Duration) {
+	close(l.stopHeartbeat)
+	<-l.heartbeatDone
+
+	var err error
+	if keepFor > 0 {
+		_, err = l.manager.storage.AcquireLock(l.name, l.manager.owner, time.Now().Add(keepFor))
+	} else {
+		err = l.manager.storage.ReleaseLock(l.name, l.manager.owner)
+	}
+	if err != nil {
+		l.manager.logger.Errorf("error releasing %s lock - %s", l.name, err)
+	}
+}
+
+func newLockManager(logger *logs.Logger, storage Storage, owner string) *lockManager {
+	return &lockManager{logger: logger, storage: storage, owner: owner, ttl: lockTTL, heartbeat: lockHeartbeat}
+}
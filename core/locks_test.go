@@ -0,0 +1,175 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wellness/driven/storage"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+func newTestLockManager(store Storage, owner string) *lockManager {
+	return newLockManager(logs.NewLogger("test", &logs.LoggerOpts{}), store, owner)
+}
+
+func TestLockManagerAcquire(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	first := newTestLockManager(store, "first")
+	second := newTestLockManager(store, "second")
+
+	lock, err := first.acquire("job")
+	if err != nil || lock == nil {
+		t.Fatalf("acquire() of a free lock = %v, %v", lock, err)
+	}
+	defer lock.release(0)
+
+	other, err := second.acquire("job")
+	if err != nil {
+		t.Fatalf("acquire() of a held lock error = %v", err)
+	}
+	if other != nil {
+		t.Fatal("acquire() of a lock held by another owner succeeded")
+	}
+
+	another, err := second.acquire("another job")
+	if err != nil || another == nil {
+		t.Fatalf("acquire() of another free lock = %v, %v", another, err)
+	}
+	another.release(0)
+}
+
+func TestHeldLockRelease(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	first := newTestLockManager(store, "first")
+	second := newTestLockManager(store, "second")
+
+	lock, err := first.acquire("job")
+	if err != nil || lock == nil {
+		t.Fatalf("acquire() = %v, %v", lock, err)
+	}
+	lock.release(0)
+
+	other, err := second.acquire("job")
+	if err != nil || other == nil {
+		t.Fatalf("acquire() of a released lock = %v, %v", other, err)
+	}
+	other.release(0)
+}
+
+func TestHeldLockReleaseKeepFor(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	first := newTestLockManager(store, "first")
+	second := newTestLockManager(store, "second")
+
+	lock, err := first.acquire("job")
+	if err != nil || lock == nil {
+		t.Fatalf("acquire() = %v, %v", lock, err)
+	}
+	lock.release(100 * time.Millisecond)
+
+	other, err := second.acquire("job")
+	if err != nil || other != nil {
+		t.Fatalf("acquire() of a kept lock = %v, %v, want nil", other, err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	other, err = second.acquire("job")
+	if err != nil || other == nil {
+		t.Fatalf("acquire() after the keep duration = %v, %v", other, err)
+	}
+	other.release(0)
+}
+
+func TestHeldLockHeartbeat(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	first := newTestLockManager(store, "first")
+	first.ttl = 100 * time.Millisecond
+	first.heartbeat = 20 * time.Millisecond
+	second := newTestLockManager(store, "second")
+
+	lock, err := first.acquire("job")
+	if err != nil || lock == nil {
+		t.Fatalf("acquire() = %v, %v", lock, err)
+	}
+
+	//the heartbeats keep the lock beyond its ttl
+	time.Sleep(300 * time.Millisecond)
+	other, err := second.acquire("job")
+	if err != nil || other != nil {
+		t.Fatalf("acquire() of a lock kept by heartbeats = %v, %v, want nil", other, err)
+	}
+	lock.release(0)
+}
+
+func TestLockExpiry(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	second := newTestLockManager(store, "second")
+
+	//an instance which died right after acquiring the lock sends no heartbeats
+	acquired, err := store.AcquireLock("job", "dead", time.Now().Add(50*time.Millisecond))
+	if err != nil || !acquired {
+		t.Fatalf("AcquireLock() = %v, %v", acquired, err)
+	}
+
+	other, err := second.acquire("job")
+	if err != nil || other != nil {
+		t.Fatalf("acquire() of a live lock = %v, %v, want nil", other, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	other, err = second.acquire("job")
+	if err != nil || other == nil {
+		t.Fatalf("acquire() of an expired lock = %v, %v", other, err)
+	}
+	other.release(0)
+}
+
+func TestLockManagerRunExclusively(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	first := newTestLockManager(store, "first")
+	second := newTestLockManager(store, "second")
+
+	var nested bool
+	ran, err := first.runExclusively("job", func() error {
+		skipped, err := second.runExclusively("job", func() error {
+			nested = true
+			return nil
+		})
+		if skipped || err != nil {
+			t.Errorf("runExclusively() of a held lock = %v, %v, want false", skipped, err)
+		}
+		return nil
+	})
+	if !ran || err != nil {
+		t.Fatalf("runExclusively() = %v, %v", ran, err)
+	}
+	if nested {
+		t.Fatal("the task ran while another owner held the lock")
+	}
+
+	//the lock is released after the task, also when it fails
+	taskErr := errors.New("task failed")
+	ran, err = second.runExclusively("job", func() error { return taskErr })
+	if !ran || !errors.Is(err, taskErr) {
+		t.Fatalf("runExclusively() of a failing task = %v, %v", ran, err)
+	}
+	ran, err = first.runExclusively("job", func() error { return nil })
+	if !ran || err != nil {
+		t.Fatalf("runExclusively() after a failed task = %v, %v", ran, err)
+	}
+}
@@ -14,6 +14,26 @@
 
 package model
 
+import "time"
+
+const (
+	//DefaultNotificationTitle the title of the todo reminders when the app/org does not configure one
+	DefaultNotificationTitle string = "To-Do List Reminder"
+	//ReminderTextTitlePlaceholder is replaced with the title of the todo entry in the configured reminder text
+	ReminderTextTitlePlaceholder string = "{title}"
+	//DefaultRingNudgeTime the local time after which the rings behind the goal are nudged when the app/org does not configure one
+	DefaultRingNudgeTime string = "19:00"
+	//DefaultMaxRingNudgesPerWeek how many ring nudges a user gets in 7 days when the app/org does not configure it
+	DefaultMaxRingNudgesPerWeek int = 3
+
+	//FeatureRings the rings and their records
+	FeatureRings string = "rings"
+	//FeatureRingNudges the nudges of the rings behind the goal
+	FeatureRingNudges string = "ring_nudges"
+	//FeatureDigest the daily digest
+	FeatureDigest string = "digest"
+)
+
 // Config the main config structure
 type Config struct {
 	InternalAPIKey string
@@ -21,6 +41,28 @@ type Config struct {
 	ServiceURL     string
 }
 
+// DeleteDataConfig configures the processing of the deleted users data
+type DeleteDataConfig struct {
+	Schedule string //cron-like expression
+	Timezone string
+	DryRun   bool
+}
+
+// DigestConfig configures the daily digest job
+type DigestConfig struct {
+	Schedule string //cron-like expression of the checks for the users whose digest time has come
+}
+
+// RingNudgesConfig configures the ring nudges job
+type RingNudgesConfig struct {
+	Schedule string //cron-like expression of the checks for the rings behind the goal
+}
+
+// OverdueConfig configures the overdue todo entries sweeper
+type OverdueConfig struct {
+	Schedule string //cron-like expression of the sweeps for the overdue todo entries
+}
+
 // DeletedUserData represents a user-deleted
 type DeletedUserData struct {
 	AppID       string              `json:"app_id"`
@@ -33,3 +75,43 @@ type DeletedMembership struct {
 	AccountID string                  `json:"account_id"`
 	Context   *map[string]interface{} `json:"context,omitempty"`
 }
+
+// AppOrgConfig keeps the wellness settings of an app/org. The settings are tuned per app/org without a redeploy.
+type AppOrgConfig struct {
+	ID          string         `json:"id" bson:"_id"`
+	AppID       string         `json:"app_id" bson:"app_id"`
+	OrgID       string         `json:"org_id" bson:"org_id"`
+	Settings    ConfigSettings `json:"settings" bson:"settings"`
+	DateCreated time.Time      `json:"date_created" bson:"date_created"`
+	DateUpdated *time.Time     `json:"date_updated" bson:"date_updated"`
+} // @name AppOrgConfig
+
+// ConfigSettings the wellness settings of an app/org. The empty values fall back to the defaults.
+type ConfigSettings struct {
+	//the text of the todo reminders, {title} is replaced with the title of the entry - the title alone when empty
+	ReminderText string `json:"reminder_text" bson:"reminder_text"`
+	//the title of the todo reminders - DefaultNotificationTitle when empty
+	NotificationTitle string `json:"notification_title" bson:"notification_title"`
+	//how many days back the clients let the users backfill the rings records - 0 for today only
+	BackfillWindowDays int `json:"backfill_window_days" bson:"backfill_window_days"`
+	//how many rings a user can have - 0 for no limit
+	MaxRings int `json:"max_rings" bson:"max_rings"`
+	//the features turned on or off by name, see FeatureRings, FeatureRingNudges and FeatureDigest - on when missing
+	Features map[string]bool `json:"features" bson:"features"`
+	//the language of the reminders of the users who have not chosen one - DefaultLanguage when empty
+	Language string `json:"language" bson:"language"`
+	//the IANA time zone of the reminders of the users who have not chosen one - UTC when empty
+	Timezone string `json:"timezone" bson:"timezone"`
+	//the local time like 15:04 after which the rings behind the goal are nudged - DefaultRingNudgeTime when empty
+	RingNudgeTime string `json:"ring_nudge_time" bson:"ring_nudge_time"`
+	//how many ring nudges a user gets in 7 days - DefaultMaxRingNudgesPerWeek when 0
+	MaxRingNudgesPerWeek int `json:"max_ring_nudges_per_week" bson:"max_ring_nudges_per_week"`
+	//move the overdue recurring todo entries to their next occurrence instead of keeping them overdue
+	RollForwardRecurring bool `json:"roll_forward_recurring" bson:"roll_forward_recurring"`
+} // @name ConfigSettings
+
+// IsFeatureEnabled tells if the feature is on - the features are on unless they are turned off
+func (s ConfigSettings) IsFeatureEnabled(name string) bool {
+	enabled, ok := s.Features[name]
+	return !ok || enabled
+}
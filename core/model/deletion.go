@@ -0,0 +1,50 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+const (
+	// DeletionAuditNotifications key for the cancelled scheduled notifications count
+	DeletionAuditNotifications string = "notifications"
+	// DeletionAuditTodoCategories key for the deleted todo categories count
+	DeletionAuditTodoCategories string = "todo_categories"
+	// DeletionAuditTodoEntries key for the deleted todo entries count
+	DeletionAuditTodoEntries string = "todo_entries"
+	// DeletionAuditTodoTemplates key for the deleted todo templates count
+	DeletionAuditTodoTemplates string = "todo_templates"
+	// DeletionAuditRings key for the deleted rings count
+	DeletionAuditRings string = "rings"
+	// DeletionAuditRingsRecords key for the deleted rings records count
+	DeletionAuditRingsRecords string = "rings_records"
+	// DeletionAuditRingNudges key for the deleted ring nudges count
+	DeletionAuditRingNudges string = "ring_nudges"
+	// DeletionAuditUserPreferences key for the deleted user preferences count
+	DeletionAuditUserPreferences string = "user_preferences"
+)
+
+// DeletionAudit represents a single run of the deleted users data processing for an app/org
+type DeletionAudit struct {
+	ID                   string           `json:"id" bson:"_id"`
+	AppID                string           `json:"app_id" bson:"app_id"`
+	OrgID                string           `json:"org_id" bson:"org_id"`
+	DryRun               bool             `json:"dry_run" bson:"dry_run"`
+	AccountsCount        int              `json:"accounts_count" bson:"accounts_count"`
+	DeletedCounts        map[string]int64 `json:"deleted_counts" bson:"deleted_counts"`
+	NotificationFailures int              `json:"notification_failures" bson:"notification_failures"`
+	Errors               []string         `json:"errors" bson:"errors"`
+	Duration             int64            `json:"duration_ms" bson:"duration_ms"`
+	DateCreated          time.Time        `json:"date_created" bson:"date_created"`
+} // @name DeletionAudit
@@ -0,0 +1,103 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorType is the kind of an error. The APIs give the response status by it.
+type ErrorType string
+
+const (
+	//ErrorTypeNotFound the requested entity does not exist
+	ErrorTypeNotFound ErrorType = "not_found"
+	//ErrorTypeValidation the input is not valid
+	ErrorTypeValidation ErrorType = "validation"
+	//ErrorTypeConflict the operation cannot be done in the current state of the entity
+	ErrorTypeConflict ErrorType = "conflict"
+	//ErrorTypeForbidden the caller is not allowed to do the operation
+	ErrorTypeForbidden ErrorType = "forbidden"
+	//ErrorTypeUnauthorized the caller is not authenticated
+	ErrorTypeUnauthorized ErrorType = "unauthorized"
+	//ErrorTypeInternal an unexpected failure - its details are not given to the clients
+	ErrorTypeInternal ErrorType = "internal"
+)
+
+// Error is an error of a known type with a message which is safe to give to the clients
+type Error struct {
+	Type    ErrorType
+	Message string
+	//the invalid fields of a validation error
+	Fields []FieldError
+}
+
+// FieldError is the reason a field of the input is not valid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+} //@name FieldError
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewNotFoundError creates an error for a missing entity
+func NewNotFoundError(entity string, id string) *Error {
+	return &Error{Type: ErrorTypeNotFound, Message: fmt.Sprintf("%s %s not found", entity, id)}
+}
+
+// NewValidationError creates an error for an invalid input
+func NewValidationError(message string) *Error {
+	return &Error{Type: ErrorTypeValidation, Message: message}
+}
+
+// NewFieldsValidationError creates an error for an input with invalid fields
+func NewFieldsValidationError(fields ...FieldError) *Error {
+	return &Error{Type: ErrorTypeValidation, Message: "the request data is not valid", Fields: fields}
+}
+
+// NewConflictError creates an error for an operation which does not fit the current state
+func NewConflictError(message string) *Error {
+	return &Error{Type: ErrorTypeConflict, Message: message}
+}
+
+// NewForbiddenError creates an error for a not allowed operation
+func NewForbiddenError(message string) *Error {
+	return &Error{Type: ErrorTypeForbidden, Message: message}
+}
+
+// NewUnauthorizedError creates an error for a not authenticated caller
+func NewUnauthorizedError(message string) *Error {
+	return &Error{Type: ErrorTypeUnauthorized, Message: message}
+}
+
+// AsError gives the typed error in the chain of the error, nil when there is none
+func AsError(err error) *Error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return nil
+}
+
+// ErrorResponse is the body of the failed API requests
+type ErrorResponse struct {
+	Code      ErrorType    `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id"`
+	Fields    []FieldError `json:"fields,omitempty"`
+} //@name ErrorResponse
@@ -0,0 +1,36 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+const (
+	//HealthStatusUp the service or the dependency works
+	HealthStatusUp string = "up"
+	//HealthStatusDown the service or the dependency does not work
+	HealthStatusDown string = "down"
+)
+
+// Readiness shows if the service can serve requests
+type Readiness struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+} // @name Readiness
+
+// DependencyStatus shows if a dependency of the service works
+type DependencyStatus struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Error    *string `json:"error,omitempty"`
+	Duration int64   `json:"duration_ms"`
+} // @name DependencyStatus
@@ -0,0 +1,31 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// Migration represents a versioned data migration applied to the storage
+type Migration struct {
+	Version     int       `json:"version" bson:"_id"`
+	Name        string    `json:"name" bson:"name"`
+	Summary     string    `json:"summary" bson:"summary"`
+	DryRun      bool      `json:"dry_run" bson:"-"`
+	Duration    int64     `json:"duration_ms" bson:"duration_ms"`
+	DateApplied time.Time `json:"date_applied" bson:"date_applied"`
+}
+
+// MigrationTask migrates the data which needs the application logic, e.g. the other building blocks. The storage applies
+// it as the versioned migration with the same name. In dry run mode it must not change anything, just report what would be changed.
+type MigrationTask func(dryRun bool) (string, error)
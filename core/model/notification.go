@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // NotificationMessage wrapper for internal message
 type NotificationMessage struct {
 	OrgID      string                  `json:"org_id" bson:"org_id"`
@@ -31,3 +33,77 @@ type CoreUserRef struct {
 	UserID *string `json:"user_id" bson:"user_id"`
 	Name   *string `json:"name" bson:"name"`
 }
+
+// NotificationDeleteFailure represents a scheduled message which could not be deleted from the Notifications BB
+type NotificationDeleteFailure struct {
+	ID          string    `json:"id" bson:"_id"`
+	AppID       string    `json:"app_id" bson:"app_id"`
+	OrgID       string    `json:"org_id" bson:"org_id"`
+	UserID      string    `json:"user_id" bson:"user_id"`
+	TodoEntryID string    `json:"todo_entry_id" bson:"todo_entry_id"`
+	MessageID   string    `json:"message_id" bson:"message_id"`
+	Error       string    `json:"error" bson:"error"`
+	DateCreated time.Time `json:"date_created" bson:"date_created"`
+}
+
+const (
+	//DefaultLanguage the language of the reminders when neither the user nor the app/org has one
+	DefaultLanguage string = "en"
+
+	//ReminderKindDue the reminder sent at the due time of a todo entry
+	ReminderKindDue string = "due"
+	//ReminderKindReminder the reminder sent at the reminder time of a todo entry
+	ReminderKindReminder string = "reminder"
+	//NotificationKindRingNudge the nudge sent in the evening for a ring behind the goal
+	NotificationKindRingNudge string = "ring_nudge"
+
+	//TemplateKindTodoReminder the templates of the reminders of the todo entries, see ReminderTemplateData
+	TemplateKindTodoReminder string = "todo_reminder"
+	//TemplateKindDigest the templates of the daily digest, see DigestTemplateData
+	TemplateKindDigest string = "digest"
+	//TemplateKindRingNudge the templates of the ring nudges, see RingNudgeTemplateData
+	TemplateKindRingNudge string = "ring_nudge"
+)
+
+// NotificationTemplate is the text/template of a kind of notifications of an app/org in a language
+type NotificationTemplate struct {
+	ID          string     `json:"id" bson:"_id"`
+	AppID       string     `json:"app_id" bson:"app_id"`
+	OrgID       string     `json:"org_id" bson:"org_id"`
+	Kind        string     `json:"kind" bson:"kind"`         //TemplateKindTodoReminder, TemplateKindDigest or TemplateKindRingNudge
+	Language    string     `json:"language" bson:"language"` //BCP 47 tag, eg. es or zh-Hans
+	Subject     string     `json:"subject" bson:"subject"`
+	Body        string     `json:"body" bson:"body"`
+	DateCreated time.Time  `json:"date_created" bson:"date_created"`
+	DateUpdated *time.Time `json:"date_updated" bson:"date_updated"`
+} // @name NotificationTemplate
+
+// ReminderTemplateData the variables of the notification templates. The times are in the time zone of the user.
+type ReminderTemplateData struct {
+	Kind         string     //ReminderKindDue or ReminderKindReminder
+	Title        string     //the title of the todo entry
+	Description  string     //the description of the todo entry
+	Category     string     //the name of the category, empty when the entry has none
+	Location     string     //the location of the todo entry, empty when the entry has none
+	Due          *time.Time //the due time, nil when the entry has none
+	DueTime      string     //the due time formatted as 2006-01-02 15:04, empty when the entry has none
+	Reminder     *time.Time //the reminder time, nil when the entry has none
+	ReminderTime string     //the reminder time formatted as 2006-01-02 15:04, empty when the entry has none
+}
+
+// DigestTemplateData the variables of the daily digest templates
+type DigestTemplateData struct {
+	Date        string   //the local date of the digest formatted as 2006-01-02
+	DueToday    int      //how many todo entries are due today
+	Overdue     int      //how many todo entries are overdue
+	RingsBehind []string //the names of the rings behind the goal of the day
+}
+
+// RingNudgeTemplateData the variables of the ring nudge templates
+type RingNudgeTemplateData struct {
+	Ring  string  //the name of the ring
+	Unit  string  //the unit of the ring, eg. glasses
+	Goal  float64 //the goal of the day
+	Value float64 //the total of the day
+	Left  float64 //how much is left to hit the goal
+}
@@ -4,15 +4,36 @@ import "time"
 
 // Ring represents wellness ring wrapper
 type Ring struct {
-	ID          string             `json:"id" bson:"_id"`
-	AppID       string             `json:"app_id" bson:"app_id"`
-	OrgID       string             `json:"org_id" bson:"org_id"`
-	UserID      string             `json:"user_id" bson:"user_id"`
-	History     []RingHistoryEntry `json:"history" bson:"history"`
-	DateCreated time.Time          `json:"date_created" bson:"date_created"`
-	DateUpdated *time.Time         `json:"date_updated" bson:"date_updated"`
+	ID            string             `json:"id" bson:"_id"`
+	AppID         string             `json:"app_id" bson:"app_id"`
+	OrgID         string             `json:"org_id" bson:"org_id"`
+	UserID        string             `json:"user_id" bson:"user_id"`
+	History       []RingHistoryEntry `json:"history" bson:"history"`
+	NudgesEnabled bool               `json:"nudges_enabled" bson:"nudges_enabled"` //nudge the user in the evening when the ring is behind the goal
+	DateCreated   time.Time          `json:"date_created" bson:"date_created"`
+	DateUpdated   *time.Time         `json:"date_updated" bson:"date_updated"`
 } // @name Ring
 
+// EffectiveHistoryEntry gives the history entry - the goal - in effect at the time. The oldest entry is in effect before
+// the first change. It gives nil if the ring has no history.
+func (r *Ring) EffectiveHistoryEntry(at time.Time) *RingHistoryEntry {
+	var latest *RingHistoryEntry
+	var oldest *RingHistoryEntry
+	for i := range r.History {
+		entry := &r.History[i]
+		if oldest == nil || entry.DateCreated.Before(oldest.DateCreated) {
+			oldest = entry
+		}
+		if !entry.DateCreated.After(at) && (latest == nil || entry.DateCreated.After(latest.DateCreated)) {
+			latest = entry
+		}
+	}
+	if latest != nil {
+		return latest
+	}
+	return oldest
+}
+
 // RingHistoryEntry represents single history entry
 type RingHistoryEntry struct {
 	ID          string     `json:"id" bson:"id"`
@@ -36,3 +57,40 @@ type RingRecord struct {
 	DateCreated time.Time  `json:"date_created" bson:"date_created"`
 	DateUpdated *time.Time `json:"date_updated" bson:"date_updated"`
 } //@name RingRecord
+
+// RingSummary the daily totals of a ring in a week in the time zone of the user
+type RingSummary struct {
+	RingID    string           `json:"ring_id"`
+	Unit      string           `json:"unit"`
+	WeekStart string           `json:"week_start"` //2006-01-02
+	Days      []RingDaySummary `json:"days"`
+	Total     float64          `json:"total"`
+} // @name RingSummary
+
+// RingDaySummary the total of a ring in a day with the goal of that day
+type RingDaySummary struct {
+	Date     string  `json:"date"` //2006-01-02
+	Value    float64 `json:"value"`
+	Goal     float64 `json:"goal"`
+	Achieved bool    `json:"achieved"`
+} // @name RingDaySummary
+
+// RingNudge records a nudge sent for a ring behind the goal, so a ring gets one nudge a day and a user a limited number a week
+type RingNudge struct {
+	ID          string    `json:"id" bson:"_id"`
+	AppID       string    `json:"app_id" bson:"app_id"`
+	OrgID       string    `json:"org_id" bson:"org_id"`
+	UserID      string    `json:"user_id" bson:"user_id"`
+	RingID      string    `json:"ring_id" bson:"ring_id"`
+	Date        string    `json:"date" bson:"date"` //the local date of the user, 2006-01-02
+	Value       float64   `json:"value" bson:"value"`
+	Goal        float64   `json:"goal" bson:"goal"`
+	DateCreated time.Time `json:"date_created" bson:"date_created"`
+} // @name RingNudge
+
+// RingNudgesCap is how many ring nudges the users of an app/org get in 7 days
+type RingNudgesCap struct {
+	AppID string
+	OrgID string
+	Max   int
+}
@@ -16,6 +16,15 @@ package model
 
 import "time"
 
+const (
+	//TodoRecurrenceDaily the todo entry is due every day
+	TodoRecurrenceDaily string = "daily"
+	//TodoRecurrenceWeekly the todo entry is due every week
+	TodoRecurrenceWeekly string = "weekly"
+	//TodoRecurrenceMonthly the todo entry is due every month
+	TodoRecurrenceMonthly string = "monthly"
+)
+
 // TodoCategory user defined todo category
 type TodoCategory struct {
 	ID          string     `json:"id" bson:"_id"`
@@ -24,6 +33,7 @@ type TodoCategory struct {
 	UserID      string     `json:"user_id" bson:"user_id"`
 	Name        string     `json:"name" bson:"name"`
 	Color       string     `json:"color" bson:"color"`
+	Position    float64    `json:"position" bson:"position"` //the manual order of the categories of the user, ascending
 	DateCreated time.Time  `json:"date_created" bson:"date_created"`
 	DateUpdated *time.Time `json:"date_updated" bson:"date_updated"`
 } // @name TodoCategory
@@ -52,10 +62,16 @@ type TodoEntry struct {
 	DueDateTime      *time.Time   `json:"due_date_time" bson:"due_date_time"`
 	ReminderType     string       `json:"reminder_type" bson:"reminder_type"`
 	ReminderDateTime *time.Time   `json:"reminder_date_time" bson:"reminder_date_time"`
+	Recurrence       string       `json:"recurrence" bson:"recurrence"` //TodoRecurrenceDaily, TodoRecurrenceWeekly, TodoRecurrenceMonthly or empty
+	Overdue          bool         `json:"overdue" bson:"overdue"`       //set when the entry is written and by the overdue sweeper, see IsOverdue
+	SnoozeCount      int          `json:"snooze_count" bson:"snooze_count"`
+	Position         float64      `json:"position" bson:"position"` //the manual order of the entries of the user, ascending
 	MessageIDs       MessageIDs   `json:"message_ids" bson:"message_ids"`
-	TaskTime         *time.Time   `json:"task_time" bson:"task_time"`
-	DateCreated      time.Time    `json:"date_created" bson:"date_created"`
-	DateUpdated      *time.Time   `json:"date_updated" bson:"date_updated"`
+	//the reminders moved or dropped because of the quiet hours of the user
+	ReminderAdjustments []ReminderAdjustment `json:"reminder_adjustments" bson:"reminder_adjustments"`
+	TaskTime            *time.Time           `json:"task_time" bson:"task_time"`
+	DateCreated         time.Time            `json:"date_created" bson:"date_created"`
+	DateUpdated         *time.Time           `json:"date_updated" bson:"date_updated"`
 } // @name TodoEntry
 
 // RequiresMessageIDsMigration Checks if the record requires db data migration
@@ -64,6 +80,105 @@ func (t *TodoEntry) RequiresMessageIDsMigration() bool {
 		(t.ReminderDateTime != nil && time.Now().Before(*t.ReminderDateTime) && t.MessageIDs.ReminderDateMessageID == nil)
 }
 
+// SendTime gives the time the notification of the kind is sent at - the time set by the quiet hours when they moved it,
+// otherwise the due or the reminder time. It is nil when the quiet hours dropped the notification.
+func (t *TodoEntry) SendTime(kind string) *time.Time {
+	for _, item := range t.ReminderAdjustments {
+		if item.Kind == kind {
+			return item.ScheduledTime
+		}
+	}
+	if kind == ReminderKindDue {
+		return t.DueDateTime
+	}
+	return t.ReminderDateTime
+}
+
+// EarliestDayEndOffset is the offset of the time zone where the days end first - UTC+14. An entry without a due time is
+// overdue nowhere before its due day is over there.
+const EarliestDayEndOffset = 14 * time.Hour
+
+// IsOverdue tells if the entry is overdue at the time - it is not completed and its due time has passed. An entry without a
+// due time is overdue once its due day - the UTC date of the due time - is over in the time zone of the user.
+func (t *TodoEntry) IsOverdue(at time.Time, location *time.Location) bool {
+	if t.Completed || t.DueDateTime == nil {
+		return false
+	}
+	if t.HasDueTime {
+		return t.DueDateTime.Before(at)
+	}
+	due := t.DueDateTime.UTC()
+	dayEnd := time.Date(due.Year(), due.Month(), due.Day()+1, 0, 0, 0, 0, location)
+	return !dayEnd.After(at)
+}
+
+// NextOccurrence gives the time one recurrence after the time. It gives nil if the entry does not recur.
+func (t *TodoEntry) NextOccurrence(at time.Time) *time.Time {
+	var next time.Time
+	switch t.Recurrence {
+	case TodoRecurrenceDaily:
+		next = at.AddDate(0, 0, 1)
+	case TodoRecurrenceWeekly:
+		next = at.AddDate(0, 0, 7)
+	case TodoRecurrenceMonthly:
+		next = at.AddDate(0, 1, 0)
+	default:
+		return nil
+	}
+	return &next
+}
+
+// OverdueSummary the number of the overdue todo entries of a user
+type OverdueSummary struct {
+	Count      int            `json:"count"`
+	Categories map[string]int `json:"categories"` //by category id, the entries without a category are not included
+} // @name OverdueSummary
+
+// BulkTodoOperation an action applied to many todo entries of a user at once
+type BulkTodoOperation struct {
+	Action           string     //one of the BulkTodoAction constants
+	IDs              []string   //the ids of the todo entries
+	CategoryID       *string    //the category of BulkTodoActionMove - no category when nil
+	DueDateTime      *time.Time //the due time of BulkTodoActionReschedule
+	ReminderDateTime *time.Time //the reminder time of BulkTodoActionReschedule - the reminders keep their offset to the due time when nil
+}
+
+// BulkTodoResult the result of a bulk operation for a todo entry
+type BulkTodoResult struct {
+	ID     string     `json:"id"`
+	Status string     `json:"status"` //one of the BulkTodoStatus constants
+	Error  string     `json:"error,omitempty"`
+	Entry  *TodoEntry `json:"entry,omitempty"` //the updated entry, nil when it is deleted or not changed
+} // @name BulkTodoResult
+
+const (
+	//BulkTodoActionComplete completes the todo entries
+	BulkTodoActionComplete string = "complete"
+	//BulkTodoActionUncomplete uncompletes the todo entries
+	BulkTodoActionUncomplete string = "uncomplete"
+	//BulkTodoActionDelete deletes the todo entries
+	BulkTodoActionDelete string = "delete"
+	//BulkTodoActionMove moves the todo entries to a category
+	BulkTodoActionMove string = "move"
+	//BulkTodoActionReschedule changes the due time of the todo entries
+	BulkTodoActionReschedule string = "reschedule"
+
+	//BulkTodoStatusOK the action was applied to the todo entry
+	BulkTodoStatusOK string = "ok"
+	//BulkTodoStatusNotFound the user has no such todo entry
+	BulkTodoStatusNotFound string = "not_found"
+	//BulkTodoStatusInvalid the action cannot be applied to the todo entry
+	BulkTodoStatusInvalid string = "invalid"
+)
+
+// ReminderAdjustment records a reminder of a todo entry which is not sent at the requested time because of the quiet hours
+type ReminderAdjustment struct {
+	Kind          string     `json:"kind" bson:"kind"`     //ReminderKindDue or ReminderKindReminder
+	Action        string     `json:"action" bson:"action"` //QuietHoursActionShift or QuietHoursActionDrop
+	RequestedTime time.Time  `json:"requested_time" bson:"requested_time"`
+	ScheduledTime *time.Time `json:"scheduled_time" bson:"scheduled_time"` //nil when the reminder is dropped
+} // @name ReminderAdjustment
+
 // MessageIDs is used to collect due and reminder time messages
 type MessageIDs struct {
 	ReminderDateMessageID *string `json:"reminder_date_message_id" bson:"reminder_date_message_id"`
@@ -79,3 +194,31 @@ type CategoryRef struct {
 	Name   string `json:"name" bson:"name"`
 	Color  string `json:"color" bson:"color"`
 } // @name CategoryRef
+
+// TodoTemplate is a reusable todo entry of a user or, without a user, of all the users of the app/org. The reminder and the
+// work days are relative to the due time given when the template is used.
+type TodoTemplate struct {
+	ID              string       `json:"id" bson:"_id"`
+	OrgID           string       `json:"org_id" bson:"org_id"`
+	AppID           string       `json:"app_id" bson:"app_id"`
+	UserID          string       `json:"user_id" bson:"user_id"` //empty for the templates of the app/org published by the admins
+	Name            string       `json:"name" bson:"name"`
+	Title           string       `json:"title" bson:"title"`
+	Description     string       `json:"description" bson:"description"`
+	Category        *CategoryRef `json:"category" bson:"category"` //only in the templates of a user
+	Subtasks        []string     `json:"subtasks" bson:"subtasks"` //the titles of the todo entries created along with the main one
+	Location        *string      `json:"location" bson:"location"`
+	ReminderType    string       `json:"reminder_type" bson:"reminder_type"`
+	ReminderOffsets []int        `json:"reminder_offsets" bson:"reminder_offsets"` //minutes before the due time, the first one is used
+	WorkDayOffsets  []int        `json:"work_day_offsets" bson:"work_day_offsets"` //days before the due date
+	Recurrence      string       `json:"recurrence" bson:"recurrence"`
+	DateCreated     time.Time    `json:"date_created" bson:"date_created"`
+	DateUpdated     *time.Time   `json:"date_updated" bson:"date_updated"`
+} // @name TodoTemplate
+
+// TodoTemplateInstance the values of the todo entries created from a template
+type TodoTemplateInstance struct {
+	DueDateTime *time.Time //the due time of the entries - no due time, reminder and work days when nil
+	HasDueTime  bool
+	CategoryID  *string //replaces the category of the template - no category when empty
+}
@@ -14,10 +14,59 @@
 
 package model
 
+import (
+	"slices"
+	"time"
+)
+
+const (
+	//QuietHoursActionShift moves the reminders inside the quiet hours to the end of the quiet hours
+	QuietHoursActionShift string = "shift"
+	//QuietHoursActionDrop does not send the reminders inside the quiet hours
+	QuietHoursActionDrop string = "drop"
+)
+
 // UserDataResponse user todo entry
 type UserDataResponse struct {
-	Rings          []Ring         `json:"my_rings"`
-	RingsRecord    []RingRecord   `json:"my_rings_records"`
-	TodoEntries    []TodoEntry    `json:"todo_entries"`
-	TodoCategories []TodoCategory `json:"todo_categories"`
+	Rings          []Ring            `json:"my_rings"`
+	RingsRecord    []RingRecord      `json:"my_rings_records"`
+	TodoEntries    []TodoEntry       `json:"todo_entries"`
+	TodoCategories []TodoCategory    `json:"todo_categories"`
+	TodoTemplates  []TodoTemplate    `json:"todo_templates"`
+	Preferences    []UserPreferences `json:"preferences"`
 } // @name UserDataResponse
+
+// UserPreferences the settings of a user in an app/org. The empty values fall back to the app/org config and the defaults.
+type UserPreferences struct {
+	ID       string `json:"id" bson:"_id"`
+	AppID    string `json:"app_id" bson:"app_id"`
+	OrgID    string `json:"org_id" bson:"org_id"`
+	UserID   string `json:"user_id" bson:"user_id"`
+	Timezone string `json:"timezone" bson:"timezone"` //IANA time zone, eg. America/Chicago
+	Language string `json:"language" bson:"language"` //BCP 47 tag, eg. es or zh-Hans
+	//the first day of the week of the rings summaries - 0 for Sunday
+	WeekStartDay time.Weekday `json:"week_start_day" bson:"week_start_day"`
+	//minutes before the due time, the first one is the reminder of the new todo entries which have no reminder time
+	DefaultReminderOffsets []int       `json:"default_reminder_offsets" bson:"default_reminder_offsets"`
+	QuietHours             *QuietHours `json:"quiet_hours" bson:"quiet_hours"`
+	//the kinds of the notifications the user does not want to get, eg. ReminderKindDue
+	NotificationOptOuts []string `json:"notification_opt_outs" bson:"notification_opt_outs"`
+	//the local time of the daily digest like 15:04 - no digest when empty
+	DigestTime string `json:"digest_time" bson:"digest_time"`
+	//the local date of the last sent daily digest, so it is sent once a day
+	LastDigestDate string     `json:"last_digest_date" bson:"last_digest_date"`
+	DateCreated    time.Time  `json:"date_created" bson:"date_created"`
+	DateUpdated    *time.Time `json:"date_updated" bson:"date_updated"`
+} // @name UserPreferences
+
+// IsOptedOut checks if the user does not want to get the notifications of the kind
+func (p UserPreferences) IsOptedOut(kind string) bool {
+	return slices.Contains(p.NotificationOptOuts, kind)
+}
+
+// QuietHours the daily time range without reminders in the time zone of the user. It wraps midnight when the end is before the start.
+type QuietHours struct {
+	Start  string `json:"start" bson:"start"`   //15:04
+	End    string `json:"end" bson:"end"`       //15:04
+	Action string `json:"action" bson:"action"` //QuietHoursActionShift or QuietHoursActionDrop
+} // @name QuietHours
@@ -0,0 +1,206 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ringNudgesJobName = "ring_nudges"
+
+	//how many rings the ring nudges read at once
+	ringNudgesBatchSize = 500
+
+	//the title of the ring nudge notification when the app/org has no ring nudge template for the language of the user
+	ringNudgeTitle = "Wellness Rings"
+)
+
+// sendRingNudges nudges the users whose rings with enabled nudges are behind the goal of the day once the nudge time of
+// their app/org has come. A ring gets one nudge a day even when the job runs many times or on many instances. The rings
+// are read in batches, without the rings of the users who got all their nudges of the week.
+func (app *Application) sendRingNudges() {
+	now := time.Now()
+	caps := app.ringNudgesCaps()
+	sent := 0
+	afterID := ""
+	for {
+		rings, err := app.storage.GetRingsWithNudges(afterID, ringNudgesBatchSize, now.AddDate(0, 0, -7), caps, model.DefaultMaxRingNudgesPerWeek)
+		if err != nil {
+			app.logger.Errorf("error on getting the rings with nudges - %s", err)
+			break
+		}
+
+		for _, ring := range rings {
+			if app.scheduler.stopping() {
+				app.logger.Infof("ring nudges - stopping after %d nudges, the remaining rings are checked on the next run", sent)
+				return
+			}
+
+			ok, err := app.sendRingNudge(ring, now)
+			if err != nil {
+				app.logger.Errorf("error on nudging the ring %s of %s - %s", ring.ID, ring.UserID, err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+
+		if int64(len(rings)) < ringNudgesBatchSize {
+			break
+		}
+		afterID = rings[len(rings)-1].ID
+	}
+	app.logger.Infof("ring nudges - sent %d nudges", sent)
+}
+
+// ringNudgesCaps gives the weekly caps of the ring nudges of the apps/orgs which configure one
+func (app *Application) ringNudgesCaps() []model.RingNudgesCap {
+	app.cacheLock.Lock()
+	defer app.cacheLock.Unlock()
+
+	var caps []model.RingNudgesCap
+	for _, config := range app.configs {
+		if config.Settings.MaxRingNudgesPerWeek > 0 {
+			caps = append(caps, model.RingNudgesCap{AppID: config.AppID, OrgID: config.OrgID, Max: config.Settings.MaxRingNudgesPerWeek})
+		}
+	}
+	return caps
+}
+
+// sendRingNudge nudges the user if the ring is behind the goal of the day after the nudge time, outside the quiet hours
+// and within the weekly cap of the app/org. It gives true if the nudge was sent.
+func (app *Application) sendRingNudge(ring model.Ring, now time.Time) (bool, error) {
+	appID, orgID, userID := ring.AppID, ring.OrgID, ring.UserID
+
+	preferences := app.userPreferences(appID, orgID, userID)
+	if preferences.IsOptedOut(model.NotificationKindRingNudge) {
+		return false, nil
+	}
+
+	settings := app.getConfigSettings(appID, orgID)
+	if !settings.IsFeatureEnabled(model.FeatureRingNudges) {
+		return false, nil
+	}
+	nudgeTime := settings.RingNudgeTime
+	if nudgeTime == "" {
+		nudgeTime = model.DefaultRingNudgeTime
+	}
+	parsedNudgeTime, err := time.Parse(quietHoursLayout, nudgeTime)
+	if err != nil {
+		return false, fmt.Errorf("invalid ring nudge time %s", nudgeTime)
+	}
+
+	language, location := app.preferencesLocale(appID, orgID, userID, preferences)
+	localNow := now.In(location)
+	dayStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, location)
+	if localNow.Before(dayStart.Add(time.Duration(parsedNudgeTime.Hour())*time.Hour + time.Duration(parsedNudgeTime.Minute())*time.Minute)) {
+		return false, nil
+	}
+	if _, inside := quietHoursEnd(preferences.QuietHours, localNow); inside {
+		return false, nil
+	}
+
+	goal := ring.EffectiveHistoryEntry(localNow)
+	if goal == nil {
+		return false, nil
+	}
+	totals, err := app.ringTotals(appID, orgID, userID, dayStart)
+	if err != nil {
+		return false, err
+	}
+	value := totals[ring.ID]
+	if value >= goal.Value {
+		return false, nil
+	}
+
+	maxNudges := settings.MaxRingNudgesPerWeek
+	if maxNudges <= 0 {
+		maxNudges = model.DefaultMaxRingNudgesPerWeek
+	}
+	count, err := app.storage.CountRingNudges(appID, orgID, userID, now.AddDate(0, 0, -7))
+	if err != nil {
+		return false, err
+	}
+	if count >= int64(maxNudges) {
+		return false, nil
+	}
+
+	//record the nudge before sending it, so a failed record never leads to a second nudge the same day
+	today := localNow.Format(ringSummaryDateLayout)
+	nudge := model.RingNudge{ID: uuid.NewString(), AppID: appID, OrgID: orgID, UserID: userID, RingID: ring.ID,
+		Date: today, Value: value, Goal: goal.Value, DateCreated: now.UTC()}
+	inserted, err := app.storage.InsertRingNudge(nudge)
+	if err != nil {
+		return false, err
+	}
+	if !inserted {
+		return false, nil
+	}
+
+	topic := "ring nudge"
+	data := map[string]string{
+		"type":        "wellness_ring_nudge",
+		"operation":   "ring_nudge",
+		"entity_type": "wellness_ring",
+		"entity_id":   ring.ID,
+		"entity_name": goal.Name,
+	}
+	title, text := app.ringNudgeContent(appID, orgID, language, model.RingNudgeTemplateData{Ring: goal.Name, Unit: goal.Unit,
+		Goal: goal.Value, Value: value, Left: goal.Value - value})
+	_, err = app.notifications.SendNotification([]model.NotificationRecipient{{UserID: userID}}, &topic,
+		title, text, appID, orgID, nil, data)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ringNudgeContent gives the title and the text of a ring nudge. It renders the ring nudge template of the app/org in the
+// language of the user, falling back to the language of the app/org, to English and at last to the built-in English text.
+func (app *Application) ringNudgeContent(appID string, orgID string, language string, data model.RingNudgeTemplateData) (string, string) {
+	subject, body, ok := app.renderTemplateOfKind(appID, orgID, model.TemplateKindRingNudge, language, data)
+	if !ok {
+		return ringNudgeTitle, defaultRingNudgeText(data)
+	}
+	return subject, body
+}
+
+// defaultRingNudgeText tells in English how much is left to hit the goal, eg. "2 glasses left to hit your water ring"
+func defaultRingNudgeText(data model.RingNudgeTemplateData) string {
+	left := strconv.FormatFloat(data.Left, 'f', -1, 64)
+	if data.Unit == "" {
+		return fmt.Sprintf("%s left to hit your %s ring", left, data.Ring)
+	}
+	return fmt.Sprintf("%s %s left to hit your %s ring", left, data.Unit, data.Ring)
+}
+
+// updateRingNudges turns the evening nudges of a ring on or off
+func (app *Application) updateRingNudges(appID string, orgID string, userID string, id string, enabled bool) (*model.Ring, error) {
+	ring, err := app.storage.UpdateRingNudges(appID, orgID, userID, id, enabled)
+	if err != nil {
+		return nil, err
+	}
+	if ring == nil {
+		return nil, model.NewNotFoundError("ring", id)
+	}
+	return ring, nil
+}
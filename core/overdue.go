@@ -0,0 +1,166 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+	"wellness/core/model"
+	"wellness/driven/storage"
+)
+
+const (
+	overdueJobName = "overdue_sweeper"
+
+	//how many todo entries the sweeper checks in one transaction
+	overdueBatchSize = 500
+)
+
+// overdueEntry is an overdue todo entry with the time zone of its user
+type overdueEntry struct {
+	entry    model.TodoEntry
+	location *time.Location
+}
+
+// sweepOverdueTodoEntries marks the todo entries whose due time has passed as overdue. The overdue recurring entries of
+// the apps/orgs which roll them forward are moved to their next occurrence instead. The entries are processed in batches,
+// every one in its own transaction.
+func (app *Application) sweepOverdueTodoEntries() {
+	now := time.Now().UTC()
+
+	marked, rolled := 0, 0
+	afterID := ""
+	for {
+		if app.scheduler.stopping() {
+			app.logger.Info("overdue sweeper - stopping, the remaining entries are processed on the next run")
+			break
+		}
+
+		batchMarked, toRoll, lastID, err := app.markOverdueBatch(now, afterID)
+		if err != nil {
+			app.logger.Errorf("error on marking the overdue todo entries - %s", err)
+			break
+		}
+		marked += batchMarked
+
+		for _, item := range toRoll {
+			entry := item.entry
+			_, err := app.patchTodoEntry(entry.AppID, entry.OrgID, entry.UserID, entry.ID, func(current model.TodoEntry) (*model.TodoEntry, error) {
+				rollForward(&current, now, item.location)
+				return &current, nil
+			})
+			if err != nil {
+				app.logger.Errorf("error on rolling forward the todo entry %s - %s", entry.ID, err)
+				continue
+			}
+			rolled++
+		}
+
+		if lastID == "" {
+			break
+		}
+		afterID = lastID
+	}
+	app.logger.Infof("overdue sweeper - marked %d todo entries overdue, rolled forward %d", marked, rolled)
+}
+
+// markOverdueBatch marks the overdue entries of the batch after the id in a transaction. It gives how many entries were
+// marked, the entries to roll forward and the last id of the batch - empty when it was the last batch.
+func (app *Application) markOverdueBatch(now time.Time, afterID string) (int, []overdueEntry, string, error) {
+	var ids []string
+	var toRoll []overdueEntry
+	lastID := ""
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		ids, toRoll, lastID = []string{}, nil, ""
+		entries, err := app.storage.GetTodoEntriesToMarkOverdue(context, now, afterID, overdueBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(entries) == overdueBatchSize {
+			lastID = entries[len(entries)-1].ID
+		}
+
+		//the time zones of the users of the batch
+		locations := map[string]*time.Location{}
+		for _, entry := range entries {
+			key := entry.AppID + "/" + entry.OrgID + "/" + entry.UserID
+			location, ok := locations[key]
+			if !ok {
+				_, location = app.userLocale(entry.AppID, entry.OrgID, entry.UserID)
+				locations[key] = location
+			}
+			if !entry.IsOverdue(now, location) {
+				continue
+			}
+
+			if app.rollsForward(entry) {
+				toRoll = append(toRoll, overdueEntry{entry: entry, location: location})
+			} else {
+				ids = append(ids, entry.ID)
+			}
+		}
+		err = app.storage.MarkTodoEntriesOverdue(context, ids)
+		if err != nil {
+			return err
+		}
+		//record when the entries were processed by the sweeper
+		return app.storage.UpdateTodoEntriesTaskTime(context, ids, now)
+	})
+	if err != nil {
+		return 0, nil, "", err
+	}
+	return len(ids), toRoll, lastID, nil
+}
+
+// rollsForward tells if the overdue entry is moved to its next occurrence instead of being marked overdue
+func (app *Application) rollsForward(entry model.TodoEntry) bool {
+	return entry.Recurrence != "" && app.getConfigSettings(entry.AppID, entry.OrgID).RollForwardRecurring
+}
+
+// rollForward moves the due and the reminder times of a recurring entry by whole recurrences until none of them has passed
+// in the time zone of the user
+func rollForward(todo *model.TodoEntry, at time.Time, location *time.Location) {
+	for todo.DueDateTime != nil && (todo.IsOverdue(at, location) || (todo.ReminderDateTime != nil && todo.ReminderDateTime.Before(at))) {
+		due := todo.NextOccurrence(*todo.DueDateTime)
+		if due == nil {
+			return
+		}
+		todo.DueDateTime = due
+		if todo.ReminderDateTime != nil {
+			todo.ReminderDateTime = todo.NextOccurrence(*todo.ReminderDateTime)
+		}
+	}
+}
+
+// getOverdueSummary counts the todo entries of the user which are overdue now, see TodoEntry.IsOverdue
+func (app *Application) getOverdueSummary(appID string, orgID string, userID string) (*model.OverdueSummary, error) {
+	entries, err := app.storage.GetTodoEntries(nil, appID, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, location := app.userLocale(appID, orgID, userID)
+	summary := model.OverdueSummary{Categories: map[string]int{}}
+	for i := range entries {
+		if !entries[i].IsOverdue(now, location) {
+			continue
+		}
+		summary.Count++
+		if entries[i].Category != nil {
+			summary.Categories[entries[i].Category.ID]++
+		}
+	}
+	return &summary, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+func TestIsOverdueAllDayInUserZone(t *testing.T) {
+	due := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	todo := model.TodoEntry{DueDateTime: &due}
+	at := time.Date(2026, 3, 11, 3, 0, 0, 0, time.UTC)
+
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("error loading the time zone - %s", err)
+	}
+	for _, item := range []struct {
+		location *time.Location
+		overdue  bool
+	}{
+		{time.UTC, true},
+		{chicago, false},
+		{time.FixedZone("UTC+14", int(model.EarliestDayEndOffset.Seconds())), true},
+	} {
+		if got := todo.IsOverdue(at, item.location); got != item.overdue {
+			t.Errorf("IsOverdue() in %s = %v, want %v", item.location, got, item.overdue)
+		}
+	}
+}
+
+func TestSweepOverdueTodoEntriesInBatches(t *testing.T) {
+	app, _ := newTestApplication(t)
+	due := time.Now().Add(-time.Hour)
+	count := overdueBatchSize + 2
+	for i := 0; i < count; i++ {
+		//stored directly, so the entries are not marked overdue on creation
+		_, err := app.storage.CreateTodoEntry(nil, "app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due},
+			model.MessageIDs{}, uuid.NewString())
+		if err != nil {
+			t.Fatalf("CreateTodoEntry() error = %v", err)
+		}
+	}
+
+	app.sweepOverdueTodoEntries()
+
+	entries, err := app.storage.GetTodoEntries(nil, "app", "org", "user")
+	if err != nil {
+		t.Fatalf("GetTodoEntries() error = %v", err)
+	}
+	overdue := 0
+	for _, entry := range entries {
+		if entry.Overdue {
+			overdue++
+		}
+	}
+	if len(entries) != count || overdue != count {
+		t.Fatalf("the sweeper marked %d of %d entries overdue, want all of them", overdue, len(entries))
+	}
+}
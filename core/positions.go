@@ -0,0 +1,163 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"wellness/core/model"
+	"wellness/driven/storage"
+)
+
+// minPositionGap the smallest gap between two neighbors an item is moved into. The list is renumbered when the gap is
+// smaller, so the positions keep their precision.
+const minPositionGap = 1e-6
+
+// positionedItem an item of a manually ordered list
+type positionedItem struct {
+	id       string
+	position float64
+}
+
+// nextPosition gives the position after the last position of a list, so a new item is added at the end of the list
+func nextPosition(lastPosition float64) float64 {
+	return max(lastPosition, 0) + 1
+}
+
+// movePosition gives the positions to store for moving the item right after the item with afterID - to the top of the
+// list when afterID is nil. The items must be sorted by their positions. Only the moved item gets a new position, unless
+// there is no room between its new neighbors and the whole list is renumbered. The entity names the items in the errors.
+func movePosition(entity string, items []positionedItem, id string, afterID *string) (map[string]float64, error) {
+	others := make([]positionedItem, 0, len(items))
+	for _, item := range items {
+		if item.id != id {
+			others = append(others, item)
+		}
+	}
+
+	index := 0
+	if afterID != nil {
+		if *afterID == id {
+			return nil, model.NewFieldsValidationError(model.FieldError{Field: "after_id", Message: "must differ from the moved item"})
+		}
+		index = -1
+		for i, item := range others {
+			if item.id == *afterID {
+				index = i + 1
+				break
+			}
+		}
+		if index < 0 {
+			return nil, model.NewNotFoundError(entity, *afterID)
+		}
+	}
+
+	switch {
+	case len(others) == 0:
+		return map[string]float64{id: 1}, nil
+	case index == 0:
+		return map[string]float64{id: others[0].position - 1}, nil
+	case index == len(others):
+		return map[string]float64{id: others[index-1].position + 1}, nil
+	}
+
+	before, after := others[index-1].position, others[index].position
+	if after-before >= minPositionGap {
+		return map[string]float64{id: (before + after) / 2}, nil
+	}
+
+	positions := map[string]float64{}
+	for i, item := range others {
+		position := float64(i + 1)
+		if i >= index {
+			position++
+		}
+		positions[item.id] = position
+	}
+	positions[id] = float64(index + 1)
+	return positions, nil
+}
+
+func todoEntryPositions(entries []model.TodoEntry) []positionedItem {
+	items := make([]positionedItem, len(entries))
+	for i, entry := range entries {
+		items[i] = positionedItem{id: entry.ID, position: entry.Position}
+	}
+	return items
+}
+
+func todoCategoryPositions(categories []model.TodoCategory) []positionedItem {
+	items := make([]positionedItem, len(categories))
+	for i, category := range categories {
+		items[i] = positionedItem{id: category.ID, position: category.Position}
+	}
+	return items
+}
+
+// moveTodoEntry moves the todo entry right after another entry of the user - to the top when afterID is nil
+func (app *Application) moveTodoEntry(appID string, orgID string, userID string, id string, afterID *string) (*model.TodoEntry, error) {
+	var moved *model.TodoEntry
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		current, err := app.storage.GetTodoEntry(context, appID, orgID, userID, id)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return model.NewNotFoundError("todo entry", id)
+		}
+
+		entries, err := app.storage.GetTodoEntries(context, appID, orgID, userID)
+		if err != nil {
+			return err
+		}
+		positions, err := movePosition("todo entry", todoEntryPositions(entries), id, afterID)
+		if err != nil {
+			return err
+		}
+		err = app.storage.UpdateTodoEntryPositions(context, appID, orgID, userID, positions)
+		if err != nil {
+			return err
+		}
+
+		moved, err = app.storage.GetTodoEntry(context, appID, orgID, userID, id)
+		return err
+	})
+	return moved, err
+}
+
+// moveTodoCategory moves the todo category right after another category of the user - to the top when afterID is nil
+func (app *Application) moveTodoCategory(appID string, orgID string, userID string, id string, afterID *string) (*model.TodoCategory, error) {
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		current, err := app.storage.GetTodoCategory(context, appID, orgID, userID, id)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return model.NewNotFoundError("todo category", id)
+		}
+
+		categories, err := app.storage.GetTodoCategories(context, appID, orgID, userID)
+		if err != nil {
+			return err
+		}
+		positions, err := movePosition("todo category", todoCategoryPositions(categories), id, afterID)
+		if err != nil {
+			return err
+		}
+		return app.storage.UpdateTodoCategoryPositions(context, appID, orgID, userID, positions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return app.getTodoCategory(appID, orgID, userID, id)
+}
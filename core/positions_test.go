@@ -0,0 +1,152 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+	"wellness/core/model"
+	"wellness/driven/storage"
+)
+
+func TestMovePosition(t *testing.T) {
+	after := func(id string) *string { return &id }
+	items := []positionedItem{{"a", 1}, {"b", 2}, {"c", 3}}
+
+	for _, item := range []struct {
+		name      string
+		items     []positionedItem
+		id        string
+		afterID   *string
+		positions map[string]float64
+	}{
+		{"between neighbors", items, "c", after("a"), map[string]float64{"c": 1.5}},
+		{"to the top", items, "c", nil, map[string]float64{"c": 0}},
+		{"to the bottom", items, "a", after("c"), map[string]float64{"a": 4}},
+		{"in place", items, "b", after("a"), map[string]float64{"b": 2}},
+		{"into an empty list", []positionedItem{{"a", 5}}, "a", nil, map[string]float64{"a": 1}},
+		{"renumbered when the gap is too small", []positionedItem{{"a", 1}, {"b", 1 + minPositionGap/2}, {"c", 3}}, "c", after("a"),
+			map[string]float64{"a": 1, "c": 2, "b": 3}},
+		{"legacy items without positions", []positionedItem{{"a", 0}, {"b", 0}, {"c", 0}}, "c", after("a"),
+			map[string]float64{"a": 1, "c": 2, "b": 3}},
+		{"legacy items to the top", []positionedItem{{"a", 0}, {"b", 0}}, "b", nil, map[string]float64{"b": -1}},
+	} {
+		positions, err := movePosition("item", item.items, item.id, item.afterID)
+		if err != nil {
+			t.Errorf("%s: error moving - %s", item.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(positions, item.positions) {
+			t.Errorf("%s: positions %v, expected %v", item.name, positions, item.positions)
+		}
+	}
+}
+
+func TestMovePositionErrors(t *testing.T) {
+	items := []positionedItem{{"a", 1}, {"b", 2}}
+
+	self := "a"
+	_, err := movePosition("item", items, "a", &self)
+	if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeValidation {
+		t.Errorf("moving after itself gave %v, expected a validation error", err)
+	}
+
+	unknown := "z"
+	_, err = movePosition("item", items, "a", &unknown)
+	if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeNotFound {
+		t.Errorf("moving after an unknown item gave %v, expected a not found error", err)
+	}
+}
+
+func TestCreateTodoEntryAddsToTheEnd(t *testing.T) {
+	app, _ := newTestApplication(t)
+	for _, id := range []string{"legacy-1", "legacy-2"} {
+		_, err := app.storage.CreateTodoEntry(nil, "app", "org", "user", &model.TodoEntry{Title: id}, model.MessageIDs{}, id)
+		if err != nil {
+			t.Fatalf("error storing the legacy entry - %s", err)
+		}
+	}
+
+	for _, expected := range []float64{1, 2} {
+		entry, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk"})
+		if err != nil {
+			t.Fatalf("createTodoEntry() error = %v", err)
+		}
+		if entry.Position != expected {
+			t.Errorf("createTodoEntry() position = %v, want %v", entry.Position, expected)
+		}
+	}
+}
+
+// transactionReadsStorage records if the user lists are read in a transaction
+type transactionReadsStorage struct {
+	Storage
+	reads        int
+	transactions int
+}
+
+func (s *transactionReadsStorage) GetTodoEntries(context storage.TransactionContext, appID string, orgID string, userID string) ([]model.TodoEntry, error) {
+	s.count(context)
+	return s.Storage.GetTodoEntries(context, appID, orgID, userID)
+}
+
+func (s *transactionReadsStorage) GetTodoCategories(context storage.TransactionContext, appID string, orgID string, userID string) ([]model.TodoCategory, error) {
+	s.count(context)
+	return s.Storage.GetTodoCategories(context, appID, orgID, userID)
+}
+
+func (s *transactionReadsStorage) count(context storage.TransactionContext) {
+	s.reads++
+	if context != nil {
+		s.transactions++
+	}
+}
+
+func TestMoveReadsTheListsInTheTransaction(t *testing.T) {
+	app, _ := newTestApplication(t)
+	var categories []*model.TodoCategory
+	var entries []*model.TodoEntry
+	for _, name := range []string{"Health", "Study"} {
+		category, err := app.createTodoCategory("app", "org", "user", &model.TodoCategory{Name: name})
+		if err != nil {
+			t.Fatalf("createTodoCategory() error = %v", err)
+		}
+		categories = append(categories, category)
+		entry, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: name})
+		if err != nil {
+			t.Fatalf("createTodoEntry() error = %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	for i, category := range categories {
+		if category.Position != float64(i+1) {
+			t.Errorf("createTodoCategory() %s position = %v, want %v", category.Name, category.Position, i+1)
+		}
+	}
+
+	store := &transactionReadsStorage{Storage: app.storage}
+	app.storage = store
+	movedCategory, err := app.moveTodoCategory("app", "org", "user", categories[1].ID, nil)
+	if err != nil || movedCategory.Position >= categories[0].Position {
+		t.Fatalf("moveTodoCategory() = %+v, %v, want the category at the top", movedCategory, err)
+	}
+	movedEntry, err := app.moveTodoEntry("app", "org", "user", entries[1].ID, nil)
+	if err != nil || movedEntry.Position >= entries[0].Position {
+		t.Fatalf("moveTodoEntry() = %+v, %v, want the entry at the top", movedEntry, err)
+	}
+	if store.reads != 2 || store.transactions != 2 {
+		t.Fatalf("the moves read %d lists, %d in their transactions, want both in them", store.reads, store.transactions)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+	"wellness/core/model"
+)
+
+// userPreferences gives the preferences of a user, the empty preferences when the user has none or they cannot be read
+func (app *Application) userPreferences(appID string, orgID string, userID string) model.UserPreferences {
+	preferences, err := app.storage.GetUserPreferences(appID, orgID, userID)
+	if err != nil {
+		app.logger.Errorf("error on getting the preferences of %s - %s", userID, err)
+		return model.UserPreferences{}
+	}
+	if preferences == nil {
+		return model.UserPreferences{}
+	}
+	return *preferences
+}
+
+func (app *Application) getUserPreferences(appID string, orgID string, userID string) (*model.UserPreferences, error) {
+	preferences, err := app.storage.GetUserPreferences(appID, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if preferences == nil {
+		//the user has not saved any preferences yet - all of them fall back to the defaults
+		preferences = &model.UserPreferences{AppID: appID, OrgID: orgID, UserID: userID}
+	}
+	return preferences, nil
+}
+
+func (app *Application) updateUserPreferences(appID string, orgID string, userID string, preferences model.UserPreferences) (*model.UserPreferences, error) {
+	return app.storage.SaveUserPreferences(appID, orgID, userID, preferences)
+}
+
+func (app *Application) deleteUserPreferences(appID string, orgID string, userID string) error {
+	return app.storage.DeleteUserPreferences(appID, orgID, userID)
+}
+
+// defaultReminderDateTime gives the reminder time of a new todo entry from the first default reminder offset of the user.
+// It gives nil when the user has no offsets, the entry has no due time or the reminder would be in the past.
+func defaultReminderDateTime(preferences model.UserPreferences, todo *model.TodoEntry) *time.Time {
+	if len(preferences.DefaultReminderOffsets) == 0 || todo.DueDateTime == nil {
+		return nil
+	}
+
+	reminder := todo.DueDateTime.Add(-time.Duration(preferences.DefaultReminderOffsets[0]) * time.Minute)
+	if reminder.Before(time.Now()) {
+		return nil
+	}
+	return &reminder
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"slices"
+	"time"
+	"wellness/core/model"
+)
+
+// quietHoursLayout the layout of the start and the end of the quiet hours
+const quietHoursLayout = "15:04"
+
+// scheduleTime gives the time to send a reminder of the todo entry at - nil when the reminder is dropped because of the
+// quiet hours of the user. The adjustment is recorded on the entry, replacing the previous one of the same kind.
+func (app *Application) scheduleTime(appID string, orgID string, userID string, preferences model.UserPreferences,
+	todo *model.TodoEntry, kind string, requested time.Time) *time.Time {
+	todo.ReminderAdjustments = withoutReminderAdjustment(todo.ReminderAdjustments, kind)
+
+	_, location := app.preferencesLocale(appID, orgID, userID, preferences)
+	end, inside := quietHoursEnd(preferences.QuietHours, requested.In(location))
+	if !inside {
+		return &requested
+	}
+
+	adjustment := model.ReminderAdjustment{Kind: kind, Action: preferences.QuietHours.Action, RequestedTime: requested}
+	if preferences.QuietHours.Action == model.QuietHoursActionShift {
+		scheduled := end.UTC()
+		adjustment.ScheduledTime = &scheduled
+	}
+	todo.ReminderAdjustments = append(todo.ReminderAdjustments, adjustment)
+	return adjustment.ScheduledTime
+}
+
+// quietHoursEnd checks if the time is inside the quiet hours and gives the end of those quiet hours. The time must be in the
+// time zone of the user. The quiet hours which end before they start wrap midnight.
+func quietHoursEnd(quietHours *model.QuietHours, at time.Time) (time.Time, bool) {
+	if quietHours == nil {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(quietHoursLayout, quietHours.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse(quietHoursLayout, quietHours.End)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	minute := at.Hour()*60 + at.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	endDay := at
+	switch {
+	case startMinute < endMinute:
+		if minute < startMinute || minute >= endMinute {
+			return time.Time{}, false
+		}
+	case startMinute > endMinute:
+		if minute < startMinute && minute >= endMinute {
+			return time.Time{}, false
+		}
+		if minute >= startMinute {
+			endDay = at.AddDate(0, 0, 1)
+		}
+	default:
+		return time.Time{}, false
+	}
+	return time.Date(endDay.Year(), endDay.Month(), endDay.Day(), end.Hour(), end.Minute(), 0, 0, at.Location()), true
+}
+
+func withoutReminderAdjustment(adjustments []model.ReminderAdjustment, kind string) []model.ReminderAdjustment {
+	return slices.DeleteFunc(slices.Clone(adjustments), func(item model.ReminderAdjustment) bool { return item.Kind == kind })
+}
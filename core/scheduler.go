@@ -0,0 +1,185 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wellness/core/model"
+	"wellness/utils"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+const (
+	//how long the lock is kept after a run, so the instances with a slightly late clock do not repeat it
+	schedulerRunGrace = 5 * time.Minute
+	//how long a triggered run waits for the job lock
+	schedulerTriggerWait = 30 * time.Second
+)
+
+// scheduledJob is a background job which runs on a cron-like schedule
+type scheduledJob struct {
+	name     string
+	schedule *utils.CronSchedule
+	location *time.Location
+	run      func()
+
+	//the triggered runs - the result of acquiring the job lock is sent to the channel of the run
+	trigger chan chan error
+	running atomic.Bool
+}
+
+// jobScheduler runs the scheduled jobs. Every run is guarded by a lock, so only one of the service
+// instances executes a job at a time.
+type jobScheduler struct {
+	logger *logs.Logger
+	locks  *lockManager
+
+	jobs map[string]*scheduledJob
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// addJob registers a job. It must be called before start.
+func (s *jobScheduler) addJob(name string, expression string, timezone string, run func()) error {
+	schedule, err := utils.ParseCronExpression(expression)
+	if err != nil {
+		return fmt.Errorf("error parsing the schedule of %s job - %s", name, err)
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("error loading the timezone of %s job - %s", name, err)
+	}
+
+	s.jobs[name] = &scheduledJob{name: name, schedule: schedule, location: location, run: run, trigger: make(chan chan error, 1)}
+	return nil
+}
+
+func (s *jobScheduler) start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+}
+
+// stop cancels the pending runs and waits for the running jobs to finish
+func (s *jobScheduler) stop() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+// stopping tells if the scheduler is being stopped, so the running jobs can finish early at a safe point
+func (s *jobScheduler) stopping() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// runNow triggers an immediate run of a job. It waits until the run has started, so it gives a conflict error when
+// the run is skipped because the job is running on this instance or another instance holds its lock.
+func (s *jobScheduler) runNow(name string) error {
+	job, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("there is no %s job", name)
+	}
+	if job.running.Load() {
+		return model.NewConflictError(fmt.Sprintf("the %s job is already running", name))
+	}
+
+	started := make(chan error, 1)
+	select {
+	case job.trigger <- started:
+	default:
+		return model.NewConflictError(fmt.Sprintf("a run of the %s job is already pending", name))
+	}
+
+	timer := time.NewTimer(schedulerTriggerWait)
+	defer timer.Stop()
+	select {
+	case err := <-started:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("the %s job has not started in %s", name, schedulerTriggerWait)
+	}
+}
+
+func (s *jobScheduler) loop(job *scheduledJob) {
+	defer s.wg.Done()
+
+	for {
+		next := job.schedule.Next(time.Now().In(job.location))
+		if next.IsZero() {
+			s.logger.Errorf("%s job - the schedule %s has no next run", job.name, job.schedule)
+			return
+		}
+		s.logger.Infof("%s job - next run at %s", job.name, next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			s.execute(job, nil)
+		case started := <-job.trigger:
+			timer.Stop()
+			s.logger.Infof("%s job - triggered", job.name)
+			s.execute(job, started)
+		case <-s.done:
+			timer.Stop()
+			s.logger.Infof("%s job - stopped", job.name)
+			return
+		}
+	}
+}
+
+// execute runs the job if its lock is acquired. The result of acquiring the lock is sent to started of a triggered run.
+func (s *jobScheduler) execute(job *scheduledJob, started chan<- error) {
+	report := func(err error) {
+		if started != nil {
+			started <- err
+		}
+	}
+
+	lock, err := s.locks.acquire("job_" + job.name)
+	if err != nil {
+		s.logger.Errorf("%s job - error acquiring lock - %s", job.name, err)
+		report(err)
+		return
+	}
+	if lock == nil {
+		s.logger.Infof("%s job - another instance is running it, skipping", job.name)
+		report(model.NewConflictError(fmt.Sprintf("the %s job is running or has just run on another instance", job.name)))
+		return
+	}
+	defer lock.release(schedulerRunGrace)
+
+	job.running.Store(true)
+	defer job.running.Store(false)
+	report(nil)
+
+	startedAt := time.Now()
+	job.run()
+	s.logger.Infof("%s job - finished in %s", job.name, time.Since(startedAt))
+}
+
+func newJobScheduler(logger *logs.Logger, locks *lockManager) *jobScheduler {
+	return &jobScheduler{logger: logger, locks: locks, jobs: map[string]*scheduledJob{}, done: make(chan struct{})}
+}
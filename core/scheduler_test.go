@@ -0,0 +1,103 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+	"time"
+	"wellness/core/model"
+	"wellness/driven/storage"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+func newTestScheduler(t *testing.T, store Storage, run func()) *jobScheduler {
+	t.Helper()
+	logger := logs.NewLogger("test", &logs.LoggerOpts{})
+	scheduler := newJobScheduler(logger, newLockManager(logger, store, "instance"))
+	//yearly, so only the triggered runs happen in the tests
+	err := scheduler.addJob("test", "0 0 1 1 *", "UTC", run)
+	if err != nil {
+		t.Fatalf("error adding the job - %s", err)
+	}
+	scheduler.start()
+	t.Cleanup(scheduler.stop)
+	return scheduler
+}
+
+func TestJobSchedulerRunNow(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	scheduler := newTestScheduler(t, storage.NewMemoryAdapter(), func() { ran <- struct{}{} })
+
+	err := scheduler.runNow("test")
+	if err != nil {
+		t.Fatalf("runNow() error = %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the triggered job did not run")
+	}
+}
+
+func TestJobSchedulerRunNowUnknownJob(t *testing.T) {
+	scheduler := newTestScheduler(t, storage.NewMemoryAdapter(), func() {})
+
+	err := scheduler.runNow("missing")
+	if err == nil {
+		t.Fatal("runNow() of an unknown job succeeded")
+	}
+}
+
+func TestJobSchedulerRunNowLockedByAnotherInstance(t *testing.T) {
+	store := storage.NewMemoryAdapter()
+	acquired, err := store.AcquireLock("job_test", "another instance", time.Now().Add(time.Hour))
+	if err != nil || !acquired {
+		t.Fatalf("error acquiring the lock for another instance - %v", err)
+	}
+	ran := make(chan struct{}, 1)
+	scheduler := newTestScheduler(t, store, func() { ran <- struct{}{} })
+
+	err = scheduler.runNow("test")
+	if typed := model.AsError(err); typed == nil || typed.Type != model.ErrorTypeConflict {
+		t.Fatalf("runNow() error = %v, want a conflict", err)
+	}
+	select {
+	case <-ran:
+		t.Fatal("the job ran while another instance held its lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestJobSchedulerRunNowWhileRunning(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	scheduler := newTestScheduler(t, storage.NewMemoryAdapter(), func() {
+		close(started)
+		<-finish
+	})
+
+	err := scheduler.runNow("test")
+	if err != nil {
+		t.Fatalf("runNow() error = %v", err)
+	}
+	<-started
+
+	err = scheduler.runNow("test")
+	close(finish)
+	if typed := model.AsError(err); typed == nil || typed.Type != model.ErrorTypeConflict {
+		t.Fatalf("runNow() of a running job error = %v, want a conflict", err)
+	}
+}
@@ -15,32 +15,98 @@
 package core
 
 import (
+	"fmt"
 	"log"
 	"strings"
+	"time"
 	"wellness/core/model"
 	"wellness/driven/storage"
 
 	"github.com/google/uuid"
 )
 
+const (
+	//the reminders a little in the past are accepted as the clocks of the devices are not exact
+	reminderPastGrace = time.Minute
+	//the layout of the days of the rings summaries
+	ringSummaryDateLayout = "2006-01-02"
+)
+
 func (app *Application) getVersion() string {
 	return app.version
 }
 
 func (app *Application) getTodoCategories(appID string, orgID string, userID string) ([]model.TodoCategory, error) {
-	return app.storage.GetTodoCategories(appID, orgID, userID)
+	return app.storage.GetTodoCategories(nil, appID, orgID, userID)
 }
 
 func (app *Application) getTodoCategory(appID string, orgID string, userID string, id string) (*model.TodoCategory, error) {
-	return app.storage.GetTodoCategory(appID, orgID, userID, id)
+	category, err := app.storage.GetTodoCategory(nil, appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, model.NewNotFoundError("todo category", id)
+	}
+	return category, nil
 }
 
 func (app *Application) createTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
-	return app.storage.CreateTodoCategory(appID, orgID, userID, category)
+	var created *model.TodoCategory
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		lastPosition, err := app.storage.GetLastTodoCategoryPosition(context, appID, orgID, userID)
+		if err != nil {
+			return err
+		}
+		category.Position = nextPosition(lastPosition)
+
+		created, err = app.storage.CreateTodoCategory(context, appID, orgID, userID, category)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// patchTodoCategory applies the patch to the current category in a transaction, so the concurrent changes of the other fields are not lost
+func (app *Application) patchTodoCategory(appID string, orgID string, userID string, id string, patch func(current model.TodoCategory) (*model.TodoCategory, error)) (*model.TodoCategory, error) {
+	var updated *model.TodoCategory
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		current, err := app.storage.GetTodoCategory(context, appID, orgID, userID, id)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return model.NewNotFoundError("todo category", id)
+		}
+
+		category, err := patch(*current)
+		if err != nil {
+			return err
+		}
+		category.ID = id
+		updated, err = app.storage.UpdateTodoCategory(context, appID, orgID, userID, category)
+		if err != nil {
+			return err
+		}
+		if updated == nil {
+			return model.NewNotFoundError("todo category", id)
+		}
+		return nil
+	})
+	return updated, err
 }
 
 func (app *Application) updateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
-	return app.storage.UpdateTodoCategory(appID, orgID, userID, category)
+	updated, err := app.storage.UpdateTodoCategory(nil, appID, orgID, userID, category)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, model.NewNotFoundError("todo category", category.ID)
+	}
+	return updated, nil
 }
 
 func (app *Application) deleteTodoCategory(appID string, orgID string, userID string, id string) error {
@@ -48,228 +114,423 @@ func (app *Application) deleteTodoCategory(appID string, orgID string, userID st
 }
 
 func (app *Application) getTodoEntries(appID string, orgID string, userID string) ([]model.TodoEntry, error) {
-	return app.storage.GetTodoEntries(appID, orgID, userID)
+	return app.storage.GetTodoEntries(nil, appID, orgID, userID)
 }
 
 func (app *Application) getTodoEntry(appID string, orgID string, userID string, id string) (*model.TodoEntry, error) {
-	return app.storage.GetTodoEntry(nil, appID, orgID, userID, id)
+	todoEntry, err := app.storage.GetTodoEntry(nil, appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if todoEntry == nil {
+		return nil, model.NewNotFoundError("todo entry", id)
+	}
+	return todoEntry, nil
 }
 
 func (app *Application) createTodoEntry(appID, orgID, userID string, todo *model.TodoEntry) (*model.TodoEntry, error) {
+	err := validateReminderDateTime(todo.ReminderDateTime, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	var created *model.TodoEntry
+	preferences := app.userPreferences(appID, orgID, userID)
+	batch := &notificationBatch{}
+
+	err = app.storage.PerformTransaction(func(ctx storage.TransactionContext) error {
+		var err error
+		created, err = app.createTodoEntryInTransaction(ctx, appID, orgID, userID, preferences, todo, batch)
+		return err
+	})
+	batch.finish(app, appID, orgID, userID, err == nil)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// createTodoEntryInTransaction adds the todo entry at the end of the user's list and schedules its reminders. The
+// scheduled messages are recorded in the batch, so they are deleted when the transaction fails.
+func (app *Application) createTodoEntryInTransaction(context storage.TransactionContext, appID string, orgID string, userID string,
+	preferences model.UserPreferences, todo *model.TodoEntry, batch *notificationBatch) (*model.TodoEntry, error) {
 	entityID := uuid.NewString()
 
-	err := app.storage.PerformTransaction(func(ctx storage.TransactionContext) error {
-		topic := "create todo entry"
-		var dueMsgID *string
-		var reminderMsgID *string
-
-		// Normalize the reminder type
-		rt := strings.ToLower(strings.TrimSpace(todo.ReminderType))
-		remindersEnabled := rt != "" && rt != "none"
-
-		if remindersEnabled {
-			log.Printf("Reminders are ENABLED for this to-do entry: %s", todo.Title)
-
-			// If your product only wants a due-time notification when it's the chosen mode,
-			// gate it explicitly (adjust values to your actual enum):
-			includesDue := rt == "at_due_time" || rt == "both"
-
-			if includesDue && todo.DueDateTime != nil {
-				dueUnix := todo.DueDateTime.Unix()
-				id, err := app.notifications.SendNotification(
-					[]model.NotificationRecipient{{UserID: userID}},
-					&topic, "To-Do List Reminder", todo.Title, appID, orgID, &dueUnix,
-					map[string]string{
-						"type":        "wellness_todo_entry",
-						"operation":   "todo_reminder",
-						"entity_type": "wellness_todo_entry",
-						"entity_id":   entityID,
-						"entity_name": todo.Title,
-					},
-				)
-				if err != nil {
-					log.Printf("Error sending DueDateTime notification for %s: %v", todo.ID, err)
-				} else {
-					dueMsgID = id
-					log.Printf("Successfully sent DueDateTime notification for %s", entityID)
-				}
+	lastPosition, err := app.storage.GetLastTodoEntryPosition(context, appID, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	todo.Position = nextPosition(lastPosition)
+
+	topic := "create todo entry"
+	var dueMsgID *string
+	var reminderMsgID *string
+
+	// Normalize the reminder type
+	rt := strings.ToLower(strings.TrimSpace(todo.ReminderType))
+	remindersEnabled := rt != "" && rt != "none"
+
+	if remindersEnabled {
+		log.Printf("Reminders are ENABLED for this to-do entry: %s", todo.Title)
+
+		// If your product only wants a due-time notification when it's the chosen mode,
+		// gate it explicitly (adjust values to your actual enum):
+		includesDue := rt == "at_due_time" || rt == "both"
+
+		if rt != "at_due_time" && todo.ReminderDateTime == nil {
+			todo.ReminderDateTime = defaultReminderDateTime(preferences, todo)
+		}
+
+		var dueAt, reminderAt *time.Time
+		if includesDue && todo.DueDateTime != nil && !preferences.IsOptedOut(model.ReminderKindDue) {
+			dueAt = app.scheduleTime(appID, orgID, userID, preferences, todo, model.ReminderKindDue, *todo.DueDateTime)
+		}
+		if todo.ReminderDateTime != nil && !preferences.IsOptedOut(model.ReminderKindReminder) {
+			reminderAt = app.scheduleTime(appID, orgID, userID, preferences, todo, model.ReminderKindReminder, *todo.ReminderDateTime)
+		}
+
+		if dueAt != nil {
+			dueUnix := dueAt.Unix()
+			dueTitle, dueText := app.reminderContent(appID, orgID, userID, preferences, todo, model.ReminderKindDue)
+			id, err := app.notifications.SendNotification(
+				[]model.NotificationRecipient{{UserID: userID}},
+				&topic, dueTitle, dueText, appID, orgID, &dueUnix,
+				map[string]string{
+					"type":        "wellness_todo_entry",
+					"operation":   "todo_reminder",
+					"entity_type": "wellness_todo_entry",
+					"entity_id":   entityID,
+					"entity_name": todo.Title,
+				},
+			)
+			if err != nil {
+				log.Printf("Error sending DueDateTime notification for %s: %v", todo.ID, err)
+			} else {
+				dueMsgID = id
+				batch.created(entityID, id)
+				log.Printf("Successfully sent DueDateTime notification for %s", entityID)
 			}
+		}
 
-			if todo.ReminderDateTime != nil {
-				remUnix := todo.ReminderDateTime.Unix()
-				id, err := app.notifications.SendNotification(
-					[]model.NotificationRecipient{{UserID: userID}},
-					&topic, "To-Do List Reminder", todo.Title, appID, orgID, &remUnix,
-					map[string]string{
-						"type":        "wellness_todo_entry",
-						"operation":   "todo_reminder",
-						"entity_type": "wellness_todo_entry",
-						"entity_id":   entityID,
-						"entity_name": todo.Title,
-					},
-				)
-				if err != nil {
-					log.Printf("Error sending ReminderDateTime notification for %s: %v", todo.ID, err)
-				} else {
-					reminderMsgID = id
-					log.Printf("Successfully sent ReminderDateTime notification for %s", entityID)
-				}
+		if reminderAt != nil {
+			remUnix := reminderAt.Unix()
+			reminderTitle, reminderText := app.reminderContent(appID, orgID, userID, preferences, todo, model.ReminderKindReminder)
+			id, err := app.notifications.SendNotification(
+				[]model.NotificationRecipient{{UserID: userID}},
+				&topic, reminderTitle, reminderText, appID, orgID, &remUnix,
+				map[string]string{
+					"type":        "wellness_todo_entry",
+					"operation":   "todo_reminder",
+					"entity_type": "wellness_todo_entry",
+					"entity_id":   entityID,
+					"entity_name": todo.Title,
+				},
+			)
+			if err != nil {
+				log.Printf("Error sending ReminderDateTime notification for %s: %v", todo.ID, err)
+			} else {
+				reminderMsgID = id
+				batch.created(entityID, id)
+				log.Printf("Successfully sent ReminderDateTime notification for %s", entityID)
 			}
-		} else {
-			log.Printf("Reminders are DISABLED for this to-do entry: %s", todo.Title)
-			// Hard block: ensure we never schedule notifications accidentally
-			// by ignoring DueDateTime/ReminderDateTime entirely when reminders are off.
 		}
+	} else {
+		log.Printf("Reminders are DISABLED for this to-do entry: %s", todo.Title)
+		// Hard block: ensure we never schedule notifications accidentally
+		// by ignoring DueDateTime/ReminderDateTime entirely when reminders are off.
+	}
 
-		var err error
-		created, err = app.storage.CreateTodoEntry(
-			appID, orgID, userID, todo,
-			model.MessageIDs{
-				ReminderDateMessageID: reminderMsgID,
-				DueDateMessageID:      dueMsgID,
-			},
-			entityID,
-		)
+	_, location := app.preferencesLocale(appID, orgID, userID, preferences)
+	todo.Overdue = todo.IsOverdue(time.Now(), location)
+
+	return app.storage.CreateTodoEntry(context,
+		appID, orgID, userID, todo,
+		model.MessageIDs{
+			ReminderDateMessageID: reminderMsgID,
+			DueDateMessageID:      dueMsgID,
+		},
+		entityID,
+	)
+}
+
+func (app *Application) updateTodoEntry(appID string, orgID string, userID string, todo *model.TodoEntry, id string) (*model.TodoEntry, error) {
+	return app.patchTodoEntry(appID, orgID, userID, id, func(current model.TodoEntry) (*model.TodoEntry, error) {
+		return todo, nil
+	})
+}
+
+// patchTodoEntry applies the patch to the current entry in a transaction, so the concurrent changes of the other fields are not lost
+func (app *Application) patchTodoEntry(appID string, orgID string, userID string, id string, patch func(current model.TodoEntry) (*model.TodoEntry, error)) (*model.TodoEntry, error) {
+	var updateTodoEntry *model.TodoEntry
+	batch := &notificationBatch{}
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		todoEntry, err := app.storage.GetTodoEntry(context, appID, orgID, userID, id)
+		if err != nil {
+			log.Printf("Error on getting todo entry: %s", err)
+			return err
+		}
+		if todoEntry == nil {
+			return model.NewNotFoundError("todo entry", id)
+		}
+
+		todo, err := patch(*todoEntry)
+		if err != nil {
+			return err
+		}
+		err = validateReminderDateTime(todo.ReminderDateTime, todoEntry.ReminderDateTime)
+		if err != nil {
+			return err
+		}
+
+		preferences := app.userPreferences(appID, orgID, userID)
+		err = app.rescheduleTodoEntryReminders(appID, orgID, userID, preferences, id, todoEntry, todo, batch)
+		if err != nil {
+			return err
+		}
+		_, location := app.preferencesLocale(appID, orgID, userID, preferences)
+		todo.Overdue = todo.IsOverdue(time.Now(), location)
+		todo.SnoozeCount = todoEntry.SnoozeCount
+
+		updateTodoEntry, err = app.storage.UpdateTodoEntry(context, appID, orgID, userID, todo, id)
 		if err != nil {
-			log.Printf("Error creating todo entry: %v", err)
+			log.Printf("Error on updating todo entry: %s", err)
+			return err
 		}
+
 		return nil
 	})
-
-	return created, err
+	batch.finish(app, appID, orgID, userID, err == nil)
+	return updateTodoEntry, err
 }
 
-func (app *Application) updateTodoEntry(appID string, orgID string, userID string, todo *model.TodoEntry, id string) (*model.TodoEntry, error) {
-	var updateTodoEntry *model.TodoEntry
+// snoozeTodoEntry moves the reminder of the entry to the time - its current reminder message is replaced - and counts the snooze.
+// The entries without a reminder before the due time cannot be snoozed, the snooze to the current reminder time changes nothing.
+func (app *Application) snoozeTodoEntry(appID string, orgID string, userID string, id string, until time.Time) (*model.TodoEntry, error) {
+	var snoozed *model.TodoEntry
+	batch := &notificationBatch{}
 	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
 		todoEntry, err := app.storage.GetTodoEntry(context, appID, orgID, userID, id)
 		if err != nil {
 			log.Printf("Error on getting todo entry: %s", err)
+			return err
+		}
+		if todoEntry == nil {
+			return model.NewNotFoundError("todo entry", id)
+		}
+		if todoEntry.Completed {
+			return model.NewConflictError("a completed todo entry cannot be snoozed")
+		}
+		reminderType := strings.ToLower(strings.TrimSpace(todoEntry.ReminderType))
+		if reminderType == "" || reminderType == "none" || reminderType == "at_due_time" {
+			return model.NewConflictError("the todo entry has no reminder to snooze")
+		}
+
+		err = validateReminderDateTime(&until, nil)
+		if err != nil {
+			return err
+		}
+		if todoEntry.ReminderDateTime != nil && todoEntry.ReminderDateTime.Equal(until) {
+			snoozed = todoEntry
+			return nil
+		}
+
+		todo := *todoEntry
+		todo.ReminderDateTime = &until
+		preferences := app.userPreferences(appID, orgID, userID)
+		err = app.rescheduleTodoEntryReminders(appID, orgID, userID, preferences, id, todoEntry, &todo, batch)
+		if err != nil {
+			return err
+		}
+		_, location := app.preferencesLocale(appID, orgID, userID, preferences)
+		todo.Overdue = todo.IsOverdue(time.Now(), location)
+		todo.SnoozeCount = todoEntry.SnoozeCount + 1
+
+		snoozed, err = app.storage.UpdateTodoEntry(context, appID, orgID, userID, &todo, id)
+		if err != nil {
+			log.Printf("Error on snoozing todo entry: %s", err)
+			return err
 		}
-		if todoEntry.MessageIDs.DueDateMessageID != nil {
-			err = app.notifications.DeleteNotification(appID, orgID, *todoEntry.MessageIDs.DueDateMessageID)
+		return nil
+	})
+	batch.finish(app, appID, orgID, userID, err == nil)
+	return snoozed, err
+}
+
+// rescheduleTodoEntryReminders replaces the notifications of the changed due and reminder dates. The notifications of the
+// unchanged dates are kept, unless they were never scheduled. The replaced notifications are deleted by the batch when
+// there is one.
+func (app *Application) rescheduleTodoEntryReminders(appID string, orgID string, userID string, preferences model.UserPreferences, id string,
+	current *model.TodoEntry, todo *model.TodoEntry, batch *notificationBatch) error {
+	todo.MessageIDs = current.MessageIDs
+	todo.ReminderAdjustments = current.ReminderAdjustments
+	data := map[string]string{
+		"type":        "wellness_todo_entry",
+		"operation":   "todo_reminder",
+		"entity_type": "wellness_todo_entry",
+		"entity_id":   id,
+		"entity_name": todo.Title,
+	}
+	contentChanged := reminderContentChanged(current, todo)
+
+	if requiresRescheduling(current.DueDateTime, todo.DueDateTime, current.SendTime(model.ReminderKindDue), current.MessageIDs.DueDateMessageID, contentChanged) {
+		if current.MessageIDs.DueDateMessageID != nil {
+			err := batch.deleteNotification(app.notifications, appID, orgID, id, *current.MessageIDs.DueDateMessageID)
 			if err != nil {
-				log.Printf("Error on delete notification with DueDateMessageID %s", *todoEntry.MessageIDs.DueDateMessageID)
+				log.Printf("Error on delete notification with DueDateMessageID %s", *current.MessageIDs.DueDateMessageID)
 				return err
 			}
+			todo.MessageIDs.DueDateMessageID = nil
 		}
 
-		if todo.DueDateTime != nil {
-			if todoEntry.DueDateTime != todo.DueDateTime {
-				topic := "update due date time"
-				dueDateTime := todo.DueDateTime.Unix()
-				duoMsg, err := app.notifications.SendNotification([]model.NotificationRecipient{{UserID: userID}}, &topic, "To-Do List Reminder", todo.Title, appID, orgID, &dueDateTime, map[string]string{
-					"type":        "wellness_todo_entry",
-					"operation":   "todo_reminder",
-					"entity_type": "wellness_todo_entry",
-					"entity_id":   todo.ID,
-					"entity_name": todo.Title,
-				})
+		var dueAt *time.Time
+		todo.ReminderAdjustments = withoutReminderAdjustment(todo.ReminderAdjustments, model.ReminderKindDue)
+		if todo.DueDateTime != nil && !preferences.IsOptedOut(model.ReminderKindDue) {
+			dueAt = app.scheduleTime(appID, orgID, userID, preferences, todo, model.ReminderKindDue, *todo.DueDateTime)
+		}
 
-				if err != nil {
-					log.Printf("Error on sending DueDateTime notification %s inbox message: %s", id, err)
-					//return err // Don't propagate the error. Just create the reminder.
-				}
+		if dueAt != nil {
+			topic := "update due date time"
+			dueDateTime := dueAt.Unix()
+			dueTitle, dueText := app.reminderContent(appID, orgID, userID, preferences, todo, model.ReminderKindDue)
+			duoMsg, err := app.notifications.SendNotification([]model.NotificationRecipient{{UserID: userID}}, &topic, dueTitle, dueText, appID, orgID, &dueDateTime, data)
+			if err != nil {
+				log.Printf("Error on sending DueDateTime notification %s inbox message: %s", id, err)
+				//return err // Don't propagate the error. Just create the reminder.
+			} else {
 				todo.MessageIDs.DueDateMessageID = duoMsg
+				batch.created(id, duoMsg)
 				log.Printf("Sent DueDateTime notification %s successfully", id)
 			}
 		}
+	}
 
-		if todoEntry.MessageIDs.ReminderDateMessageID != nil {
-			err = app.notifications.DeleteNotification(appID, orgID, *todoEntry.MessageIDs.ReminderDateMessageID)
+	if requiresRescheduling(current.ReminderDateTime, todo.ReminderDateTime, current.SendTime(model.ReminderKindReminder), current.MessageIDs.ReminderDateMessageID,
+		contentChanged) {
+		if current.MessageIDs.ReminderDateMessageID != nil {
+			err := batch.deleteNotification(app.notifications, appID, orgID, id, *current.MessageIDs.ReminderDateMessageID)
 			if err != nil {
-				log.Printf("Error on delete notification with ReminderDateMessageID %s", *todoEntry.MessageIDs.ReminderDateMessageID)
+				log.Printf("Error on delete notification with ReminderDateMessageID %s", *current.MessageIDs.ReminderDateMessageID)
 				return err
 			}
+			todo.MessageIDs.ReminderDateMessageID = nil
 		}
-		if todo.ReminderDateTime != nil {
-			if todoEntry.ReminderDateTime != todo.ReminderDateTime {
-				topic := "update due date time"
-				reminderDateTime := todo.ReminderDateTime.Unix()
-				reminderMsg, err := app.notifications.SendNotification([]model.NotificationRecipient{{UserID: userID}}, &topic, "To-Do List Reminder", todo.Title, appID, orgID, &reminderDateTime, map[string]string{
-					"type":        "wellness_todo_entry",
-					"operation":   "todo_reminder",
-					"entity_type": "wellness_todo_entry",
-					"entity_id":   todo.ID,
-					"entity_name": todo.Title,
-				})
 
-				if err != nil {
-					log.Printf("Error on sending ReminderDateTime notification %s inbox message: %s", id, err)
-					return err
-				}
+		var reminderAt *time.Time
+		todo.ReminderAdjustments = withoutReminderAdjustment(todo.ReminderAdjustments, model.ReminderKindReminder)
+		if todo.ReminderDateTime != nil && !preferences.IsOptedOut(model.ReminderKindReminder) {
+			reminderAt = app.scheduleTime(appID, orgID, userID, preferences, todo, model.ReminderKindReminder, *todo.ReminderDateTime)
+		}
 
-				todo.MessageIDs.ReminderDateMessageID = reminderMsg
-				log.Printf("Sent ReminderDateTime notification %s successfully", id)
+		if reminderAt != nil {
+			topic := "update due date time"
+			reminderDateTime := reminderAt.Unix()
+			reminderTitle, reminderText := app.reminderContent(appID, orgID, userID, preferences, todo, model.ReminderKindReminder)
+			reminderMsg, err := app.notifications.SendNotification([]model.NotificationRecipient{{UserID: userID}}, &topic, reminderTitle, reminderText, appID, orgID, &reminderDateTime, data)
+			if err != nil {
+				log.Printf("Error on sending ReminderDateTime notification %s inbox message: %s", id, err)
+				return err
 			}
-		}
 
-		updateTodoEntry, err = app.storage.UpdateTodoEntry(context, appID, orgID, userID, todo, id)
-		if err != nil {
-			log.Printf("Error on updating todo entry: %s", err)
-			return err
+			todo.MessageIDs.ReminderDateMessageID = reminderMsg
+			batch.created(id, reminderMsg)
+			log.Printf("Sent ReminderDateTime notification %s successfully", id)
 		}
+	}
+	return nil
+}
 
+// requiresRescheduling tells if the notification of a date has to be replaced - the date changed, or a future date has no
+// notification, or the content changed while its notification is pending. The notification is pending until its send
+// time, which the quiet hours may have moved after the date.
+func requiresRescheduling(current *time.Time, updated *time.Time, sendTime *time.Time, messageID *string, contentChanged bool) bool {
+	if (current == nil) != (updated == nil) {
+		return true
+	}
+	if current == nil {
+		return false
+	}
+	if !current.Equal(*updated) {
+		return true
+	}
+	if messageID == nil {
+		return updated.After(time.Now())
+	}
+	return contentChanged && sendTime != nil && sendTime.After(time.Now())
+}
+
+// validateReminderDateTime rejects a new reminder in the past. An unchanged reminder is kept even if it has passed, so the
+// clients can update the other fields of the entry.
+func validateReminderDateTime(reminderDateTime *time.Time, current *time.Time) error {
+	if reminderDateTime == nil || (current != nil && current.Equal(*reminderDateTime)) {
 		return nil
-	})
-	return updateTodoEntry, err
+	}
+	if reminderDateTime.Before(time.Now().Add(-reminderPastGrace)) {
+		return model.NewFieldsValidationError(model.FieldError{Field: "reminder_date_time", Message: "must not be in the past"})
+	}
+	return nil
 }
 
+// deleteTodoEntry deletes the todo entry. Its messages are deleted once the entry is deleted.
 func (app *Application) deleteTodoEntry(appID string, orgID string, userID string, id string) error {
-
-	return app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+	batch := &notificationBatch{}
+	err := app.storage.PerformTransaction(func(context storage.TransactionContext) error {
 		todoEntry, err := app.storage.GetTodoEntry(context, appID, orgID, userID, id)
 		if err != nil {
 			log.Printf("Error on getting todo entry: %s", err)
+			return err
 		}
-		if todoEntry.MessageIDs.DueDateMessageID != nil {
-			err = app.notifications.DeleteNotification(appID, orgID, *todoEntry.MessageIDs.DueDateMessageID)
-			if err != nil {
-				log.Printf("Error on delete DueDateMessageID notificarion with id %s", *todoEntry.MessageIDs.DueDateMessageID)
-				//return err // Don't propagate the error. Just create the reminder.
-			}
+		if todoEntry == nil {
+			return model.NewNotFoundError("todo entry", id)
 		}
-		if todoEntry.MessageIDs.ReminderDateMessageID != nil {
-			err = app.notifications.DeleteNotification(appID, orgID, *todoEntry.MessageIDs.ReminderDateMessageID)
-			if err != nil {
-				log.Printf("Error on delete ReminderDateMessageID notificarion with id %s", *todoEntry.MessageIDs.ReminderDateMessageID)
-				//return err // Don't propagate the error. Just create the reminder.
+		for _, messageID := range []*string{todoEntry.MessageIDs.DueDateMessageID, todoEntry.MessageIDs.ReminderDateMessageID} {
+			if messageID != nil {
+				err = batch.deleteNotification(app.notifications, appID, orgID, id, *messageID)
+				if err != nil {
+					return err
+				}
 			}
 		}
-		err = app.storage.DeleteTodoEntry(context, appID, orgID, userID, id)
-		if err != nil {
-			log.Printf("Error on delete todo entry: %s", err)
-		}
-
-		return nil
+		return app.storage.DeleteTodoEntry(context, appID, orgID, userID, id)
 	})
+	batch.finish(app, appID, orgID, userID, err == nil)
+	return err
 }
 
 func (app *Application) deleteCompletedTodoEntries(appID string, orgID string, userID string) error {
 	return app.storage.DeleteCompletedTodoEntries(appID, orgID, userID)
 }
 
-// MigrateMessageIDs migrate message ids
-func (app *Application) MigrateMessageIDs() error {
-	transaction := func(context storage.TransactionContext) error {
-		todoEntries, err := app.storage.GetTodoEntriesForMigration()
-		if err != nil {
-			log.Printf("error on getting todo entries - %s", err)
-		}
-
-		for _, todo := range todoEntries {
-			if todo.RequiresMessageIDsMigration() {
-				_, err := app.updateTodoEntry(todo.AppID, todo.OrgID, todo.UserID, &todo, todo.ID)
-				if err != nil {
-					log.Printf("error on updating todo entries - %s", err)
-				}
+// migrateMessageIDs schedules the notifications of the todo entries created before the message ids were stored. The
+// entries which fail - e.g. while the Notifications BB is unreachable - are logged and skipped, so they do not stop the
+// start of the service. They are counted in the summary.
+func (app *Application) migrateMessageIDs(dryRun bool) (string, error) {
+	todoEntries, err := app.storage.GetTodoEntriesForMigration()
+	if err != nil {
+		return "", err
+	}
 
+	migrated := 0
+	failed := 0
+	for _, todo := range todoEntries {
+		if !todo.RequiresMessageIDsMigration() {
+			continue
+		}
+		if !dryRun {
+			//legacy entries which are not backfilled yet belong to the multi-tenancy app and org
+			appID, orgID := todo.AppID, todo.OrgID
+			if appID == "" || orgID == "" {
+				appID, orgID = app.multiTenancyAppID, app.multiTenancyOrgID
+			}
+			_, err := app.updateTodoEntry(appID, orgID, todo.UserID, &todo, todo.ID)
+			if err != nil {
+				log.Printf("Error on migrating the message ids of the todo entry %s - %s", todo.ID, err)
+				failed++
+				continue
 			}
 		}
-		return nil
+		migrated++
 	}
-	return app.storage.PerformTransaction(transaction)
+	return fmt.Sprintf("todo_entries:%d failed:%d", migrated, failed), nil
 }
 
 func (app *Application) getRings(appID string, orgID string, userID string) ([]model.Ring, error) {
@@ -277,10 +538,93 @@ func (app *Application) getRings(appID string, orgID string, userID string) ([]m
 }
 
 func (app *Application) getRing(appID string, orgID string, userID string, id string) (*model.Ring, error) {
-	return app.storage.GetRing(appID, orgID, userID, id)
+	ring, err := app.storage.GetRing(appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ring == nil {
+		return nil, model.NewNotFoundError("ring", id)
+	}
+	return ring, nil
+}
+
+// getRingSummary gives the daily totals of the ring in the week of the date - today when nil. The week starts on the
+// day chosen by the user and the days are in the time zone of the user.
+func (app *Application) getRingSummary(appID string, orgID string, userID string, ringID string, date *string) (*model.RingSummary, error) {
+	ring, err := app.getRing(appID, orgID, userID, ringID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := app.userPreferences(appID, orgID, userID)
+	_, location := app.preferencesLocale(appID, orgID, userID, preferences)
+
+	day := time.Now().In(location)
+	if date != nil {
+		day, err = time.ParseInLocation(ringSummaryDateLayout, *date, location)
+		if err != nil {
+			return nil, model.NewFieldsValidationError(model.FieldError{Field: "date", Message: "must be a date like " + ringSummaryDateLayout})
+		}
+	}
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, location)
+	weekStart := day.AddDate(0, 0, -int((day.Weekday()-preferences.WeekStartDay+7)%7))
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	startEpoch := weekStart.UnixMilli()
+	records, err := app.storage.GetRingsRecords(appID, orgID, userID, &ringID, &startEpoch, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]float64{}
+	for _, record := range records {
+		if !record.DateCreated.Before(weekEnd) {
+			continue
+		}
+		totals[record.DateCreated.In(location).Format(ringSummaryDateLayout)] += record.Value
+	}
+
+	summary := model.RingSummary{RingID: ring.ID, WeekStart: weekStart.Format(ringSummaryDateLayout), Days: make([]model.RingDaySummary, 0, 7)}
+	if entry := ring.EffectiveHistoryEntry(weekEnd); entry != nil {
+		summary.Unit = entry.Unit
+	}
+	for current := weekStart; current.Before(weekEnd); current = current.AddDate(0, 0, 1) {
+		daySummary := model.RingDaySummary{Date: current.Format(ringSummaryDateLayout), Value: totals[current.Format(ringSummaryDateLayout)]}
+		if entry := ring.EffectiveHistoryEntry(current.AddDate(0, 0, 1)); entry != nil {
+			daySummary.Goal = entry.Value
+			daySummary.Achieved = daySummary.Value >= entry.Value
+		}
+		summary.Days = append(summary.Days, daySummary)
+		summary.Total += daySummary.Value
+	}
+	return &summary, nil
+}
+
+// checkRingsEnabled gives a forbidden error when the app/org turned the rings off
+func (app *Application) checkRingsEnabled(appID string, orgID string) error {
+	if !app.getConfigSettings(appID, orgID).IsFeatureEnabled(model.FeatureRings) {
+		return model.NewForbiddenError("the rings are turned off for the app/org")
+	}
+	return nil
 }
 
 func (app *Application) createRing(appID string, orgID string, userID string, category *model.Ring) (*model.Ring, error) {
+	err := app.checkRingsEnabled(appID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRings := app.getConfigSettings(appID, orgID).MaxRings
+	if maxRings > 0 {
+		rings, err := app.storage.GetRings(appID, orgID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(rings) >= maxRings {
+			return nil, model.NewConflictError(fmt.Sprintf("the user cannot have more than %d rings", maxRings))
+		}
+	}
+
 	return app.storage.CreateRing(appID, orgID, userID, category)
 }
 
@@ -289,7 +633,14 @@ func (app *Application) deleteRing(appID string, orgID string, userID string, id
 }
 
 func (app *Application) createRingHistory(appID string, orgID string, userID string, ringID string, ringHistory *model.RingHistoryEntry) (*model.Ring, error) {
-	return app.storage.CreateRingHistory(appID, orgID, userID, ringID, ringHistory)
+	ring, err := app.storage.CreateRingHistory(appID, orgID, userID, ringID, ringHistory)
+	if err != nil {
+		return nil, err
+	}
+	if ring == nil {
+		return nil, model.NewNotFoundError("ring", ringID)
+	}
+	return ring, nil
 }
 
 func (app *Application) deleteRingHistory(appID string, orgID string, userID string, ringID string, ringHistoryID string) (*model.Ring, error) {
@@ -301,15 +652,52 @@ func (app *Application) getRingsRecords(appID string, orgID string, userID strin
 }
 
 func (app *Application) getRingsRecord(appID string, orgID string, userID string, id string) (*model.RingRecord, error) {
-	return app.storage.GetRingsRecord(appID, orgID, userID, id)
+	record, err := app.storage.GetRingsRecord(appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, model.NewNotFoundError("ring record", id)
+	}
+	return record, nil
 }
 
 func (app *Application) createRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error) {
+	err := app.checkRingsEnabled(appID, orgID)
+	if err != nil {
+		return nil, err
+	}
 	return app.storage.CreateRingsRecord(appID, orgID, userID, record)
 }
 
+// updateRingsRecord updates the value of a record created within the backfill window of the app/org - today in the time
+// zone of the user and the configured number of days before
 func (app *Application) updateRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error) {
-	return app.storage.UpdateRingsRecord(appID, orgID, userID, record)
+	err := app.checkRingsEnabled(appID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := app.getRingsRecord(appID, orgID, userID, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	backfillWindowDays := app.getConfigSettings(appID, orgID).BackfillWindowDays
+	_, location := app.userLocale(appID, orgID, userID)
+	localNow := time.Now().In(location)
+	windowStart := time.Date(localNow.Year(), localNow.Month(), localNow.Day()-backfillWindowDays, 0, 0, 0, 0, location)
+	if current.DateCreated.Before(windowStart) {
+		return nil, model.NewForbiddenError(fmt.Sprintf("the ring records older than %d days cannot be changed", backfillWindowDays))
+	}
+
+	updated, err := app.storage.UpdateRingsRecord(appID, orgID, userID, record)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, model.NewNotFoundError("ring record", record.ID)
+	}
+	return updated, nil
 }
 
 func (app *Application) deleteRingsRecords(appID string, orgID string, userID string, ringID *string, recordID *string) error {
@@ -322,11 +710,14 @@ func (app *Application) getUserData(userID string) (*model.UserDataResponse, err
 		err  error
 	}
 
-	// Create channels to get results concurrently
-	ringsChan := make(chan result)
-	ringsRecordChan := make(chan result)
-	todoCategoryChan := make(chan result)
-	todoEntryChan := make(chan result)
+	// Create channels to get results concurrently. Every channel has room for the result of its goroutine, so the
+	// goroutines finish even when an error returns before all the results are received
+	ringsChan := make(chan result, 1)
+	ringsRecordChan := make(chan result, 1)
+	todoCategoryChan := make(chan result, 1)
+	todoEntryChan := make(chan result, 1)
+	preferencesChan := make(chan result, 1)
+	todoTemplateChan := make(chan result, 1)
 
 	// Fetch Rings concurrently
 	go func() {
@@ -364,6 +755,18 @@ func (app *Application) getUserData(userID string) (*model.UserDataResponse, err
 		todoEntryChan <- result{data: todoEntry, err: err}
 	}()
 
+	// Fetch Todo Templates concurrently
+	go func() {
+		todoTemplates, err := app.storage.GetTodoTemplatesByUserID(userID)
+		todoTemplateChan <- result{data: todoTemplates, err: err}
+	}()
+
+	// Fetch Preferences concurrently
+	go func() {
+		preferences, err := app.storage.GetUserPreferencesByUserID(userID)
+		preferencesChan <- result{data: preferences, err: err}
+	}()
+
 	// Collect results
 	ringsRes := <-ringsChan
 	if ringsRes.err != nil {
@@ -385,12 +788,24 @@ func (app *Application) getUserData(userID string) (*model.UserDataResponse, err
 		return nil, todoEntryRes.err
 	}
 
+	todoTemplateRes := <-todoTemplateChan
+	if todoTemplateRes.err != nil {
+		return nil, todoTemplateRes.err
+	}
+
+	preferencesRes := <-preferencesChan
+	if preferencesRes.err != nil {
+		return nil, preferencesRes.err
+	}
+
 	// Create the response
 	userData := model.UserDataResponse{
 		Rings:          ringsRes.data.([]model.Ring),             // Adjust type assertion based on actual data type
 		RingsRecord:    ringsRecordRes.data.([]model.RingRecord), // Adjust type assertion
 		TodoCategories: todoCategoryRes.data.([]model.TodoCategory),
 		TodoEntries:    todoEntryRes.data.([]model.TodoEntry),
+		TodoTemplates:  todoTemplateRes.data.([]model.TodoTemplate),
+		Preferences:    preferencesRes.data.([]model.UserPreferences),
 	}
 
 	return &userData, nil
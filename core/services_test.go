@@ -0,0 +1,602 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+	"wellness/core/model"
+	"wellness/driven/fakes"
+	"wellness/driven/storage"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+func newTestApplication(t *testing.T) (*Application, *fakes.NotificationsAdapter) {
+	t.Helper()
+	notifications := fakes.NewNotificationsAdapter()
+	//yearly, so the background jobs do not run in the tests
+	yearly := "0 0 1 1 *"
+	app := NewApplication("test", "test", logs.NewLogger("test", &logs.LoggerOpts{}), storage.NewMemoryAdapter(),
+		fakes.NewCoreAdapter(nil), notifications, "app", "org",
+		model.DeleteDataConfig{Schedule: yearly, Timezone: "UTC"}, model.DigestConfig{Schedule: yearly},
+		model.RingNudgesConfig{Schedule: yearly}, model.OverdueConfig{Schedule: yearly}, false, false)
+	return app, notifications
+}
+
+// activeMessages gives the messages which are not deleted
+func activeMessages(notifications *fakes.NotificationsAdapter) []fakes.Message {
+	var result []fakes.Message
+	for _, message := range notifications.Messages() {
+		if !message.Deleted {
+			result = append(result, message)
+		}
+	}
+	return result
+}
+
+func TestPatchTodoEntryReschedulesOnContentChange(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	created, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due, ReminderType: "at_due_time"})
+	if err != nil {
+		t.Fatalf("createTodoEntry() error = %v", err)
+	}
+	if created.MessageIDs.DueDateMessageID == nil {
+		t.Fatal("createTodoEntry() scheduled no due message")
+	}
+
+	completed, err := app.patchTodoEntry("app", "org", "user", created.ID, func(current model.TodoEntry) (*model.TodoEntry, error) {
+		current.Completed = true
+		return &current, nil
+	})
+	if err != nil {
+		t.Fatalf("patchTodoEntry() of completed error = %v", err)
+	}
+	if messages := activeMessages(notifications); len(messages) != 1 || messages[0].ID != *completed.MessageIDs.DueDateMessageID {
+		t.Fatalf("messages after patching completed = %+v, want the due message %s kept", messages, *created.MessageIDs.DueDateMessageID)
+	}
+
+	renamed, err := app.patchTodoEntry("app", "org", "user", created.ID, func(current model.TodoEntry) (*model.TodoEntry, error) {
+		current.Title = "Run"
+		return &current, nil
+	})
+	if err != nil {
+		t.Fatalf("patchTodoEntry() of title error = %v", err)
+	}
+	messages := activeMessages(notifications)
+	if len(messages) != 1 || messages[0].ID == *created.MessageIDs.DueDateMessageID || messages[0].ID != *renamed.MessageIDs.DueDateMessageID {
+		t.Fatalf("messages after patching the title = %+v, want only the new due message", messages)
+	}
+}
+
+// failingUpdateStorage fails the updates of the todo entries, so their transactions are rolled back
+type failingUpdateStorage struct {
+	Storage
+}
+
+func (s *failingUpdateStorage) UpdateTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string,
+	todo *model.TodoEntry, id string) (*model.TodoEntry, error) {
+	return nil, errors.New("storage failure")
+}
+
+func TestPatchTodoEntryKeepsMessagesOnFailure(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	created, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due, ReminderType: "at_due_time"})
+	if err != nil {
+		t.Fatalf("createTodoEntry() error = %v", err)
+	}
+
+	app.storage = &failingUpdateStorage{Storage: app.storage}
+	_, err = app.patchTodoEntry("app", "org", "user", created.ID, func(current model.TodoEntry) (*model.TodoEntry, error) {
+		current.Title = "Run"
+		return &current, nil
+	})
+	if err == nil {
+		t.Fatal("patchTodoEntry() error = nil, want the storage error")
+	}
+	messages := activeMessages(notifications)
+	if len(messages) != 1 || messages[0].ID != *created.MessageIDs.DueDateMessageID {
+		t.Fatalf("messages after the failed patch = %+v, want only the due message %s kept", messages, *created.MessageIDs.DueDateMessageID)
+	}
+}
+
+func TestPatchTodoCategory(t *testing.T) {
+	app, _ := newTestApplication(t)
+	category, err := app.createTodoCategory("app", "org", "user", &model.TodoCategory{Name: "Health", Color: "red"})
+	if err != nil {
+		t.Fatalf("createTodoCategory() error = %v", err)
+	}
+
+	patched, err := app.patchTodoCategory("app", "org", "user", category.ID, func(current model.TodoCategory) (*model.TodoCategory, error) {
+		current.Color = "blue"
+		return &current, nil
+	})
+	if err != nil {
+		t.Fatalf("patchTodoCategory() error = %v", err)
+	}
+	if patched.Name != "Health" || patched.Color != "blue" {
+		t.Fatalf("patchTodoCategory() = %+v, want the name kept and the color changed", patched)
+	}
+
+	_, err = app.patchTodoCategory("app", "org", "user", "missing", func(current model.TodoCategory) (*model.TodoCategory, error) {
+		return &current, nil
+	})
+	if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeNotFound {
+		t.Fatalf("patchTodoCategory() of a missing category error = %v, want not found", err)
+	}
+}
+
+// oldRecordsStorage gives the ring records as created two days ago
+type oldRecordsStorage struct {
+	Storage
+}
+
+func (s oldRecordsStorage) GetRingsRecord(appID string, orgID string, userID string, id string) (*model.RingRecord, error) {
+	record, err := s.Storage.GetRingsRecord(appID, orgID, userID, id)
+	if record != nil {
+		record.DateCreated = record.DateCreated.AddDate(0, 0, -2)
+	}
+	return record, err
+}
+
+func TestRingsRecordBackfillWindow(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.storage = oldRecordsStorage{Storage: app.storage}
+	ring, err := app.createRing("app", "org", "user", &model.Ring{})
+	if err != nil {
+		t.Fatalf("createRing() error = %v", err)
+	}
+	record, err := app.createRingsRecord("app", "org", "user", &model.RingRecord{RingID: ring.ID, Value: 1})
+	if err != nil {
+		t.Fatalf("createRingsRecord() error = %v", err)
+	}
+
+	record.Value = 2
+	_, err = app.updateRingsRecord("app", "org", "user", record)
+	if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeForbidden {
+		t.Fatalf("updateRingsRecord() outside the backfill window error = %v, want forbidden", err)
+	}
+
+	_, err = app.createAppOrgConfig("app", "org", model.ConfigSettings{BackfillWindowDays: 2})
+	if err != nil {
+		t.Fatalf("createAppOrgConfig() error = %v", err)
+	}
+	updated, err := app.updateRingsRecord("app", "org", "user", record)
+	if err != nil {
+		t.Fatalf("updateRingsRecord() within the backfill window error = %v", err)
+	}
+	if updated.Value != 2 {
+		t.Fatalf("updateRingsRecord() value = %v, want 2", updated.Value)
+	}
+}
+
+func TestRingsFeatureTurnedOff(t *testing.T) {
+	app, _ := newTestApplication(t)
+	_, err := app.createAppOrgConfig("app", "org", model.ConfigSettings{Features: map[string]bool{model.FeatureRings: false}})
+	if err != nil {
+		t.Fatalf("createAppOrgConfig() error = %v", err)
+	}
+
+	_, err = app.createRing("app", "org", "user", &model.Ring{})
+	if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeForbidden {
+		t.Fatalf("createRing() with the rings turned off error = %v, want forbidden", err)
+	}
+	_, err = app.createRing("other", "org", "user", &model.Ring{})
+	if err != nil {
+		t.Fatalf("createRing() of another app error = %v", err)
+	}
+}
+
+// countingTemplatesStorage counts the reads of the notification templates
+type countingTemplatesStorage struct {
+	Storage
+	reads int
+}
+
+func (s *countingTemplatesStorage) GetNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error) {
+	s.reads++
+	return s.Storage.GetNotificationTemplates(appID, orgID)
+}
+
+func TestReminderContentCachesTemplates(t *testing.T) {
+	app, _ := newTestApplication(t)
+	store := &countingTemplatesStorage{Storage: app.storage}
+	app.storage = store
+	template, err := app.createNotificationTemplate("app", "org", model.NotificationTemplate{Language: model.DefaultLanguage, Subject: "Due", Body: "{{.Title}}"})
+	if err != nil {
+		t.Fatalf("createNotificationTemplate() error = %v", err)
+	}
+
+	todo := &model.TodoEntry{Title: "Walk"}
+	for i := 0; i < 3; i++ {
+		subject, body := app.reminderContent("app", "org", "user", model.UserPreferences{}, todo, model.ReminderKindDue)
+		if subject != "Due" || body != "Walk" {
+			t.Fatalf("reminderContent() = %s, %s, want Due, Walk", subject, body)
+		}
+	}
+	if store.reads != 1 {
+		t.Fatalf("the templates were read %d times, want 1", store.reads)
+	}
+
+	_, err = app.updateNotificationTemplate("app", "org", template.ID, model.NotificationTemplate{Language: model.DefaultLanguage, Subject: "Soon", Body: "{{.Title}}"})
+	if err != nil {
+		t.Fatalf("updateNotificationTemplate() error = %v", err)
+	}
+	subject, _ := app.reminderContent("app", "org", "user", model.UserPreferences{}, todo, model.ReminderKindDue)
+	if subject != "Soon" {
+		t.Fatalf("reminderContent() subject after the update = %s, want Soon", subject)
+	}
+}
+
+func TestSendDigestRendersTemplate(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	_, err := app.createNotificationTemplate("app", "org", model.NotificationTemplate{Kind: model.TemplateKindDigest, Language: "es",
+		Subject: "Resumen", Body: "{{.Overdue}} atrasados"})
+	if err != nil {
+		t.Fatalf("createNotificationTemplate() error = %v", err)
+	}
+	due := time.Now().AddDate(0, 0, -2)
+	for _, userID := range []string{"es-user", "en-user"} {
+		_, err = app.createTodoEntry("app", "org", userID, &model.TodoEntry{Title: "Walk", DueDateTime: &due, ReminderType: "none"})
+		if err != nil {
+			t.Fatalf("createTodoEntry() error = %v", err)
+		}
+	}
+
+	for _, item := range []struct {
+		userID   string
+		language string
+		subject  string
+		body     string
+	}{
+		{"es-user", "es-MX", "Resumen", "1 atrasados"},
+		{"en-user", "", digestTitle, "1 overdue."},
+	} {
+		preferences, err := app.updateUserPreferences("app", "org", item.userID, model.UserPreferences{Language: item.language, DigestTime: "00:00"})
+		if err != nil {
+			t.Fatalf("updateUserPreferences() error = %v", err)
+		}
+		notifications.Reset()
+		sent, err := app.sendDigest(*preferences, time.Now())
+		if err != nil || !sent {
+			t.Fatalf("sendDigest() of %s = %v, %v, want sent", item.userID, sent, err)
+		}
+		messages := notifications.Messages()
+		if len(messages) != 1 || messages[0].Subject != item.subject || messages[0].Body != item.body {
+			t.Fatalf("the digest of %s = %+v, want %s: %s", item.userID, messages, item.subject, item.body)
+		}
+	}
+}
+
+func TestUpdateUserPreferencesKeepsLastDigestDate(t *testing.T) {
+	app, _ := newTestApplication(t)
+	preferences, err := app.updateUserPreferences("app", "org", "user", model.UserPreferences{DigestTime: "08:00"})
+	if err != nil {
+		t.Fatalf("updateUserPreferences() error = %v", err)
+	}
+	marked, err := app.storage.MarkDigestSent(preferences.ID, "2026-01-02")
+	if err != nil || !marked {
+		t.Fatalf("MarkDigestSent() = %v, %v, want marked", marked, err)
+	}
+
+	updated, err := app.updateUserPreferences("app", "org", "user", model.UserPreferences{DigestTime: "09:00", LastDigestDate: "2000-01-01"})
+	if err != nil {
+		t.Fatalf("updateUserPreferences() error = %v", err)
+	}
+	if updated.ID != preferences.ID || updated.DigestTime != "09:00" || updated.LastDigestDate != "2026-01-02" {
+		t.Fatalf("updateUserPreferences() = %+v, want the digest time changed and the last digest date kept", updated)
+	}
+}
+
+func TestSendRingNudgeRendersTemplate(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	_, err := app.createAppOrgConfig("app", "org", model.ConfigSettings{RingNudgeTime: "00:00", MaxRingNudgesPerWeek: 10})
+	if err != nil {
+		t.Fatalf("createAppOrgConfig() error = %v", err)
+	}
+	_, err = app.createNotificationTemplate("app", "org", model.NotificationTemplate{Kind: model.TemplateKindRingNudge, Language: "es",
+		Subject: "Anillos", Body: "Faltan {{.Left}} {{.Unit}} para {{.Ring}}"})
+	if err != nil {
+		t.Fatalf("createNotificationTemplate() error = %v", err)
+	}
+
+	for _, item := range []struct {
+		userID   string
+		language string
+		subject  string
+		body     string
+	}{
+		{"es-user", "es", "Anillos", "Faltan 8 vasos para Agua"},
+		{"en-user", "", ringNudgeTitle, "8 vasos left to hit your Agua ring"},
+	} {
+		_, err = app.updateUserPreferences("app", "org", item.userID, model.UserPreferences{Language: item.language})
+		if err != nil {
+			t.Fatalf("updateUserPreferences() error = %v", err)
+		}
+		ring := model.Ring{ID: item.userID + "-ring", AppID: "app", OrgID: "org", UserID: item.userID,
+			History: []model.RingHistoryEntry{{Name: "Agua", Unit: "vasos", Value: 8, DateCreated: time.Now().AddDate(0, 0, -1)}}}
+		notifications.Reset()
+		sent, err := app.sendRingNudge(ring, time.Now())
+		if err != nil || !sent {
+			t.Fatalf("sendRingNudge() of %s = %v, %v, want sent", item.userID, sent, err)
+		}
+		messages := notifications.Messages()
+		if len(messages) != 1 || messages[0].Subject != item.subject || messages[0].Body != item.body {
+			t.Fatalf("the nudge of %s = %+v, want %s: %s", item.userID, messages, item.subject, item.body)
+		}
+	}
+}
+
+func TestSnoozeTodoEntry(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	reminder := due.Add(-time.Hour)
+	entry, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due,
+		ReminderDateTime: &reminder, ReminderType: "both"})
+	if err != nil {
+		t.Fatalf("createTodoEntry() error = %v", err)
+	}
+
+	same, err := app.snoozeTodoEntry("app", "org", "user", entry.ID, reminder)
+	if err != nil {
+		t.Fatalf("snoozeTodoEntry() to the current reminder error = %v", err)
+	}
+	if same.SnoozeCount != 0 || len(notifications.Messages()) != 2 {
+		t.Fatalf("snoozeTodoEntry() to the current reminder = %+v with %d messages, want nothing changed", same, len(notifications.Messages()))
+	}
+
+	later := reminder.Add(30 * time.Minute)
+	snoozed, err := app.snoozeTodoEntry("app", "org", "user", entry.ID, later)
+	if err != nil {
+		t.Fatalf("snoozeTodoEntry() error = %v", err)
+	}
+	if snoozed.SnoozeCount != 1 || !snoozed.ReminderDateTime.Equal(later) {
+		t.Fatalf("snoozeTodoEntry() = %+v, want the reminder moved and the snooze counted", snoozed)
+	}
+
+	for _, reminderType := range []string{"none", "at_due_time", ""} {
+		other, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Run", HasDueTime: true, DueDateTime: &due, ReminderType: reminderType})
+		if err != nil {
+			t.Fatalf("createTodoEntry() error = %v", err)
+		}
+		notifications.Reset()
+		_, err = app.snoozeTodoEntry("app", "org", "user", other.ID, later)
+		if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeConflict {
+			t.Fatalf("snoozeTodoEntry() of a %q entry error = %v, want conflict", reminderType, err)
+		}
+		if len(notifications.Messages()) != 0 {
+			t.Fatalf("snoozeTodoEntry() of a %q entry scheduled %+v", reminderType, notifications.Messages())
+		}
+	}
+}
+
+// failingNotifications fails the notifications of the given todo entry, as an unreachable Notifications BB does
+type failingNotifications struct {
+	Notifications
+	entityID string
+}
+
+func (n *failingNotifications) SendNotification(recipients []model.NotificationRecipient, topic *string, title string, text string,
+	appID string, orgID string, time *int64, data map[string]string) (*string, error) {
+	if data["entity_id"] == n.entityID {
+		return nil, errors.New("notifications unreachable")
+	}
+	return n.Notifications.SendNotification(recipients, topic, title, text, appID, orgID, time, data)
+}
+
+func TestMigrateMessageIDsSkipsFailedEntries(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	app.notifications = &failingNotifications{Notifications: notifications, entityID: "failing"}
+	reminder := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	for _, id := range []string{"failing", "legacy"} {
+		_, err := app.storage.CreateTodoEntry(nil, "app", "org", "user", &model.TodoEntry{Title: id, ReminderDateTime: &reminder,
+			ReminderType: "both"}, model.MessageIDs{}, id)
+		if err != nil {
+			t.Fatalf("error storing the legacy entry - %s", err)
+		}
+	}
+
+	summary, err := app.migrateMessageIDs(false)
+	if err != nil {
+		t.Fatalf("migrateMessageIDs() error = %v", err)
+	}
+	if summary != "todo_entries:1 failed:1" {
+		t.Errorf("migrateMessageIDs() = %s, want one migrated and one failed entry", summary)
+	}
+	migrated, err := app.getTodoEntry("app", "org", "user", "legacy")
+	if err != nil || migrated.MessageIDs.ReminderDateMessageID == nil {
+		t.Errorf("getTodoEntry() = %+v, %v, want the reminder of the migrated entry scheduled", migrated, err)
+	}
+}
+
+func TestSnoozeTodoEntryKeepsMessagesOnFailure(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	reminder := due.Add(-time.Hour)
+	entry, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due,
+		ReminderDateTime: &reminder, ReminderType: "reminder"})
+	if err != nil {
+		t.Fatalf("createTodoEntry() error = %v", err)
+	}
+
+	app.storage = &failingUpdateStorage{Storage: app.storage}
+	_, err = app.snoozeTodoEntry("app", "org", "user", entry.ID, reminder.Add(30*time.Minute))
+	if err == nil {
+		t.Fatal("snoozeTodoEntry() error = nil, want the storage error")
+	}
+	messages := activeMessages(notifications)
+	if len(messages) != 1 || messages[0].ID != *entry.MessageIDs.ReminderDateMessageID {
+		t.Fatalf("messages after the failed snooze = %+v, want only the reminder message %s kept", messages, *entry.MessageIDs.ReminderDateMessageID)
+	}
+}
+
+// failingRingsStorage fails the reads of the rings of a user
+type failingRingsStorage struct {
+	Storage
+}
+
+func (s *failingRingsStorage) GetRingsByUserID(userID string) ([]model.Ring, error) {
+	return nil, errors.New("storage failure")
+}
+
+func TestGetUserDataErrorDoesNotLeakGoroutines(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.storage = &failingRingsStorage{Storage: app.storage}
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		_, err := app.getUserData("user")
+		if err == nil {
+			t.Fatal("getUserData() error = nil, want the storage error")
+		}
+	}
+
+	//the goroutines of the other reads finish soon after the error
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("getUserData() left %d goroutines running", after-before)
+	}
+}
+
+// storeShiftedReminder stores an entry whose reminder time has passed while its notification, moved by the quiet hours of
+// the user, is still pending
+func storeShiftedReminder(t *testing.T, app *Application) (*model.TodoEntry, string) {
+	t.Helper()
+	now := time.Now().UTC()
+	_, err := app.storage.SaveUserPreferences("app", "org", "user", model.UserPreferences{Timezone: "UTC",
+		QuietHours: &model.QuietHours{Start: now.Add(-3 * time.Hour).Format("15:04"), End: now.Add(time.Hour).Format("15:04"),
+			Action: model.QuietHoursActionShift}})
+	if err != nil {
+		t.Fatalf("error saving the preferences - %s", err)
+	}
+
+	reminder := now.Add(-time.Hour).Truncate(time.Minute)
+	scheduled := now.Add(time.Hour).Truncate(time.Minute)
+	scheduledUnix := scheduled.Unix()
+	messageID, err := app.notifications.SendNotification([]model.NotificationRecipient{{UserID: "user"}}, nil, "Walk", "Walk",
+		"app", "org", &scheduledUnix, map[string]string{"entity_id": "shifted"})
+	if err != nil {
+		t.Fatalf("error scheduling the reminder - %s", err)
+	}
+	todo := &model.TodoEntry{Title: "Walk", ReminderDateTime: &reminder, ReminderType: "reminder",
+		ReminderAdjustments: []model.ReminderAdjustment{{Kind: model.ReminderKindReminder, Action: model.QuietHoursActionShift,
+			RequestedTime: reminder, ScheduledTime: &scheduled}}}
+	todo, err = app.storage.CreateTodoEntry(nil, "app", "org", "user", todo, model.MessageIDs{ReminderDateMessageID: messageID}, "shifted")
+	if err != nil {
+		t.Fatalf("error storing the entry - %s", err)
+	}
+	return todo, *messageID
+}
+
+func TestShiftedReminderIsPending(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	_, messageID := storeShiftedReminder(t, app)
+
+	renamed, err := app.patchTodoEntry("app", "org", "user", "shifted", func(current model.TodoEntry) (*model.TodoEntry, error) {
+		current.Title = "Run"
+		return &current, nil
+	})
+	if err != nil {
+		t.Fatalf("patchTodoEntry() error = %v", err)
+	}
+	messages := activeMessages(notifications)
+	if renamed.MessageIDs.ReminderDateMessageID == nil || len(messages) != 1 || messages[0].ID != *renamed.MessageIDs.ReminderDateMessageID ||
+		messages[0].ID == messageID {
+		t.Fatalf("messages after renaming = %+v, want the shifted reminder %s replaced", messages, messageID)
+	}
+
+	cancelled, failed, err := app.deleteDataLogic.cancelPendingNotifications("app", "org", []string{"user"})
+	if err != nil || cancelled != 1 || failed != 0 {
+		t.Fatalf("cancelPendingNotifications() = %d, %d, %v, want the shifted reminder cancelled", cancelled, failed, err)
+	}
+	if messages := activeMessages(notifications); len(messages) != 0 {
+		t.Fatalf("messages after cancelling = %+v, want none", messages)
+	}
+}
+
+func TestSendDigestsInBatches(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	due := time.Now().AddDate(0, 0, -2)
+	count := digestBatchSize + 2
+	for i := 0; i < count; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		_, err := app.storage.SaveUserPreferences("app", "org", userID, model.UserPreferences{DigestTime: "00:00"})
+		if err != nil {
+			t.Fatalf("SaveUserPreferences() error = %v", err)
+		}
+		_, err = app.storage.CreateTodoEntry(nil, "app", "org", userID, &model.TodoEntry{Title: "Walk", DueDateTime: &due},
+			model.MessageIDs{}, userID)
+		if err != nil {
+			t.Fatalf("CreateTodoEntry() error = %v", err)
+		}
+	}
+
+	app.sendDigests()
+
+	if messages := notifications.Messages(); len(messages) != count {
+		t.Fatalf("sendDigests() sent %d digests, want %d", len(messages), count)
+	}
+}
+
+// failingDeleteStorage fails the deletes of the todo entries
+type failingDeleteStorage struct {
+	Storage
+}
+
+func (s *failingDeleteStorage) DeleteTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string, id string) error {
+	return errors.New("storage failure")
+}
+
+func TestDeleteTodoEntry(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	reminder := due.Add(-time.Hour)
+	entry, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due,
+		ReminderDateTime: &reminder, ReminderType: "both"})
+	if err != nil {
+		t.Fatalf("createTodoEntry() error = %v", err)
+	}
+
+	store := app.storage
+	app.storage = &failingDeleteStorage{Storage: store}
+	err = app.deleteTodoEntry("app", "org", "user", entry.ID)
+	if err == nil {
+		t.Fatal("deleteTodoEntry() error = nil, want the storage error")
+	}
+	if messages := activeMessages(notifications); len(messages) != 2 {
+		t.Fatalf("messages after the failed delete = %+v, want both messages kept", messages)
+	}
+
+	app.storage = store
+	err = app.deleteTodoEntry("app", "org", "user", entry.ID)
+	if err != nil {
+		t.Fatalf("deleteTodoEntry() error = %v", err)
+	}
+	if messages := activeMessages(notifications); len(messages) != 0 {
+		t.Fatalf("messages after the delete = %+v, want none", messages)
+	}
+	_, err = app.getTodoEntry("app", "org", "user", entry.ID)
+	if model.AsError(err) == nil || model.AsError(err).Type != model.ErrorTypeNotFound {
+		t.Fatalf("getTodoEntry() of the deleted entry error = %v, want not found", err)
+	}
+}
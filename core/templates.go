@@ -0,0 +1,329 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+// reminderTimeLayout the layout of the formatted times in the notification templates
+const reminderTimeLayout = "2006-01-02 15:04"
+
+// userLocale gives the language and the time zone of the reminders of a user. The preferences of the user come first,
+// then the app/org config.
+func (app *Application) userLocale(appID string, orgID string, userID string) (string, *time.Location) {
+	return app.preferencesLocale(appID, orgID, userID, app.userPreferences(appID, orgID, userID))
+}
+
+// preferencesLocale gives the language and the time zone of the reminders of a user whose preferences are already loaded
+func (app *Application) preferencesLocale(appID string, orgID string, userID string, preferences model.UserPreferences) (string, *time.Location) {
+	settings := app.getConfigSettings(appID, orgID)
+
+	language := model.DefaultLanguage
+	for _, item := range []string{preferences.Language, settings.Language} {
+		if item != "" {
+			language = item
+			break
+		}
+	}
+
+	location := time.UTC
+	for _, item := range []string{preferences.Timezone, settings.Timezone} {
+		if item == "" {
+			continue
+		}
+		loaded, err := time.LoadLocation(item)
+		if err != nil {
+			app.logger.Warnf("invalid time zone %s of %s in %s/%s - %s", item, userID, appID, orgID, err)
+			continue
+		}
+		location = loaded
+		break
+	}
+	return language, location
+}
+
+// reminderContent gives the title and the text of a reminder of a todo entry. It renders the template of the app/org in the
+// language of the user, falling back to the language of the app/org, to English and at last to the configured text.
+func (app *Application) reminderContent(appID string, orgID string, userID string, preferences model.UserPreferences, todo *model.TodoEntry, kind string) (string, string) {
+	language, location := app.preferencesLocale(appID, orgID, userID, preferences)
+
+	subject, body, ok := app.renderTemplateOfKind(appID, orgID, model.TemplateKindTodoReminder, language, newReminderTemplateData(todo, kind, location))
+	if !ok {
+		return defaultReminderContent(app.getConfigSettings(appID, orgID), todo)
+	}
+	return subject, body
+}
+
+// notificationTemplates gives the cached notification templates of an app/org. They are loaded on the first use.
+func (app *Application) notificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error) {
+	key := configKey(appID, orgID)
+	app.cacheLock.Lock()
+	templates, ok := app.templates[key]
+	generation := app.templatesGeneration
+	app.cacheLock.Unlock()
+	if ok {
+		return templates, nil
+	}
+
+	templates, err := app.storage.GetNotificationTemplates(appID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	app.cacheLock.Lock()
+	defer app.cacheLock.Unlock()
+
+	//the templates changed while they were loaded - the next use loads them again
+	if generation == app.templatesGeneration {
+		app.templates[key] = templates
+	}
+	return templates, nil
+}
+
+// clearNotificationTemplates empties the templates cache, so the next uses load the current templates
+func (app *Application) clearNotificationTemplates() {
+	app.cacheLock.Lock()
+	defer app.cacheLock.Unlock()
+
+	app.templates = map[string][]model.NotificationTemplate{}
+	app.templatesGeneration++
+}
+
+// renderTemplateOfKind renders the template of the kind of the app/org in the language of the user, falling back to the
+// language of the app/org and to English. It gives false when there is no such template or it cannot be rendered.
+func (app *Application) renderTemplateOfKind(appID string, orgID string, kind string, language string, data interface{}) (string, string, bool) {
+	templates, err := app.notificationTemplates(appID, orgID)
+	if err != nil {
+		app.logger.Errorf("error on getting the notification templates of %s/%s - %s", appID, orgID, err)
+		return "", "", false
+	}
+
+	item := selectNotificationTemplate(templates, kind, language, app.getConfigSettings(appID, orgID).Language, model.DefaultLanguage)
+	if item == nil {
+		return "", "", false
+	}
+
+	subject, body, err := renderNotificationTemplate(*item, data)
+	if err != nil {
+		app.logger.Errorf("error on rendering the notification template %s - %s", item.ID, err)
+		return "", "", false
+	}
+	return subject, body, true
+}
+
+// selectNotificationTemplate gives the template of the kind in the first language which has one. A template of the base
+// language - es for es-MX - is used when there is none for the region.
+func selectNotificationTemplate(templates []model.NotificationTemplate, kind string, languages ...string) *model.NotificationTemplate {
+	for _, language := range languages {
+		if language == "" {
+			continue
+		}
+		for i := range templates {
+			if templates[i].Kind == kind && strings.EqualFold(templates[i].Language, language) {
+				return &templates[i]
+			}
+		}
+		base, _, _ := strings.Cut(language, "-")
+		for i := range templates {
+			if templates[i].Kind == kind && strings.EqualFold(templates[i].Language, base) {
+				return &templates[i]
+			}
+		}
+	}
+	return nil
+}
+
+func newReminderTemplateData(todo *model.TodoEntry, kind string, location *time.Location) model.ReminderTemplateData {
+	data := model.ReminderTemplateData{Kind: kind, Title: todo.Title, Description: todo.Description}
+	if todo.Category != nil {
+		data.Category = todo.Category.Name
+	}
+	if todo.Location != nil {
+		data.Location = *todo.Location
+	}
+	if todo.DueDateTime != nil {
+		due := todo.DueDateTime.In(location)
+		data.Due = &due
+		data.DueTime = due.Format(reminderTimeLayout)
+	}
+	if todo.ReminderDateTime != nil {
+		reminder := todo.ReminderDateTime.In(location)
+		data.Reminder = &reminder
+		data.ReminderTime = reminder.Format(reminderTimeLayout)
+	}
+	return data
+}
+
+// reminderContentChanged tells if the fields rendered in the notifications of the entry changed
+func reminderContentChanged(current *model.TodoEntry, todo *model.TodoEntry) bool {
+	before := newReminderTemplateData(current, "", time.UTC)
+	after := newReminderTemplateData(todo, "", time.UTC)
+	return before.Title != after.Title || before.Description != after.Description || before.Category != after.Category ||
+		before.Location != after.Location || before.DueTime != after.DueTime || before.ReminderTime != after.ReminderTime
+}
+
+// renderNotificationTemplate executes the subject and the body templates
+func renderNotificationTemplate(item model.NotificationTemplate, data interface{}) (string, string, error) {
+	subject, err := executeTemplate("subject", item.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := executeTemplate("body", item.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func executeTemplate(name string, text string, data interface{}) (string, error) {
+	parsed, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var result bytes.Buffer
+	err = parsed.Execute(&result, data)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.String()), nil
+}
+
+// sampleTemplateData gives the sample data every template of the kind is rendered with on validation
+func sampleTemplateData(kind string) []interface{} {
+	switch kind {
+	case model.TemplateKindDigest:
+		return []interface{}{model.DigestTemplateData{Date: time.Now().UTC().Format(ringSummaryDateLayout), DueToday: 2, Overdue: 1,
+			RingsBehind: []string{"Water"}}}
+	case model.TemplateKindRingNudge:
+		return []interface{}{model.RingNudgeTemplateData{Ring: "Water", Unit: "glasses", Goal: 8, Value: 6, Left: 2}}
+	default:
+		now := time.Now().UTC()
+		location := "Main Library"
+		sample := &model.TodoEntry{Title: "Read chapter 3", Description: "Pages 40-62", Location: &location,
+			Category: &model.CategoryRef{Name: "Homework"}, DueDateTime: &now, ReminderDateTime: &now}
+		return []interface{}{newReminderTemplateData(sample, model.ReminderKindDue, time.UTC),
+			newReminderTemplateData(sample, model.ReminderKindReminder, time.UTC)}
+	}
+}
+
+// validateNotificationTemplate renders the template with sample data, so the invalid templates are rejected when they are
+// stored instead of when the notifications are sent
+func validateNotificationTemplate(item model.NotificationTemplate) error {
+	fields := []model.FieldError{}
+	for _, data := range sampleTemplateData(item.Kind) {
+		subject, err := executeTemplate("subject", item.Subject, data)
+		if err != nil {
+			fields = append(fields, model.FieldError{Field: "subject", Message: err.Error()})
+			break
+		}
+		if subject == "" {
+			fields = append(fields, model.FieldError{Field: "subject", Message: "must not render an empty subject"})
+			break
+		}
+		_, err = executeTemplate("body", item.Body, data)
+		if err != nil {
+			fields = append(fields, model.FieldError{Field: "body", Message: err.Error()})
+			break
+		}
+	}
+	if len(fields) > 0 {
+		return model.NewFieldsValidationError(fields...)
+	}
+	return nil
+}
+
+func (app *Application) getNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error) {
+	return app.storage.GetNotificationTemplates(appID, orgID)
+}
+
+func (app *Application) createNotificationTemplate(appID string, orgID string, item model.NotificationTemplate) (*model.NotificationTemplate, error) {
+	if item.Kind == "" {
+		item.Kind = model.TemplateKindTodoReminder
+	}
+	err := validateNotificationTemplate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	item.ID = uuid.NewString()
+	item.AppID = appID
+	item.OrgID = orgID
+	item.DateCreated = time.Now().UTC()
+	item.DateUpdated = nil
+	err = app.storage.InsertNotificationTemplate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	app.clearNotificationTemplates()
+	return &item, nil
+}
+
+func (app *Application) updateNotificationTemplate(appID string, orgID string, id string, item model.NotificationTemplate) (*model.NotificationTemplate, error) {
+	if item.Kind == "" {
+		item.Kind = model.TemplateKindTodoReminder
+	}
+	err := validateNotificationTemplate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := app.storage.GetNotificationTemplate(appID, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, model.NewNotFoundError("notification template", id)
+	}
+
+	now := time.Now().UTC()
+	current.Kind = item.Kind
+	current.Language = item.Language
+	current.Subject = item.Subject
+	current.Body = item.Body
+	current.DateUpdated = &now
+	err = app.storage.UpdateNotificationTemplate(*current)
+	if err != nil {
+		return nil, err
+	}
+
+	app.clearNotificationTemplates()
+	return current, nil
+}
+
+func (app *Application) deleteNotificationTemplate(appID string, orgID string, id string) error {
+	current, err := app.storage.GetNotificationTemplate(appID, orgID, id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return model.NewNotFoundError("notification template", id)
+	}
+	err = app.storage.DeleteNotificationTemplate(appID, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	app.clearNotificationTemplates()
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"wellness/core/model"
+	"wellness/driven/storage"
+
+	"github.com/google/uuid"
+)
+
+// todoTemplateDateLayout the layout of the work days of the todo entries
+const todoTemplateDateLayout = "2006-01-02"
+
+// getTodoTemplates gives the templates of the user and of the app/org - only the ones of the app/org when the user id is empty
+func (app *Application) getTodoTemplates(appID string, orgID string, userID string) ([]model.TodoTemplate, error) {
+	userIDs := []string{""}
+	if userID != "" {
+		userIDs = append(userIDs, userID)
+	}
+	return app.storage.GetTodoTemplates(appID, orgID, userIDs)
+}
+
+// getTodoTemplate gives a template of the user or of the app/org
+func (app *Application) getTodoTemplate(appID string, orgID string, userID string, id string) (*model.TodoTemplate, error) {
+	template, err := app.storage.GetTodoTemplate(appID, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil || (template.UserID != "" && template.UserID != userID) {
+		return nil, model.NewNotFoundError("todo template", id)
+	}
+	return template, nil
+}
+
+// ownTodoTemplate gives a template which can be changed by the user - by the admins when the user id is empty
+func (app *Application) ownTodoTemplate(appID string, orgID string, userID string, id string) (*model.TodoTemplate, error) {
+	template, err := app.getTodoTemplate(appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.UserID != userID {
+		return nil, model.NewForbiddenError("the todo templates of the app/org are managed by the admins")
+	}
+	return template, nil
+}
+
+// createTodoTemplate creates a template of the user - of the app/org when the user id is empty
+func (app *Application) createTodoTemplate(appID string, orgID string, userID string, item model.TodoTemplate) (*model.TodoTemplate, error) {
+	category, err := app.todoTemplateCategory(appID, orgID, userID, item.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	item.ID = uuid.NewString()
+	item.AppID = appID
+	item.OrgID = orgID
+	item.UserID = userID
+	item.Category = category
+	item.DateCreated = time.Now().UTC()
+	item.DateUpdated = nil
+	err = app.storage.InsertTodoTemplate(item)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (app *Application) updateTodoTemplate(appID string, orgID string, userID string, id string, item model.TodoTemplate) (*model.TodoTemplate, error) {
+	current, err := app.ownTodoTemplate(appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	category, err := app.todoTemplateCategory(appID, orgID, userID, item.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	item.ID = current.ID
+	item.AppID = current.AppID
+	item.OrgID = current.OrgID
+	item.UserID = current.UserID
+	item.Category = category
+	item.DateCreated = current.DateCreated
+	item.DateUpdated = &now
+	err = app.storage.UpdateTodoTemplate(item)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (app *Application) deleteTodoTemplate(appID string, orgID string, userID string, id string) error {
+	_, err := app.ownTodoTemplate(appID, orgID, userID, id)
+	if err != nil {
+		return err
+	}
+	return app.storage.DeleteTodoTemplate(appID, orgID, userID, id)
+}
+
+// todoTemplateCategory gives the current category of a template of the user. The templates of the app/org have no category
+// as the categories belong to the users.
+func (app *Application) todoTemplateCategory(appID string, orgID string, userID string, category *model.CategoryRef) (*model.CategoryRef, error) {
+	if userID == "" || category == nil || category.ID == "" {
+		return nil, nil
+	}
+	item, err := app.getTodoCategory(appID, orgID, userID, category.ID)
+	if err != nil {
+		return nil, err
+	}
+	ref := item.ToCategoryRef()
+	return &ref, nil
+}
+
+// saveTodoEntryAsTemplate creates a template of the user from a todo entry. The reminder and the work days of the entry are
+// kept as offsets to its due time.
+func (app *Application) saveTodoEntryAsTemplate(appID string, orgID string, userID string, todoEntryID string, name string) (*model.TodoTemplate, error) {
+	todo, err := app.getTodoEntry(appID, orgID, userID, todoEntryID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(name) == "" {
+		name = todo.Title
+	}
+
+	item := model.TodoTemplate{Name: name, Title: todo.Title, Description: todo.Description, Category: todo.Category,
+		Location: todo.Location, ReminderType: todo.ReminderType, Recurrence: todo.Recurrence}
+	if todo.DueDateTime != nil {
+		if todo.ReminderDateTime != nil && !todo.ReminderDateTime.After(*todo.DueDateTime) {
+			item.ReminderOffsets = []int{int(todo.DueDateTime.Sub(*todo.ReminderDateTime).Minutes())}
+		}
+
+		_, location := app.userLocale(appID, orgID, userID)
+		dueDay := localDay(*todo.DueDateTime, location)
+		for _, workDay := range todo.WorkDays {
+			day, err := time.ParseInLocation(todoTemplateDateLayout, workDay, location)
+			if err != nil {
+				continue
+			}
+			offset := int(math.Round(dueDay.Sub(day).Hours() / 24))
+			if offset >= 0 && !slices.Contains(item.WorkDayOffsets, offset) {
+				item.WorkDayOffsets = append(item.WorkDayOffsets, offset)
+			}
+		}
+	}
+
+	if item.Category != nil {
+		//the entry keeps the category after it is deleted
+		category, err := app.storage.GetTodoCategory(nil, appID, orgID, userID, item.Category.ID)
+		if err != nil {
+			return nil, err
+		}
+		if category == nil {
+			item.Category = nil
+		}
+	}
+	return app.createTodoTemplate(appID, orgID, userID, item)
+}
+
+// instantiateTodoTemplate creates the todo entry of a template and an entry for every subtask. The entries are created in
+// one transaction and the messages scheduled in a failed one are deleted, so the template is instantiated completely or
+// not at all.
+func (app *Application) instantiateTodoTemplate(appID string, orgID string, userID string, id string, instance model.TodoTemplateInstance) ([]model.TodoEntry, error) {
+	template, err := app.getTodoTemplate(appID, orgID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryID := ""
+	if template.Category != nil {
+		categoryID = template.Category.ID
+	}
+	if instance.CategoryID != nil {
+		categoryID = *instance.CategoryID
+	}
+	var category *model.CategoryRef
+	if categoryID != "" {
+		current, err := app.storage.GetTodoCategory(nil, appID, orgID, userID, categoryID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil && instance.CategoryID != nil {
+			return nil, model.NewNotFoundError("todo category", categoryID)
+		}
+		//the category of the template may have been deleted since
+		if current != nil {
+			ref := current.ToCategoryRef()
+			category = &ref
+		}
+	}
+
+	todos := []*model.TodoEntry{newTemplateTodoEntry(template, category, instance)}
+	for _, subtask := range template.Subtasks {
+		todos = append(todos, &model.TodoEntry{Title: subtask, Category: category, HasDueTime: instance.HasDueTime,
+			DueDateTime: instance.DueDateTime})
+	}
+	if instance.DueDateTime != nil && len(template.WorkDayOffsets) > 0 {
+		_, location := app.userLocale(appID, orgID, userID)
+		dueDay := localDay(*instance.DueDateTime, location)
+		for _, offset := range template.WorkDayOffsets {
+			todos[0].WorkDays = append(todos[0].WorkDays, dueDay.AddDate(0, 0, -offset).Format(todoTemplateDateLayout))
+		}
+	}
+
+	for _, todo := range todos {
+		err = validateReminderDateTime(todo.ReminderDateTime, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var created []model.TodoEntry
+	preferences := app.userPreferences(appID, orgID, userID)
+	batch := &notificationBatch{}
+	err = app.storage.PerformTransaction(func(context storage.TransactionContext) error {
+		created = make([]model.TodoEntry, 0, len(todos))
+		for _, todo := range todos {
+			entry, err := app.createTodoEntryInTransaction(context, appID, orgID, userID, preferences, todo, batch)
+			if err != nil {
+				return err
+			}
+			created = append(created, *entry)
+		}
+		return nil
+	})
+	batch.finish(app, appID, orgID, userID, err == nil)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// newTemplateTodoEntry gives the main todo entry of a template. The first reminder offset gives the reminder unless it has passed.
+func newTemplateTodoEntry(template *model.TodoTemplate, category *model.CategoryRef, instance model.TodoTemplateInstance) *model.TodoEntry {
+	todo := &model.TodoEntry{Title: template.Title, Description: template.Description, Category: category,
+		Location: template.Location, HasDueTime: instance.HasDueTime, DueDateTime: instance.DueDateTime,
+		ReminderType: template.ReminderType, Recurrence: template.Recurrence}
+	if instance.DueDateTime != nil && len(template.ReminderOffsets) > 0 {
+		reminder := instance.DueDateTime.Add(-time.Duration(template.ReminderOffsets[0]) * time.Minute)
+		if reminder.After(time.Now()) {
+			todo.ReminderDateTime = &reminder
+		}
+	}
+	return todo
+}
+
+// localDay gives the start of the day of the time in the location
+func localDay(t time.Time, location *time.Location) time.Time {
+	local := t.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"wellness/core/model"
+	"wellness/driven/storage"
+)
+
+// failingCreateStorage fails the creation of a todo entry from the given call on
+type failingCreateStorage struct {
+	Storage
+	failAt int
+	calls  int
+}
+
+func (s *failingCreateStorage) CreateTodoEntry(context storage.TransactionContext, appID string, orgID string, userID string, todo *model.TodoEntry,
+	messageIDs model.MessageIDs, entityID string) (*model.TodoEntry, error) {
+	s.calls++
+	if s.failAt > 0 && s.calls >= s.failAt {
+		return nil, errors.New("storage failure")
+	}
+	return s.Storage.CreateTodoEntry(context, appID, orgID, userID, todo, messageIDs, entityID)
+}
+
+func TestCreateTodoEntryReturnsStorageError(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	app.storage = &failingCreateStorage{Storage: app.storage, failAt: 1}
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	reminder := due.Add(-time.Hour)
+
+	_, err := app.createTodoEntry("app", "org", "user", &model.TodoEntry{Title: "Walk", HasDueTime: true, DueDateTime: &due,
+		ReminderDateTime: &reminder, ReminderType: "both"})
+	if err == nil {
+		t.Fatal("createTodoEntry() error = nil, want the storage error")
+	}
+	if len(notifications.Messages()) != 2 || len(activeMessages(notifications)) != 0 {
+		t.Errorf("createTodoEntry() left %d of %d messages, want the scheduled messages deleted", len(activeMessages(notifications)),
+			len(notifications.Messages()))
+	}
+}
+
+func TestInstantiateTodoTemplateInOneTransaction(t *testing.T) {
+	app, notifications := newTestApplication(t)
+	template, err := app.createTodoTemplate("app", "org", "user", model.TodoTemplate{Name: "Move", Title: "Move out",
+		Subtasks: []string{"Pack", "Clean"}, ReminderType: "both", ReminderOffsets: []int{60}})
+	if err != nil {
+		t.Fatalf("createTodoTemplate() error = %v", err)
+	}
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	instance := model.TodoTemplateInstance{DueDateTime: &due, HasDueTime: true}
+
+	store := &failingCreateStorage{Storage: app.storage, failAt: 3}
+	app.storage = store
+	_, err = app.instantiateTodoTemplate("app", "org", "user", template.ID, instance)
+	if err == nil {
+		t.Fatal("instantiateTodoTemplate() error = nil, want the storage error")
+	}
+	entries, err := app.getTodoEntries("app", "org", "user")
+	if err != nil {
+		t.Fatalf("getTodoEntries() error = %v", err)
+	}
+	if len(entries) != 0 || len(activeMessages(notifications)) != 0 {
+		t.Fatalf("instantiateTodoTemplate() left %d entries and %d messages, want none", len(entries), len(activeMessages(notifications)))
+	}
+
+	store.failAt = 0
+	created, err := app.instantiateTodoTemplate("app", "org", "user", template.ID, instance)
+	if err != nil {
+		t.Fatalf("instantiateTodoTemplate() error = %v", err)
+	}
+	if len(created) != 3 {
+		t.Fatalf("instantiateTodoTemplate() created %d entries, want 3", len(created))
+	}
+	for i, entry := range created {
+		if entry.Position != float64(i+1) {
+			t.Errorf("instantiateTodoTemplate() entry %s position = %v, want %v", entry.Title, entry.Position, i+1)
+		}
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 	"wellness/core/model"
 
 	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth"
 )
 
+// reachabilityTimeout is the time the Core BB has to respond to a reachability check
+const reachabilityTimeout = 5 * time.Second
+
 // Adapter is the adapter for Core BB APIs
 type Adapter struct {
 	coreURL               string
@@ -68,3 +72,34 @@ func (a *Adapter) LoadDeletedMemberships() ([]model.DeletedUserData, error) {
 
 	return deletedMemberships, nil
 }
+
+// CheckServiceAccount checks that the service account access tokens are available
+func (a *Adapter) CheckServiceAccount() error {
+	if a.serviceAccountManager == nil {
+		return errors.New("service account manager is nil")
+	}
+
+	if len(a.serviceAccountManager.AccessTokens()) > 0 {
+		return nil
+	}
+	_, _, err := a.serviceAccountManager.GetAccessTokens()
+	if err != nil {
+		return fmt.Errorf("error loading service account access tokens: %s", err)
+	}
+	return nil
+}
+
+// CheckReachability checks that the Core BB responds
+func (a *Adapter) CheckReachability() error {
+	client := http.Client{Timeout: reachabilityTimeout}
+	resp, err := client.Get(fmt.Sprintf("%s/version", a.coreURL))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("CheckReachability: error with response code - %d", resp.StatusCode)
+	}
+	return nil
+}
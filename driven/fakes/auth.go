@@ -0,0 +1,72 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth"
+	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth/keys"
+	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth/tokenauth"
+)
+
+// fakeTokenTTL is how long the access tokens signed by the fake auth service are valid
+const fakeTokenTTL = 24 * time.Hour
+
+// AuthLoader is an in-process stand-in for the auth service of the Core BB. It loads the static service registrations
+// of the service and of the auth service, whose key it keeps to sign the access tokens of the fake users.
+type AuthLoader struct {
+	*auth.ServiceRegSubscriptions
+
+	authHost string
+	key      *keys.PrivKey
+	services []auth.ServiceReg
+}
+
+// LoadServices gives the static service registrations
+func (a *AuthLoader) LoadServices() ([]auth.ServiceReg, error) {
+	return a.services, nil
+}
+
+// SignAccessToken signs an access token of the user with the permissions
+func (a *AuthLoader) SignAccessToken(appID string, orgID string, userID string, permissions []string) (string, error) {
+	now := time.Now()
+	claims := tokenauth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: userID, Issuer: a.authHost, Audience: jwt.ClaimStrings{tokenauth.AudRokwire},
+			IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(now.Add(fakeTokenTTL))},
+		AppID: appID, OrgID: orgID, Purpose: "access", AuthType: "fake", Authenticated: true,
+		Permissions: strings.Join(permissions, ","),
+	}
+	return tokenauth.GenerateSignedToken(&claims, a.key)
+}
+
+// NewAuthLoader creates a new fake auth service with a new key. The service is registered with its host and the auth
+// service with the host which issues the tokens - the Core BB host.
+func NewAuthLoader(serviceID string, serviceHost string, authHost string) (*AuthLoader, error) {
+	privKey, pubKey, err := keys.NewAsymmetricKeyPair(keys.RS256, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("error generating the fake auth key - %s", err)
+	}
+
+	services := []auth.ServiceReg{
+		{ServiceID: serviceID, Host: serviceHost},
+		{ServiceID: "auth", Host: authHost, PubKey: pubKey},
+	}
+	return &AuthLoader{ServiceRegSubscriptions: auth.NewServiceRegSubscriptions([]string{serviceID, "auth"}), authHost: authHost,
+		key: privKey, services: services}, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes
+
+import (
+	"sync"
+	"wellness/core/model"
+)
+
+// CoreAdapter is an in-process stand-in for the Core BB. It serves the configured deleted memberships.
+type CoreAdapter struct {
+	lock               sync.Mutex
+	deletedMemberships []model.DeletedUserData
+}
+
+// LoadDeletedMemberships gives the configured deleted memberships
+func (a *CoreAdapter) LoadDeletedMemberships() ([]model.DeletedUserData, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	result := make([]model.DeletedUserData, len(a.deletedMemberships))
+	copy(result, a.deletedMemberships)
+	return result, nil
+}
+
+// CheckServiceAccount checks the service account - the fake does not need one
+func (a *CoreAdapter) CheckServiceAccount() error {
+	return nil
+}
+
+// CheckReachability checks that the Core BB responds - the fake always responds
+func (a *CoreAdapter) CheckReachability() error {
+	return nil
+}
+
+// SetDeletedMemberships sets the deleted memberships served from now on
+func (a *CoreAdapter) SetDeletedMemberships(deletedMemberships []model.DeletedUserData) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.deletedMemberships = deletedMemberships
+}
+
+// NewCoreAdapter creates a new fake Core BB adapter serving the deleted memberships
+func NewCoreAdapter(deletedMemberships []model.DeletedUserData) *CoreAdapter {
+	return &CoreAdapter{deletedMemberships: deletedMemberships}
+}
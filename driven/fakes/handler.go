@@ -0,0 +1,117 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"wellness/core/model"
+)
+
+// Handler exposes the state of the fake building blocks so the tests can inspect and configure them:
+//
+//	GET /messages - the scheduled messages
+//	DELETE /messages - removes the scheduled messages
+//	GET /deleted-memberships - the deleted memberships served by the fake Core BB
+//	PUT /deleted-memberships - sets the deleted memberships served by the fake Core BB
+//	POST /tokens - signs an access token of a user, see tokenRequest
+type Handler struct {
+	notifications *NotificationsAdapter
+	core          *CoreAdapter
+	auth          *AuthLoader
+	mux           *http.ServeMux
+}
+
+// tokenRequest is the user an access token is signed for
+type tokenRequest struct {
+	AppID       string   `json:"app_id"`
+	OrgID       string   `json:"org_id"`
+	UserID      string   `json:"user_id"`
+	Permissions []string `json:"permissions"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) getMessages(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.notifications.Messages())
+}
+
+func (h *Handler) deleteMessages(w http.ResponseWriter, r *http.Request) {
+	h.notifications.Reset()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) getDeletedMemberships(w http.ResponseWriter, r *http.Request) {
+	deletedMemberships, _ := h.core.LoadDeletedMemberships()
+	writeJSON(w, deletedMemberships)
+}
+
+func (h *Handler) putDeletedMemberships(w http.ResponseWriter, r *http.Request) {
+	var deletedMemberships []model.DeletedUserData
+	err := json.NewDecoder(r.Body).Decode(&deletedMemberships)
+	if err != nil {
+		log.Printf("fakes: error on unmarshal the deleted memberships - %s", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	h.core.SetDeletedMemberships(deletedMemberships)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) postToken(w http.ResponseWriter, r *http.Request) {
+	var request tokenRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.AppID == "" || request.OrgID == "" || request.UserID == "" {
+		log.Printf("fakes: error on unmarshal the token request - %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.auth.SignAccessToken(request.AppID, request.OrgID, request.UserID, request.Permissions)
+	if err != nil {
+		log.Printf("fakes: error on signing the access token - %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]string{"access_token": token})
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("fakes: error on marshal - %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// NewHandler creates a new handler for the fake building blocks. The paths are relative - the caller strips its prefix.
+func NewHandler(notifications *NotificationsAdapter, core *CoreAdapter, auth *AuthLoader) *Handler {
+	h := &Handler{notifications: notifications, core: core, auth: auth, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /messages", h.getMessages)
+	h.mux.HandleFunc("DELETE /messages", h.deleteMessages)
+	h.mux.HandleFunc("GET /deleted-memberships", h.getDeletedMemberships)
+	h.mux.HandleFunc("PUT /deleted-memberships", h.putDeletedMemberships)
+	h.mux.HandleFunc("POST /tokens", h.postToken)
+	return h
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+// Message is a message scheduled in the fake Notifications BB
+type Message struct {
+	ID          string                        `json:"id"`
+	AppID       string                        `json:"app_id"`
+	OrgID       string                        `json:"org_id"`
+	Recipients  []model.NotificationRecipient `json:"recipients"`
+	Subject     string                        `json:"subject"`
+	Body        string                        `json:"body"`
+	Data        map[string]string             `json:"data"`
+	Time        *int64                        `json:"time"`
+	Deleted     bool                          `json:"deleted"`
+	DateCreated time.Time                     `json:"date_created"`
+	DateDeleted *time.Time                    `json:"date_deleted"`
+}
+
+// NotificationsAdapter is an in-process stand-in for the Notifications BB. It records the scheduled messages instead of sending them.
+type NotificationsAdapter struct {
+	lock     sync.Mutex
+	messages []Message
+}
+
+// SendNotification records the message and gives its id
+func (na *NotificationsAdapter) SendNotification(recipients []model.NotificationRecipient, topic *string, title string, text string, appID string, orgID string, time *int64, data map[string]string) (*string, error) {
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+
+	na.lock.Lock()
+	defer na.lock.Unlock()
+
+	message := Message{ID: uuid.NewString(), AppID: appID, OrgID: orgID, Recipients: recipients, Subject: title, Body: text,
+		Data: data, Time: time, DateCreated: now()}
+	na.messages = append(na.messages, message)
+	log.Printf("fake notifications: scheduled message %s for %d recipients", message.ID, len(recipients))
+	return &message.ID, nil
+}
+
+// DeleteNotification marks the message as deleted. It fails for an unknown message as the Notifications BB does.
+func (na *NotificationsAdapter) DeleteNotification(appID string, orgID string, id string) error {
+	na.lock.Lock()
+	defer na.lock.Unlock()
+
+	for i := range na.messages {
+		message := &na.messages[i]
+		if message.ID == id && !message.Deleted {
+			deleted := now()
+			message.Deleted = true
+			message.DateDeleted = &deleted
+			log.Printf("fake notifications: deleted message %s", id)
+			return nil
+		}
+	}
+	return fmt.Errorf("DeleteNotification: error with response code != 200")
+}
+
+// CheckReachability checks that the Notifications BB responds - the fake always responds
+func (na *NotificationsAdapter) CheckReachability() error {
+	return nil
+}
+
+// Messages gives the recorded messages in the order they were scheduled
+func (na *NotificationsAdapter) Messages() []Message {
+	na.lock.Lock()
+	defer na.lock.Unlock()
+
+	result := make([]Message, len(na.messages))
+	copy(result, na.messages)
+	return result
+}
+
+// Reset removes all the recorded messages
+func (na *NotificationsAdapter) Reset() {
+	na.lock.Lock()
+	defer na.lock.Unlock()
+
+	na.messages = nil
+}
+
+// NewNotificationsAdapter creates a new fake Notifications BB adapter
+func NewNotificationsAdapter() *NotificationsAdapter {
+	return &NotificationsAdapter{}
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
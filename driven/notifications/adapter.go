@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 	"wellness/core/model"
+	"wellness/utils"
 
 	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth"
 )
 
+// reachabilityTimeout is the time the Notifications BB has to respond to a reachability check
+const reachabilityTimeout = 5 * time.Second
+
 // MessageRef implemetnts appID, orgID and ID
 type MessageRef struct {
 	OrgID string `json:"org_id" bson:"org_id"`
@@ -35,11 +40,14 @@ func NewNotificationsAdapter(internalAPIKey string, notificationsHost string, ac
 
 // SendNotification sends notification to a user
 func (na *Adapter) SendNotification(recipients []model.NotificationRecipient, topic *string, title string, text string, appID string, orgID string, time *int64, data map[string]string) (*string, error) {
-	return na.sendNotification(recipients, topic, title, text, appID, orgID, time, data)
+	id, err := na.sendNotification(recipients, topic, title, text, appID, orgID, time, data)
+	utils.ObserveNotificationOperation("send", err)
+	return id, err
 }
 
 func (na *Adapter) sendNotification(recipients []model.NotificationRecipient, topic *string, title string, text string, appID string, orgID string, time *int64, data map[string]string) (*string, error) {
 	if len(recipients) > 0 {
+		appID, orgID = na.tenant(appID, orgID)
 		url := fmt.Sprintf("%s/api/bbs/message", na.baseURL)
 
 		async := true
@@ -96,6 +104,13 @@ func (na *Adapter) sendNotification(recipients []model.NotificationRecipient, to
 
 // DeleteNotification deletes notification
 func (na *Adapter) DeleteNotification(appID string, orgID string, id string) error {
+	err := na.deleteNotification(appID, orgID, id)
+	utils.ObserveNotificationOperation("delete", err)
+	return err
+}
+
+func (na *Adapter) deleteNotification(appID string, orgID string, id string) error {
+	appID, orgID = na.tenant(appID, orgID)
 
 	url := fmt.Sprintf("%s/api/bbs/message/%s", na.baseURL, id)
 
@@ -119,3 +134,29 @@ func (na *Adapter) DeleteNotification(appID string, orgID string, id string) err
 	}
 	return nil
 }
+
+// CheckReachability checks that the Notifications BB responds
+func (na *Adapter) CheckReachability() error {
+	client := http.Client{Timeout: reachabilityTimeout}
+	resp, err := client.Get(fmt.Sprintf("%s/version", na.baseURL))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("CheckReachability: error with response code - %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// tenant gives the multi-tenancy app and org for the legacy data which does not have them
+func (na *Adapter) tenant(appID string, orgID string) (string, string) {
+	if appID == "" {
+		appID = na.multiTenancyAppID
+	}
+	if orgID == "" {
+		orgID = na.multiTenancyOrgID
+	}
+	return appID, orgID
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"sync/atomic"
 	"time"
 	"wellness/core/model"
 
@@ -26,8 +27,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"github.com/rokwire/rokwire-building-block-sdk-go/utils/errors"
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
 	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logutils"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -35,13 +38,76 @@ import (
 // Adapter implements the Storage interface
 type Adapter struct {
 	db *database
+
+	//the app and org the legacy data - created before the multi-tenancy - belongs to
+	multiTenancyAppID string
+	multiTenancyOrgID string
+	//until the legacy data is backfilled it is read together with the data of the multi-tenancy app and org
+	multiTenancyBackfilled atomic.Bool
 }
 
 // Start starts the storage
 func (sa *Adapter) Start() error {
 
 	err := sa.db.start()
-	return err
+	if err != nil {
+		return err
+	}
+
+	//do not work with data migrated by a newer version of the service
+	err = sa.checkMigrationsVersion()
+	if err != nil {
+		return err
+	}
+
+	backfilled, err := sa.IsMigrationApplied(multiTenancyBackfillVersion)
+	if err != nil {
+		return err
+	}
+	sa.multiTenancyBackfilled.Store(backfilled)
+
+	return nil
+}
+
+// tenantFilter gives the filter elements which scope a query to the app and org.
+// While the legacy data is not backfilled, the multi-tenancy app and org also see the documents without app_id or org_id.
+func (sa *Adapter) tenantFilter(appID string, orgID string) bson.D {
+	if sa.multiTenancyBackfilled.Load() || appID != sa.multiTenancyAppID || orgID != sa.multiTenancyOrgID {
+		return bson.D{
+			primitive.E{Key: "org_id", Value: orgID},
+			primitive.E{Key: "app_id", Value: appID},
+		}
+	}
+
+	missing := bson.A{nil, ""}
+	return bson.D{
+		primitive.E{Key: "$or", Value: bson.A{
+			bson.D{primitive.E{Key: "org_id", Value: orgID}, primitive.E{Key: "app_id", Value: appID}},
+			bson.D{primitive.E{Key: "org_id", Value: bson.M{"$in": missing}}},
+			bson.D{primitive.E{Key: "app_id", Value: bson.M{"$in": missing}}},
+		}},
+	}
+}
+
+// Ping checks that the database is reachable
+func (sa *Adapter) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sa.db.mongoTimeout)
+	defer cancel()
+
+	err := sa.db.dbClient.Ping(ctx, readpref.Primary())
+	if err != nil {
+		return errors.WrapErrorAction("pinging", "database", nil, err)
+	}
+	return nil
+}
+
+// Stop stops watching the collections and disconnects from the database. The pending operations are given time until the context is done.
+func (sa *Adapter) Stop(ctx context.Context) error {
+	err := sa.db.stop(ctx)
+	if err != nil {
+		return errors.WrapErrorAction("disconnecting", "database", nil, err)
+	}
+	return nil
 }
 
 // PerformTransaction performs a transaction
@@ -57,6 +123,10 @@ func (sa *Adapter) PerformTransaction(transaction func(context TransactionContex
 		err = transaction(sessionContext)
 		if err != nil {
 			sa.abortTransaction(sessionContext)
+			if model.AsError(err) != nil {
+				//the typed errors of the transaction are given to the caller as they are
+				return err
+			}
 			return errors.WrapErrorAction("performing", logutils.TypeTransaction, nil, err)
 		}
 
@@ -72,15 +142,12 @@ func (sa *Adapter) PerformTransaction(transaction func(context TransactionContex
 }
 
 // GetTodoCategories gets all user defined todo categories
-func (sa *Adapter) GetTodoCategories(appID string, orgID string, userID string) ([]model.TodoCategory, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: userID},
-	}
+func (sa *Adapter) GetTodoCategories(context TransactionContext, appID string, orgID string, userID string) ([]model.TodoCategory, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
 
 	var result []model.TodoCategory
-	err := sa.db.todoCategories.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoCategories.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "position", Value: 1}, primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -102,16 +169,13 @@ func (sa *Adapter) GetTodoCategoriesByUserID(userID string) ([]model.TodoCategor
 }
 
 // GetTodoCategory gets a single user defined todo category by id
-func (sa *Adapter) GetTodoCategory(appID string, orgID string, userID string, id string) (*model.TodoCategory, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
+func (sa *Adapter) GetTodoCategory(context TransactionContext, appID string, orgID string, userID string, id string) (*model.TodoCategory, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: id},
-	}
+		primitive.E{Key: "_id", Value: id})
 
 	var result []model.TodoCategory
-	err := sa.db.todoCategories.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoCategories.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -123,83 +187,85 @@ func (sa *Adapter) GetTodoCategory(appID string, orgID string, userID string, id
 	return nil, nil
 }
 
+// GetLastTodoCategoryPosition gets the highest position of the user's todo categories, 0 when the user has none
+func (sa *Adapter) GetLastTodoCategoryPosition(context TransactionContext, appID string, orgID string, userID string) (float64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
+
+	limit := int64(1)
+	var result []model.TodoCategory
+	err := sa.db.todoCategories.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "position", Value: -1}}, Limit: &limit})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(result) > 0 {
+		return result[0].Position, nil
+	}
+
+	return 0, nil
+}
+
 // CreateTodoCategory create a new user defined todo category
-func (sa *Adapter) CreateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
+func (sa *Adapter) CreateTodoCategory(context TransactionContext, appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
 	category.ID = uuid.NewString()
 	category.OrgID = orgID
 	category.AppID = appID
 	category.UserID = userID
 	category.DateCreated = time.Now().UTC()
 
-	_, err := sa.db.todoCategories.InsertOne(&category)
+	_, err := sa.db.todoCategories.InsertOneWithContext(context, &category)
 	if err != nil {
 		return nil, err
 	}
 	return category, nil
 }
 
-// UpdateTodoCategory updates a user defined todo category
-func (sa *Adapter) UpdateTodoCategory(appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
-
-	err := sa.db.dbClient.UseSession(context.Background(), func(sessionContext mongo.SessionContext) error {
-		err := sessionContext.StartTransaction()
-		if err != nil {
-			log.Printf("error starting a transaction - %s", err)
-			return err
-		}
-
-		filter := bson.D{
-			primitive.E{Key: "app_id", Value: appID},
-			primitive.E{Key: "org_id", Value: orgID},
-			primitive.E{Key: "user_id", Value: userID},
-			primitive.E{Key: "_id", Value: category.ID}}
-		update := bson.D{
-			primitive.E{Key: "$set", Value: bson.D{
-				primitive.E{Key: "name", Value: category.Name},
-				primitive.E{Key: "color", Value: category.Color},
-				primitive.E{Key: "date_updated", Value: time.Now().UTC()},
-			}},
-		}
-		_, err = sa.db.todoCategories.UpdateOneWithContext(sessionContext, filter, update, nil)
-		if err != nil {
-			sa.abortTransaction(sessionContext)
-			log.Printf("error updating user defined todo category: %s", err)
-			return err
-		}
-
-		filter = bson.D{
-			primitive.E{Key: "app_id", Value: appID},
-			primitive.E{Key: "org_id", Value: orgID},
-			primitive.E{Key: "user_id", Value: userID},
-			primitive.E{Key: "category.id", Value: category.ID}}
-		update = bson.D{
-			primitive.E{Key: "$set", Value: bson.D{
-				primitive.E{Key: "category", Value: category.ToCategoryRef()},
-			}},
-		}
-		_, err = sa.db.todoEntries.UpdateManyWithContext(sessionContext, filter, update, nil)
-		if err != nil {
-			sa.abortTransaction(sessionContext)
-			log.Printf("error updating user defined todo category: %s", err)
+// UpdateTodoCategory updates a user defined todo category. It runs in its own transaction when no context is given
+func (sa *Adapter) UpdateTodoCategory(context TransactionContext, appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
+	if context == nil {
+		var updated *model.TodoCategory
+		err := sa.PerformTransaction(func(context TransactionContext) error {
+			var err error
+			updated, err = sa.UpdateTodoCategory(context, appID, orgID, userID, category)
 			return err
-		}
-
-		//commit the transaction
-		err = sessionContext.CommitTransaction(sessionContext)
+		})
 		if err != nil {
-			fmt.Println(err)
-			return err
+			return nil, err
 		}
+		return updated, nil
+	}
 
-		return nil
-	})
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "_id", Value: category.ID})
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "name", Value: category.Name},
+			primitive.E{Key: "color", Value: category.Color},
+			primitive.E{Key: "date_updated", Value: time.Now().UTC()},
+		}},
+	}
+	_, err := sa.db.todoCategories.UpdateOneWithContext(context, filter, update, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionUpdate, "todo category", nil, err)
+	}
 
+	//keep the category of the todo entries up to date
+	filter = append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "category.id", Value: category.ID})
+	update = bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "category", Value: category.ToCategoryRef()},
+		}},
+	}
+	_, err = sa.db.todoEntries.UpdateManyWithContext(context, filter, update, nil)
 	if err != nil {
-		log.Printf("error on update category: %s", err)
-		return nil, fmt.Errorf("error on update category: %s", err)
+		return nil, errors.WrapErrorAction(logutils.ActionUpdate, "todo entry", nil, err)
 	}
 
-	return sa.GetTodoCategory(appID, orgID, userID, category.ID)
+	return sa.GetTodoCategory(context, appID, orgID, userID, category.ID)
 }
 
 // DeleteTodoCategory deletes a user defined todo category
@@ -212,12 +278,9 @@ func (sa *Adapter) DeleteTodoCategory(appID string, orgID string, userID string,
 			return err
 		}
 
-		filter := bson.D{
-			primitive.E{Key: "app_id", Value: appID},
-			primitive.E{Key: "org_id", Value: orgID},
+		filter := append(sa.tenantFilter(appID, orgID),
 			primitive.E{Key: "user_id", Value: userID},
-			primitive.E{Key: "_id", Value: id},
-		}
+			primitive.E{Key: "_id", Value: id})
 
 		_, err = sa.db.todoCategories.DeleteOneWithContext(sessionContext, filter, nil)
 		if err != nil {
@@ -226,12 +289,9 @@ func (sa *Adapter) DeleteTodoCategory(appID string, orgID string, userID string,
 			return err
 		}
 
-		filter = bson.D{
-			primitive.E{Key: "app_id", Value: appID},
-			primitive.E{Key: "org_id", Value: orgID},
+		filter = append(sa.tenantFilter(appID, orgID),
 			primitive.E{Key: "user_id", Value: userID},
-			primitive.E{Key: "category.id", Value: id},
-		}
+			primitive.E{Key: "category.id", Value: id})
 		update := bson.D{
 			primitive.E{Key: "$set", Value: bson.D{
 				primitive.E{Key: "category", Value: bson.TypeNull},
@@ -263,30 +323,36 @@ func (sa *Adapter) DeleteTodoCategory(appID string, orgID string, userID string,
 }
 
 // DeleteTodoCategoriesForUsers the todo categories for users
-func (sa *Adapter) DeleteTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) error {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}},
+func (sa *Adapter) DeleteTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.todoCategories.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "todo category", nil, err)
 	}
+	return result.DeletedCount, nil
+}
 
-	_, err := sa.db.todoCategories.DeleteManyWithContext(nil, filter, nil)
+// CountTodoCategoriesForUsers counts the todo categories for users
+func (sa *Adapter) CountTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.todoCategories.CountDocuments(filter)
 	if err != nil {
-		return errors.WrapErrorAction(logutils.ActionDelete, "todo category", nil, err)
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "todo category", nil, err)
 	}
-	return nil
+	return count, nil
 }
 
 // GetTodoEntries gets user's todo entries
-func (sa *Adapter) GetTodoEntries(appID string, orgID string, userID string) ([]model.TodoEntry, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: userID},
-	}
+func (sa *Adapter) GetTodoEntries(context TransactionContext, appID string, orgID string, userID string) ([]model.TodoEntry, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
 
 	var result []model.TodoEntry
-	err := sa.db.todoEntries.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoEntries.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "position", Value: 1}, primitive.E{Key: "date_created", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -323,7 +389,7 @@ func (sa *Adapter) GetTodoEntriesForMigration() ([]model.TodoEntry, error) {
 	}
 
 	var result []model.TodoEntry
-	err := sa.db.todoEntries.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoEntries.Find(filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -332,15 +398,12 @@ func (sa *Adapter) GetTodoEntriesForMigration() ([]model.TodoEntry, error) {
 
 // GetTodoEntry get a single todo entry
 func (sa *Adapter) GetTodoEntry(context TransactionContext, appID string, orgID string, userID string, id string) (*model.TodoEntry, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: id},
-	}
+		primitive.E{Key: "_id", Value: id})
 
 	var result []model.TodoEntry
-	err := sa.db.todoEntries.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoEntries.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -352,8 +415,27 @@ func (sa *Adapter) GetTodoEntry(context TransactionContext, appID string, orgID
 	return nil, nil
 }
 
+// GetLastTodoEntryPosition gets the highest position of the user's todo entries, 0 when the user has none
+func (sa *Adapter) GetLastTodoEntryPosition(context TransactionContext, appID string, orgID string, userID string) (float64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
+
+	limit := int64(1)
+	var result []model.TodoEntry
+	err := sa.db.todoEntries.FindWithContext(context, filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "position", Value: -1}}, Limit: &limit})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(result) > 0 {
+		return result[0].Position, nil
+	}
+
+	return 0, nil
+}
+
 // CreateTodoEntry create a todo entry
-func (sa *Adapter) CreateTodoEntry(appID string, orgID string, userID string, category *model.TodoEntry, messageIDs model.MessageIDs, entityID string) (*model.TodoEntry, error) {
+func (sa *Adapter) CreateTodoEntry(context TransactionContext, appID string, orgID string, userID string, category *model.TodoEntry, messageIDs model.MessageIDs, entityID string) (*model.TodoEntry, error) {
 	category.ID = entityID
 	category.OrgID = orgID
 	category.AppID = appID
@@ -361,7 +443,7 @@ func (sa *Adapter) CreateTodoEntry(appID string, orgID string, userID string, ca
 	category.DateCreated = time.Now().UTC()
 	category.MessageIDs = messageIDs
 
-	_, err := sa.db.todoEntries.InsertOne(&category)
+	_, err := sa.db.todoEntries.InsertOneWithContext(context, &category)
 	if err != nil {
 		return nil, err
 	}
@@ -371,11 +453,9 @@ func (sa *Adapter) CreateTodoEntry(appID string, orgID string, userID string, ca
 // UpdateTodoEntry updates a todo entry
 func (sa *Adapter) UpdateTodoEntry(context TransactionContext, appID string, orgID string, userID string, todo *model.TodoEntry, id string) (*model.TodoEntry, error) {
 
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: id}}
+		primitive.E{Key: "_id", Value: id})
 	update := bson.D{
 		primitive.E{Key: "$set", Value: bson.D{
 			primitive.E{Key: "title", Value: todo.Title},
@@ -386,9 +466,14 @@ func (sa *Adapter) UpdateTodoEntry(context TransactionContext, appID string, org
 			primitive.E{Key: "due_date_time", Value: todo.DueDateTime},
 			primitive.E{Key: "reminder_type", Value: todo.ReminderType},
 			primitive.E{Key: "reminder_date_time", Value: todo.ReminderDateTime},
+			primitive.E{Key: "recurrence", Value: todo.Recurrence},
+			primitive.E{Key: "overdue", Value: todo.Overdue},
+			primitive.E{Key: "snooze_count", Value: todo.SnoozeCount},
 			primitive.E{Key: "work_days", Value: todo.WorkDays},
+			primitive.E{Key: "location", Value: todo.Location},
 			primitive.E{Key: "task_time", Value: todo.TaskTime},
 			primitive.E{Key: "message_ids", Value: todo.MessageIDs},
+			primitive.E{Key: "reminder_adjustments", Value: todo.ReminderAdjustments},
 			primitive.E{Key: "date_updated", Value: time.Now().UTC()},
 		}},
 	}
@@ -399,16 +484,15 @@ func (sa *Adapter) UpdateTodoEntry(context TransactionContext, appID string, org
 		return nil, err
 	}
 
-	return sa.GetTodoEntry(nil, appID, orgID, userID, id)
+	//read in the transaction, the update is not visible outside of it until it is committed
+	return sa.GetTodoEntry(context, appID, orgID, userID, id)
 }
 
 // DeleteTodoEntry deletes a todo entry
 func (sa *Adapter) DeleteTodoEntry(context TransactionContext, appID string, orgID string, userID string, id string) error {
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: id}}
+		primitive.E{Key: "_id", Value: id})
 
 	_, err := sa.db.todoEntries.DeleteOneWithContext(context, filter, nil)
 	if err != nil {
@@ -421,11 +505,9 @@ func (sa *Adapter) DeleteTodoEntry(context TransactionContext, appID string, org
 
 // DeleteCompletedTodoEntries deletes a completed todo entries
 func (sa *Adapter) DeleteCompletedTodoEntries(appID string, orgID string, userID string) error {
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "completed", Value: true}}
+		primitive.E{Key: "completed", Value: true})
 
 	_, err := sa.db.todoEntries.DeleteMany(filter, nil)
 	if err != nil {
@@ -437,18 +519,40 @@ func (sa *Adapter) DeleteCompletedTodoEntries(appID string, orgID string, userID
 }
 
 // DeleteTodoEntriesForUsers deletes todo entries for users
-func (sa *Adapter) DeleteTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) error {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}},
+func (sa *Adapter) DeleteTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.todoEntries.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "todo entry", nil, err)
 	}
+	return result.DeletedCount, nil
+}
 
-	_, err := sa.db.todoEntries.DeleteManyWithContext(nil, filter, nil)
+// CountTodoEntriesForUsers counts the todo entries for users
+func (sa *Adapter) CountTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.todoEntries.CountDocuments(filter)
 	if err != nil {
-		return errors.WrapErrorAction(logutils.ActionDelete, "todo entry", nil, err)
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "todo entry", nil, err)
 	}
-	return nil
+	return count, nil
+}
+
+// GetTodoEntriesForUsers gets the todo entries for users
+func (sa *Adapter) GetTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) ([]model.TodoEntry, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	var result []model.TodoEntry
+	err := sa.db.todoEntries.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "todo entry", nil, err)
+	}
+	return result, nil
 }
 
 // GetTodoEntriesWithCurrentReminderTime Gets all todo entries that are applied for the specified reminder datetime
@@ -474,10 +578,27 @@ func (sa *Adapter) GetTodoEntriesWithCurrentReminderTime(context TransactionCont
 	}
 
 	var result []model.TodoEntry
-	err := sa.db.todoEntries.FindOneAndUpdateWithContext(context, filter, update, &result, &options.FindOneAndUpdateOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoEntries.FindOneAndUpdateWithContext(context, filter, update, &result, &options.FindOneAndUpdateOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTodoEntriesDueBefore Gets the user's incomplete todo entries due before the specified datetime
+func (sa *Adapter) GetTodoEntriesDueBefore(appID string, orgID string, userID string, dueTime time.Time) ([]model.TodoEntry, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "completed", Value: false},
+		primitive.E{Key: "due_date_time", Value: bson.M{"$ne": nil, "$lt": dueTime}},
+	)
+
+	var result []model.TodoEntry
+	err := sa.db.todoEntries.Find(filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "due_date_time", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
+
 	return result, nil
 }
 
@@ -500,7 +621,7 @@ func (sa *Adapter) GetTodoEntriesWithCurrentDueTime(context TransactionContext,
 	}
 
 	var result []model.TodoEntry
-	err := sa.db.todoEntries.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.todoEntries.Find(filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -525,18 +646,84 @@ func (sa *Adapter) UpdateTodoEntriesTaskTime(context TransactionContext, ids []s
 	return nil
 }
 
+// UpdateTodoEntryPositions Updates the positions of the user todo entries by their ids
+func (sa *Adapter) UpdateTodoEntryPositions(context TransactionContext, appID string, orgID string, userID string, positions map[string]float64) error {
+	return sa.updatePositions(context, sa.db.todoEntries, appID, orgID, userID, positions)
+}
+
+// UpdateTodoCategoryPositions Updates the positions of the user todo categories by their ids
+func (sa *Adapter) UpdateTodoCategoryPositions(context TransactionContext, appID string, orgID string, userID string, positions map[string]float64) error {
+	return sa.updatePositions(context, sa.db.todoCategories, appID, orgID, userID, positions)
+}
+
+func (sa *Adapter) updatePositions(context TransactionContext, collection *collectionWrapper, appID string, orgID string, userID string, positions map[string]float64) error {
+	for id, position := range positions {
+		filter := append(sa.tenantFilter(appID, orgID),
+			primitive.E{Key: "user_id", Value: userID},
+			primitive.E{Key: "_id", Value: id})
+		update := bson.D{
+			primitive.E{Key: "$set", Value: bson.D{
+				primitive.E{Key: "position", Value: position},
+			}},
+		}
+		_, err := collection.UpdateOneWithContext(context, filter, update, nil)
+		if err != nil {
+			return errors.WrapErrorAction(logutils.ActionUpdate, "position", nil, err)
+		}
+	}
+	return nil
+}
+
+// GetTodoEntriesToMarkOverdue Gets at most limit todo entries after the id, ordered by id, which could be overdue at the
+// specified datetime but are not marked yet. The entries without a due time are given once their due day is over in the
+// time zone where the days end first - the caller checks them in the time zone of the user, see TodoEntry.IsOverdue
+func (sa *Adapter) GetTodoEntriesToMarkOverdue(context TransactionContext, at time.Time, afterID string, limit int64) ([]model.TodoEntry, error) {
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: bson.M{"$gt": afterID}},
+		primitive.E{Key: "completed", Value: false},
+		primitive.E{Key: "overdue", Value: bson.M{"$ne": true}},
+		primitive.E{Key: "$or", Value: []primitive.M{
+			{"has_due_time": true, "due_date_time": primitive.M{"$ne": nil, "$lt": at}},
+			{"has_due_time": primitive.M{"$ne": true}, "due_date_time": primitive.M{"$ne": nil, "$lte": at.AddDate(0, 0, -1).Add(model.EarliestDayEndOffset)}},
+		}},
+	}
+	findOptions := options.Find().SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}).SetLimit(limit)
+
+	var result []model.TodoEntry
+	err := sa.db.todoEntries.FindWithContext(context, filter, &result, findOptions)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "todo entry", nil, err)
+	}
+	return result, nil
+}
+
+// MarkTodoEntriesOverdue Marks the desired todo ids as overdue
+func (sa *Adapter) MarkTodoEntriesOverdue(context TransactionContext, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	filter := bson.D{primitive.E{Key: "_id", Value: bson.M{"$in": ids}}}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "overdue", Value: true},
+		}},
+	}
+	_, err := sa.db.todoEntries.UpdateManyWithContext(context, filter, update, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionUpdate, "todo entry", nil, err)
+	}
+	return nil
+}
+
 // Wellness Rings
 
 // GetRings gets user's wellness rings
 func (sa *Adapter) GetRings(appID string, orgID string, userID string) ([]model.Ring, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: userID},
-	}
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
 
 	var result []model.Ring
-	err := sa.db.rings.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.rings.Find(filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -559,15 +746,12 @@ func (sa *Adapter) GetRingsByUserID(userID string) ([]model.Ring, error) {
 
 // GetRing get a single user wellness ring
 func (sa *Adapter) GetRing(appID string, orgID string, userID string, id string) (*model.Ring, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: id},
-	}
+		primitive.E{Key: "_id", Value: id})
 
 	var result []model.Ring
-	err := sa.db.rings.Find(filter, &result, &options.FindOptions{Sort: bson.D{{"name", 1}}})
+	err := sa.db.rings.Find(filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
 	if err != nil {
 		return nil, err
 	}
@@ -609,11 +793,9 @@ func (sa *Adapter) DeleteRing(appID string, orgID string, userID string, id stri
 			return err
 		}
 
-		filter := bson.D{
-			primitive.E{Key: "app_id", Value: appID},
-			primitive.E{Key: "org_id", Value: orgID},
+		filter := append(sa.tenantFilter(appID, orgID),
 			primitive.E{Key: "user_id", Value: userID},
-			primitive.E{Key: "_id", Value: id}}
+			primitive.E{Key: "_id", Value: id})
 
 		_, err = sa.db.rings.DeleteOneWithContext(sessionContext, filter, nil)
 		if err != nil {
@@ -622,11 +804,9 @@ func (sa *Adapter) DeleteRing(appID string, orgID string, userID string, id stri
 			return err
 		}
 
-		filter = bson.D{
-			primitive.E{Key: "app_id", Value: appID},
-			primitive.E{Key: "org_id", Value: orgID},
+		filter = append(sa.tenantFilter(appID, orgID),
 			primitive.E{Key: "user_id", Value: userID},
-			primitive.E{Key: "ring_id", Value: id}}
+			primitive.E{Key: "ring_id", Value: id})
 
 		_, err = sa.db.ringsRecords.DeleteOneWithContext(sessionContext, filter, nil)
 		if err != nil {
@@ -661,11 +841,9 @@ func (sa *Adapter) CreateRingHistory(appID string, orgID string, userID string,
 	ringHistory.RingID = ringID
 	ringHistory.DateCreated = time.Now().UTC()
 
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: ringID}}
+		primitive.E{Key: "_id", Value: ringID})
 	update := bson.D{
 		primitive.E{Key: "$push", Value: bson.D{
 			primitive.E{Key: "history", Value: ringHistory},
@@ -691,16 +869,17 @@ func (sa *Adapter) DeleteRingHistory(appID string, orgID string, userID string,
 		return nil, fmt.Errorf("error on deleting ring history entry: %s", err)
 	}
 
-	if ring == nil || len(ring.History) < 2 {
-		log.Printf("ring contains one or less history items: %s", err)
-		return nil, fmt.Errorf("ring contains one or less history items: %s", err)
+	if ring == nil {
+		return nil, model.NewNotFoundError("ring", ringID)
+	}
+	if len(ring.History) < 2 {
+		log.Printf("ring %s contains one or less history items", ringID)
+		return nil, model.NewConflictError("the last history item of a ring cannot be deleted")
 	}
 
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: ringID}}
+		primitive.E{Key: "_id", Value: ringID})
 	update := bson.D{
 		primitive.E{Key: "$pull", Value: bson.D{
 			primitive.E{Key: "history", Value: primitive.M{"id": ringHistoryID}},
@@ -719,30 +898,153 @@ func (sa *Adapter) DeleteRingHistory(appID string, orgID string, userID string,
 }
 
 // DeleteRingsForUsers deletes rings for users
-func (sa *Adapter) DeleteRingsForUsers(appID string, orgID string, accountsIDs []string) error {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}},
+func (sa *Adapter) DeleteRingsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.rings.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "ring", nil, err)
 	}
+	return result.DeletedCount, nil
+}
 
-	_, err := sa.db.rings.DeleteManyWithContext(nil, filter, nil)
+// CountRingsForUsers counts the rings for users
+func (sa *Adapter) CountRingsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.rings.CountDocuments(filter)
 	if err != nil {
-		return errors.WrapErrorAction(logutils.ActionDelete, "ring", nil, err)
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "ring", nil, err)
 	}
-	return nil
+	return count, nil
 }
 
-// GetRingsRecords Get all ring records for the corresponding ring id
-func (sa *Adapter) GetRingsRecords(appID string, orgID string, userID string, ringID *string, startDateEpoch *int64, endDateEpoch *int64, offset *int64, limit *int64, order *string) ([]model.RingRecord, error) {
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "user_id", Value: userID},
+// GetRingsWithNudges gets a batch of the rings with nudges - the ones after the id, sorted by id. The rings of the users who
+// got the most nudges since the time - the cap of their app/org, the default cap when the app/org has none - are skipped.
+func (sa *Adapter) GetRingsWithNudges(afterID string, limit int64, since time.Time, caps []model.RingNudgesCap, defaultMax int) ([]model.Ring, error) {
+	var maxNudges interface{} = defaultMax
+	if len(caps) > 0 {
+		branches := make(bson.A, len(caps))
+		for i, item := range caps {
+			branches[i] = bson.M{
+				"case": bson.M{"$and": bson.A{bson.M{"$eq": bson.A{"$app_id", item.AppID}}, bson.M{"$eq": bson.A{"$org_id", item.OrgID}}}},
+				"then": item.Max,
+			}
+		}
+		maxNudges = bson.M{"$switch": bson.M{"branches": branches, "default": defaultMax}}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"nudges_enabled": true, "_id": bson.M{"$gt": afterID}}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+		bson.M{"$lookup": bson.M{
+			"from": "ring_nudges",
+			"let":  bson.M{"app_id": "$app_id", "org_id": "$org_id", "user_id": "$user_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$org_id", "$$org_id"}},
+					bson.M{"$eq": bson.A{"$app_id", "$$app_id"}},
+					bson.M{"$eq": bson.A{"$user_id", "$$user_id"}},
+					bson.M{"$gte": bson.A{"$date_created", since}},
+				}}}},
+				bson.M{"$count": "count"},
+			},
+			"as": "recent_nudges",
+		}},
+		bson.M{"$match": bson.M{"$expr": bson.M{"$lt": bson.A{
+			bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$recent_nudges.count", 0}}, 0}},
+			maxNudges,
+		}}}},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{"recent_nudges": 0}},
 	}
 
-	if ringID != nil {
-		filter = append(filter, primitive.E{Key: "ring_id", Value: ringID})
+	var result []model.Ring
+	err := sa.db.rings.Aggregate(pipeline, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "ring", nil, err)
+	}
+	return result, nil
+}
+
+// UpdateRingNudges turns the nudges of a user wellness ring on or off. It gives nil if there is no such ring.
+func (sa *Adapter) UpdateRingNudges(appID string, orgID string, userID string, id string, enabled bool) (*model.Ring, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "_id", Value: id})
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "nudges_enabled", Value: enabled},
+			primitive.E{Key: "date_updated", Value: time.Now().UTC()},
+		}},
+	}
+	_, err := sa.db.rings.UpdateOne(filter, update, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionUpdate, "ring", nil, err)
+	}
+
+	return sa.GetRing(appID, orgID, userID, id)
+}
+
+// InsertRingNudge records a ring nudge. It gives false if the ring has already been nudged on the date - by this or
+// another instance.
+func (sa *Adapter) InsertRingNudge(nudge model.RingNudge) (bool, error) {
+	_, err := sa.db.ringNudges.InsertOne(nudge)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WrapErrorAction(logutils.ActionInsert, "ring nudge", nil, err)
+	}
+	return true, nil
+}
+
+// CountRingNudges counts the ring nudges sent to a user since a time
+func (sa *Adapter) CountRingNudges(appID string, orgID string, userID string, since time.Time) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "date_created", Value: bson.M{"$gte": since}})
+
+	count, err := sa.db.ringNudges.CountDocuments(filter)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "ring nudge", nil, err)
+	}
+	return count, nil
+}
+
+// DeleteRingNudgesForUsers deletes the ring nudges of the users
+func (sa *Adapter) DeleteRingNudgesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.ringNudges.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "ring nudge", nil, err)
+	}
+	return result.DeletedCount, nil
+}
+
+// CountRingNudgesForUsers counts the ring nudges of the users
+func (sa *Adapter) CountRingNudgesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.ringNudges.CountDocuments(filter)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "ring nudge", nil, err)
+	}
+	return count, nil
+}
+
+// GetRingsRecords Get all ring records for the corresponding ring id
+func (sa *Adapter) GetRingsRecords(appID string, orgID string, userID string, ringID *string, startDateEpoch *int64, endDateEpoch *int64, offset *int64, limit *int64, order *string) ([]model.RingRecord, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
+
+	if ringID != nil {
+		filter = append(filter, primitive.E{Key: "ring_id", Value: ringID})
 	}
 
 	if startDateEpoch != nil {
@@ -758,9 +1060,9 @@ func (sa *Adapter) GetRingsRecords(appID string, orgID string, userID string, ri
 
 	findOptions := options.Find()
 	if order != nil && *order == "asc" {
-		findOptions.SetSort(bson.D{{"date_created", 1}})
+		findOptions.SetSort(bson.D{primitive.E{Key: "date_created", Value: 1}})
 	} else {
-		findOptions.SetSort(bson.D{{"date_created", -1}})
+		findOptions.SetSort(bson.D{primitive.E{Key: "date_created", Value: -1}})
 	}
 	if limit != nil {
 		findOptions.SetLimit(*limit)
@@ -795,12 +1097,9 @@ func (sa *Adapter) GetRingsRecordsByUserID(userID string) ([]model.RingRecord, e
 
 // GetRingsRecord gets a single ring record by id
 func (sa *Adapter) GetRingsRecord(appID string, orgID string, userID string, id string) (*model.RingRecord, error) {
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: id},
-	}
+		primitive.E{Key: "_id", Value: id})
 
 	var list []model.RingRecord
 	err := sa.db.ringsRecords.Find(filter, &list, nil)
@@ -835,11 +1134,9 @@ func (sa *Adapter) CreateRingsRecord(appID string, orgID string, userID string,
 func (sa *Adapter) UpdateRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error) {
 	now := time.Now().UTC()
 	record.DateUpdated = &now
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
+	filter := append(sa.tenantFilter(appID, orgID),
 		primitive.E{Key: "user_id", Value: userID},
-		primitive.E{Key: "_id", Value: record.ID}}
+		primitive.E{Key: "_id", Value: record.ID})
 	update := bson.D{
 		primitive.E{Key: "$set", Value: bson.D{
 			primitive.E{Key: "value", Value: record.Value},
@@ -858,11 +1155,8 @@ func (sa *Adapter) UpdateRingsRecord(appID string, orgID string, userID string,
 
 // DeleteRingsRecords deletes a ring record
 func (sa *Adapter) DeleteRingsRecords(appID string, orgID string, userID string, ringID *string, recordID *string) error {
-	filter := bson.D{
-		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "user_id", Value: userID},
-	}
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID})
 	if ringID != nil {
 		filter = append(filter, primitive.E{Key: "ring_id", Value: *ringID})
 	}
@@ -880,16 +1174,477 @@ func (sa *Adapter) DeleteRingsRecords(appID string, orgID string, userID string,
 }
 
 // DeleteRingsRecordsForUsers deletes a rings records for users
-func (sa *Adapter) DeleteRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) error {
+func (sa *Adapter) DeleteRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.ringsRecords.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "ring", nil, err)
+	}
+	return result.DeletedCount, nil
+}
+
+// CountRingsRecordsForUsers counts the rings records for users
+func (sa *Adapter) CountRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.ringsRecords.CountDocuments(filter)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "ring", nil, err)
+	}
+	return count, nil
+}
+
+// CreateNotificationDeleteFailures stores the notifications which could not be deleted
+func (sa *Adapter) CreateNotificationDeleteFailures(failures []model.NotificationDeleteFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, len(failures))
+	for i, failure := range failures {
+		documents[i] = failure
+	}
+
+	_, err := sa.db.notificationDeleteFailures.InsertMany(documents, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionInsert, "notification delete failure", nil, err)
+	}
+	return nil
+}
+
+// CreateDeletionAudit stores the audit of a deleted users data processing
+func (sa *Adapter) CreateDeletionAudit(audit model.DeletionAudit) error {
+	_, err := sa.db.deletionAudits.InsertOne(audit)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionInsert, "deletion audit", nil, err)
+	}
+	return nil
+}
+
+// GetDeletionAudits gets the deletion audits, the newest first
+func (sa *Adapter) GetDeletionAudits(appID *string, orgID *string, offset *int64, limit *int64) ([]model.DeletionAudit, error) {
+	filter := bson.D{}
+	if appID != nil {
+		filter = append(filter, primitive.E{Key: "app_id", Value: *appID})
+	}
+	if orgID != nil {
+		filter = append(filter, primitive.E{Key: "org_id", Value: *orgID})
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{primitive.E{Key: "date_created", Value: -1}})
+	if limit != nil {
+		findOptions.SetLimit(*limit)
+	}
+	if offset != nil {
+		findOptions.SetSkip(*offset)
+	}
+
+	var result []model.DeletionAudit
+	err := sa.db.deletionAudits.Find(filter, &result, findOptions)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "deletion audit", nil, err)
+	}
+	return result, nil
+}
+
+// RegisterStorageListener registers a listener of the storage changes
+func (sa *Adapter) RegisterStorageListener(listener Listener) {
+	sa.db.addListener(listener)
+}
+
+// GetAppOrgConfigs gets the configs of all the apps/orgs
+func (sa *Adapter) GetAppOrgConfigs() ([]model.AppOrgConfig, error) {
+	var result []model.AppOrgConfig
+	err := sa.db.configs.Find(bson.D{}, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "config", nil, err)
+	}
+	return result, nil
+}
+
+// GetAppOrgConfig gets the config of an app/org. It gives nil if the app/org has no config.
+func (sa *Adapter) GetAppOrgConfig(appID string, orgID string) (*model.AppOrgConfig, error) {
+	filter := bson.D{primitive.E{Key: "app_id", Value: appID}, primitive.E{Key: "org_id", Value: orgID}}
+
+	var result []model.AppOrgConfig
+	err := sa.db.configs.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "config", nil, err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// InsertAppOrgConfig inserts the config of an app/org
+func (sa *Adapter) InsertAppOrgConfig(config model.AppOrgConfig) error {
+	_, err := sa.db.configs.InsertOne(config)
+	if mongo.IsDuplicateKeyError(err) {
+		return model.NewConflictError("the app/org already has a config")
+	}
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionInsert, "config", nil, err)
+	}
+	return nil
+}
+
+// UpdateAppOrgConfig replaces the config of an app/org
+func (sa *Adapter) UpdateAppOrgConfig(config model.AppOrgConfig) error {
+	filter := bson.D{primitive.E{Key: "_id", Value: config.ID}}
+	err := sa.db.configs.ReplaceOne(filter, config, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionUpdate, "config", nil, err)
+	}
+	return nil
+}
+
+// DeleteAppOrgConfig deletes the config of an app/org
+func (sa *Adapter) DeleteAppOrgConfig(appID string, orgID string) error {
+	filter := bson.D{primitive.E{Key: "app_id", Value: appID}, primitive.E{Key: "org_id", Value: orgID}}
+	_, err := sa.db.configs.DeleteOne(filter, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionDelete, "config", nil, err)
+	}
+	return nil
+}
+
+// GetUserPreferences gets the preferences of a user. It gives nil if the user has no preferences.
+func (sa *Adapter) GetUserPreferences(appID string, orgID string, userID string) (*model.UserPreferences, error) {
+	filter := append(sa.tenantFilter(appID, orgID), primitive.E{Key: "user_id", Value: userID})
+
+	var result []model.UserPreferences
+	err := sa.db.userPreferences.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "user preferences", nil, err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// GetUserPreferencesByUserID gets the preferences of a user in all the apps/orgs
+func (sa *Adapter) GetUserPreferencesByUserID(userID string) ([]model.UserPreferences, error) {
+	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
+
+	var result []model.UserPreferences
+	err := sa.db.userPreferences.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "user preferences", nil, err)
+	}
+	return result, nil
+}
+
+// SaveUserPreferences inserts the preferences of a user or updates the fields the user edits. The fields kept by the
+// service, like the last digest date, are not changed, so the concurrent updates of them are not lost.
+func (sa *Adapter) SaveUserPreferences(appID string, orgID string, userID string, preferences model.UserPreferences) (*model.UserPreferences, error) {
+	now := time.Now().UTC()
 	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "app_id", Value: appID},
-		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}},
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "user_id", Value: userID},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "timezone", Value: preferences.Timezone},
+			primitive.E{Key: "language", Value: preferences.Language},
+			primitive.E{Key: "week_start_day", Value: preferences.WeekStartDay},
+			primitive.E{Key: "default_reminder_offsets", Value: preferences.DefaultReminderOffsets},
+			primitive.E{Key: "quiet_hours", Value: preferences.QuietHours},
+			primitive.E{Key: "notification_opt_outs", Value: preferences.NotificationOptOuts},
+			primitive.E{Key: "digest_time", Value: preferences.DigestTime},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+		primitive.E{Key: "$setOnInsert", Value: bson.D{
+			primitive.E{Key: "_id", Value: uuid.NewString()},
+			primitive.E{Key: "last_digest_date", Value: ""},
+			primitive.E{Key: "date_created", Value: now},
+		}},
+	}
+	updateOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var result model.UserPreferences
+	err := sa.db.userPreferences.FindOneAndUpdate(filter, update, &result, updateOptions)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionSave, "user preferences", nil, err)
+	}
+	return &result, nil
+}
+
+// DeleteUserPreferences deletes the preferences of a user
+func (sa *Adapter) DeleteUserPreferences(appID string, orgID string, userID string) error {
+	filter := append(sa.tenantFilter(appID, orgID), primitive.E{Key: "user_id", Value: userID})
+	_, err := sa.db.userPreferences.DeleteOne(filter, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionDelete, "user preferences", nil, err)
+	}
+	return nil
+}
+
+// DeleteUserPreferencesForUsers deletes the preferences of the users
+func (sa *Adapter) DeleteUserPreferencesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.userPreferences.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "user preferences", nil, err)
+	}
+	return result.DeletedCount, nil
+}
+
+// CountUserPreferencesForUsers counts the preferences of the users
+func (sa *Adapter) CountUserPreferencesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.userPreferences.CountDocuments(filter)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "user preferences", nil, err)
+	}
+	return count, nil
+}
+
+// GetUserPreferencesWithDigest gets a batch of the preferences of the users who want the daily digest - the ones after the
+// id, sorted by id
+func (sa *Adapter) GetUserPreferencesWithDigest(afterID string, limit int64) ([]model.UserPreferences, error) {
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: bson.M{"$gt": afterID}},
+		primitive.E{Key: "digest_time", Value: bson.M{"$nin": []interface{}{"", nil}}},
+	}
+	findOptions := options.Find().SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}).SetLimit(limit)
+
+	var result []model.UserPreferences
+	err := sa.db.userPreferences.Find(filter, &result, findOptions)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "user preferences", nil, err)
+	}
+	return result, nil
+}
+
+// MarkDigestSent sets the date of the last daily digest of the user. It gives false if the digest of the date has
+// already been marked - by this or another instance.
+func (sa *Adapter) MarkDigestSent(preferencesID string, date string) (bool, error) {
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: preferencesID},
+		primitive.E{Key: "last_digest_date", Value: bson.M{"$ne": date}},
+	}
+	update := bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: "last_digest_date", Value: date}}}}
+
+	result, err := sa.db.userPreferences.UpdateOne(filter, update, nil)
+	if err != nil {
+		return false, errors.WrapErrorAction(logutils.ActionUpdate, "user preferences", nil, err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// GetNotificationTemplates gets the notification templates of an app/org
+func (sa *Adapter) GetNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error) {
+	filter := bson.D{primitive.E{Key: "app_id", Value: appID}, primitive.E{Key: "org_id", Value: orgID}}
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{primitive.E{Key: "language", Value: 1}})
+
+	var result []model.NotificationTemplate
+	err := sa.db.notificationTemplates.Find(filter, &result, findOptions)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "notification template", nil, err)
+	}
+	return result, nil
+}
+
+// GetNotificationTemplate gets a notification template of an app/org. It gives nil if there is no such template.
+func (sa *Adapter) GetNotificationTemplate(appID string, orgID string, id string) (*model.NotificationTemplate, error) {
+	filter := bson.D{primitive.E{Key: "_id", Value: id}, primitive.E{Key: "app_id", Value: appID}, primitive.E{Key: "org_id", Value: orgID}}
+
+	var result []model.NotificationTemplate
+	err := sa.db.notificationTemplates.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "notification template", nil, err)
+	}
+	if len(result) == 0 {
+		return nil, nil
 	}
+	return &result[0], nil
+}
 
-	_, err := sa.db.ringsRecords.DeleteManyWithContext(nil, filter, nil)
+// InsertNotificationTemplate inserts a notification template
+func (sa *Adapter) InsertNotificationTemplate(template model.NotificationTemplate) error {
+	_, err := sa.db.notificationTemplates.InsertOne(template)
+	if mongo.IsDuplicateKeyError(err) {
+		return model.NewConflictError("the app/org already has a " + template.Kind + " template in " + template.Language)
+	}
 	if err != nil {
-		return errors.WrapErrorAction(logutils.ActionDelete, "ring", nil, err)
+		return errors.WrapErrorAction(logutils.ActionInsert, "notification template", nil, err)
+	}
+	return nil
+}
+
+// UpdateNotificationTemplate replaces a notification template
+func (sa *Adapter) UpdateNotificationTemplate(template model.NotificationTemplate) error {
+	filter := bson.D{primitive.E{Key: "_id", Value: template.ID}}
+	err := sa.db.notificationTemplates.ReplaceOne(filter, template, nil)
+	if mongo.IsDuplicateKeyError(err) {
+		return model.NewConflictError("the app/org already has a " + template.Kind + " template in " + template.Language)
+	}
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionUpdate, "notification template", nil, err)
+	}
+	return nil
+}
+
+// DeleteNotificationTemplate deletes a notification template of an app/org
+func (sa *Adapter) DeleteNotificationTemplate(appID string, orgID string, id string) error {
+	filter := bson.D{primitive.E{Key: "_id", Value: id}, primitive.E{Key: "app_id", Value: appID}, primitive.E{Key: "org_id", Value: orgID}}
+	_, err := sa.db.notificationTemplates.DeleteOne(filter, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionDelete, "notification template", nil, err)
+	}
+	return nil
+}
+
+// GetTodoTemplates gets the todo templates of the users of an app/org - the empty user id is for the templates of the app/org
+func (sa *Adapter) GetTodoTemplates(appID string, orgID string, userIDs []string) ([]model.TodoTemplate, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": userIDs}})
+
+	var result []model.TodoTemplate
+	err := sa.db.todoTemplates.Find(filter, &result, &options.FindOptions{Sort: bson.D{primitive.E{Key: "name", Value: 1}}})
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "todo template", nil, err)
+	}
+	return result, nil
+}
+
+// GetTodoTemplatesByUserID gets all the todo templates of a user
+func (sa *Adapter) GetTodoTemplatesByUserID(userID string) ([]model.TodoTemplate, error) {
+	filter := bson.D{primitive.E{Key: "user_id", Value: userID}}
+
+	var result []model.TodoTemplate
+	err := sa.db.todoTemplates.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "todo template", nil, err)
+	}
+	return result, nil
+}
+
+// GetTodoTemplate gets a todo template of an app/org. It gives nil if there is no such template.
+func (sa *Adapter) GetTodoTemplate(appID string, orgID string, id string) (*model.TodoTemplate, error) {
+	filter := append(sa.tenantFilter(appID, orgID), primitive.E{Key: "_id", Value: id})
+
+	var result []model.TodoTemplate
+	err := sa.db.todoTemplates.Find(filter, &result, nil)
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "todo template", nil, err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// InsertTodoTemplate inserts a todo template
+func (sa *Adapter) InsertTodoTemplate(template model.TodoTemplate) error {
+	_, err := sa.db.todoTemplates.InsertOne(template)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionInsert, "todo template", nil, err)
+	}
+	return nil
+}
+
+// UpdateTodoTemplate replaces a todo template
+func (sa *Adapter) UpdateTodoTemplate(template model.TodoTemplate) error {
+	filter := bson.D{primitive.E{Key: "_id", Value: template.ID}}
+	err := sa.db.todoTemplates.ReplaceOne(filter, template, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionUpdate, "todo template", nil, err)
+	}
+	return nil
+}
+
+// DeleteTodoTemplate deletes a todo template of a user - of the app/org when the user id is empty
+func (sa *Adapter) DeleteTodoTemplate(appID string, orgID string, userID string, id string) error {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "_id", Value: id})
+	_, err := sa.db.todoTemplates.DeleteOne(filter, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionDelete, "todo template", nil, err)
+	}
+	return nil
+}
+
+// DeleteTodoTemplatesForUsers deletes the todo templates of the users
+func (sa *Adapter) DeleteTodoTemplatesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	result, err := sa.db.todoTemplates.DeleteManyWithContext(nil, filter, nil)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionDelete, "todo template", nil, err)
+	}
+	return result.DeletedCount, nil
+}
+
+// CountTodoTemplatesForUsers counts the todo templates of the users
+func (sa *Adapter) CountTodoTemplatesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	filter := append(sa.tenantFilter(appID, orgID),
+		primitive.E{Key: "user_id", Value: bson.M{"$in": accountsIDs}})
+
+	count, err := sa.db.todoTemplates.CountDocuments(filter)
+	if err != nil {
+		return 0, errors.WrapErrorAction(logutils.ActionCount, "todo template", nil, err)
+	}
+	return count, nil
+}
+
+// AcquireLock acquires the named lock for the owner until the expiration time.
+// The owner can acquire it again to change the expiration time. It gives false if the lock is held by another owner.
+func (sa *Adapter) AcquireLock(name string, owner string, expiresAt time.Time) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: name},
+		primitive.E{Key: "$or", Value: []bson.M{
+			{"owner": owner},
+			{"expires_at": bson.M{"$lte": now}},
+		}},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "owner", Value: owner},
+			primitive.E{Key: "expires_at", Value: expiresAt.UTC()},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+		primitive.E{Key: "$setOnInsert", Value: bson.D{
+			primitive.E{Key: "date_created", Value: now},
+		}},
+	}
+
+	_, err := sa.db.locks.UpdateOne(filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			//the lock exists but it is held by another owner
+			return false, nil
+		}
+		return false, errors.WrapErrorAction(logutils.ActionUpdate, "lock", &logutils.FieldArgs{"name": name}, err)
+	}
+	return true, nil
+}
+
+// ReleaseLock releases the named lock if it is held by the owner
+func (sa *Adapter) ReleaseLock(name string, owner string) error {
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: name},
+		primitive.E{Key: "owner", Value: owner},
+	}
+
+	_, err := sa.db.locks.DeleteOne(filter, nil)
+	if err != nil {
+		return errors.WrapErrorAction(logutils.ActionDelete, "lock", &logutils.FieldArgs{"name": name}, err)
 	}
 	return nil
 }
@@ -902,7 +1657,8 @@ func (sa *Adapter) abortTransaction(sessionContext mongo.SessionContext) {
 }
 
 // NewStorageAdapter creates a new storage adapter instance
-func NewStorageAdapter(mongoDBAuth string, mongoDBName string, mongoTimeout string) *Adapter {
+func NewStorageAdapter(mongoDBAuth string, mongoDBName string, mongoTimeout string, multiTenancyAppID string, multiTenancyOrgID string,
+	logger *logs.Logger) *Adapter {
 	timeout, err := strconv.Atoi(mongoTimeout)
 	if err != nil {
 		log.Println("Set default timeout - 500")
@@ -910,8 +1666,14 @@ func NewStorageAdapter(mongoDBAuth string, mongoDBName string, mongoTimeout stri
 	}
 	timeoutMS := time.Millisecond * time.Duration(timeout)
 
-	db := &database{mongoDBAuth: mongoDBAuth, mongoDBName: mongoDBName, mongoTimeout: timeoutMS}
-	return &Adapter{db: db}
+	db := &database{mongoDBAuth: mongoDBAuth, mongoDBName: mongoDBName, mongoTimeout: timeoutMS, logger: logger}
+	return &Adapter{db: db, multiTenancyAppID: multiTenancyAppID, multiTenancyOrgID: multiTenancyOrgID}
+}
+
+// Listener listens for the storage changes
+type Listener interface {
+	OnConfigsUpdated()
+	OnNotificationTemplatesUpdated()
 }
 
 // TransactionContext wraps mongo.SessionContext for use by external packages
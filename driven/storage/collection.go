@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"time"
+	"wellness/utils"
 
 	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
 	"go.mongodb.org/mongo-driver/bson"
@@ -59,11 +60,13 @@ func (collWrapper *collectionWrapper) FindWithParams(ctx context.Context, filter
 		filter = bson.D{}
 	}
 
+	started := time.Now()
 	cur, err := collWrapper.coll.Find(ctx, filter, findOptions)
 
 	if err == nil {
 		err = cur.All(ctx, result)
 	}
+	collWrapper.observe("find", started, err)
 
 	return err
 }
@@ -84,7 +87,9 @@ func (collWrapper *collectionWrapper) FindOneWithContext(ctx context.Context, fi
 		findOptions = options.FindOne() // crash if not added!
 	}
 
+	started := time.Now()
 	singleResult := collWrapper.coll.FindOne(ctx, filter, findOptions)
+	collWrapper.observe("find_one", started, singleResult.Err())
 	if singleResult.Err() != nil {
 		return singleResult.Err()
 	}
@@ -114,7 +119,9 @@ func (collWrapper *collectionWrapper) ReplaceOneWithContext(ctx context.Context,
 		replaceOptions = options.Replace() // crash if not added!
 	}
 
+	started := time.Now()
 	res, err := collWrapper.coll.ReplaceOne(ctx, filter, replacement, replaceOptions)
+	collWrapper.observe("replace_one", started, err)
 	if err != nil {
 		return err
 	}
@@ -142,7 +149,9 @@ func (collWrapper *collectionWrapper) InsertOneWithContext(ctx context.Context,
 
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 
+	started := time.Now()
 	ins, err := collWrapper.coll.InsertOne(ctx, data)
+	collWrapper.observe("insert_one", started, err)
 	cancel()
 
 	if err == nil {
@@ -164,7 +173,9 @@ func (collWrapper *collectionWrapper) InsertManyWithContext(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	started := time.Now()
 	result, err := collWrapper.coll.InsertMany(ctx, documents, opts)
+	collWrapper.observe("insert_many", started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +204,9 @@ func (collWrapper *collectionWrapper) DeleteManyWithParams(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, *timeout)
 	defer cancel()
 
+	started := time.Now()
 	result, err := collWrapper.coll.DeleteMany(ctx, filter, opts)
+	collWrapper.observe("delete_many", started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -213,7 +226,9 @@ func (collWrapper *collectionWrapper) DeleteOneWithContext(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	started := time.Now()
 	result, err := collWrapper.coll.DeleteOne(ctx, filter, opts)
+	collWrapper.observe("delete_one", started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -233,7 +248,9 @@ func (collWrapper *collectionWrapper) UpdateOneWithContext(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	started := time.Now()
 	updateResult, err := collWrapper.coll.UpdateOne(ctx, filter, update, opts)
+	collWrapper.observe("update_one", started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -254,7 +271,9 @@ func (collWrapper *collectionWrapper) UpdateManyWithContext(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	started := time.Now()
 	updateResult, err := collWrapper.coll.UpdateMany(ctx, filter, update, opts)
+	collWrapper.observe("update_many", started, err)
 	if err != nil {
 		return nil, err
 	}
@@ -274,7 +293,9 @@ func (collWrapper *collectionWrapper) FindOneAndUpdateWithContext(ctx context.Co
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	started := time.Now()
 	singleResult := collWrapper.coll.FindOneAndUpdate(ctx, filter, update, opts)
+	collWrapper.observe("find_one_and_update", started, singleResult.Err())
 	if singleResult.Err() != nil {
 		return singleResult.Err()
 	}
@@ -293,7 +314,9 @@ func (collWrapper *collectionWrapper) CountDocuments(filter interface{}) (int64,
 		filter = bson.D{}
 	}
 
+	started := time.Now()
 	count, err := collWrapper.coll.CountDocuments(ctx, filter)
+	collWrapper.observe("count", started, err)
 
 	if err != nil {
 		return -1, err
@@ -301,19 +324,20 @@ func (collWrapper *collectionWrapper) CountDocuments(filter interface{}) (int64,
 	return count, nil
 }
 
-func (collWrapper *collectionWrapper) Watch(pipeline interface{}, l *logs.Logger) {
+// Watch watches the changes of the collection until the context is done. It resumes after the errors.
+func (collWrapper *collectionWrapper) Watch(ctx context.Context, pipeline interface{}, l *logs.Logger) {
 	var rt bson.Raw
 	var err error
-	for {
-		rt, err = collWrapper.watch(pipeline, rt, l)
-		if err != nil {
+	for ctx.Err() == nil {
+		rt, err = collWrapper.watch(ctx, pipeline, rt, l)
+		if err != nil && ctx.Err() == nil {
 			l.Errorf("mongo watch error: %s\n", err.Error())
 		}
 	}
 }
 
 // Helper function for Watch
-func (collWrapper *collectionWrapper) watch(pipeline interface{}, resumeToken bson.Raw, l *logs.Logger) (bson.Raw, error) {
+func (collWrapper *collectionWrapper) watch(ctx context.Context, pipeline interface{}, resumeToken bson.Raw, l *logs.Logger) (bson.Raw, error) {
 	if pipeline == nil {
 		pipeline = []bson.M{}
 	}
@@ -324,13 +348,15 @@ func (collWrapper *collectionWrapper) watch(pipeline interface{}, resumeToken bs
 		opts.SetResumeAfter(resumeToken)
 	}
 
-	ctx := context.Background()
 	cur, err := collWrapper.coll.Watch(ctx, pipeline, opts)
 	if err != nil {
-		time.Sleep(time.Second * 3)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second * 3):
+		}
 		return nil, fmt.Errorf("error watching: %s", err)
 	}
-	defer cur.Close(ctx)
+	defer cur.Close(context.Background())
 
 	var changeDoc map[string]interface{}
 	l.Infof("%s: waiting for changes\n", collWrapper.coll.Name())
@@ -341,6 +367,9 @@ func (collWrapper *collectionWrapper) watch(pipeline interface{}, resumeToken bs
 		collWrapper.database.onDataChanged(changeDoc)
 	}
 
+	if ctx.Err() != nil {
+		return cur.ResumeToken(), nil
+	}
 	if err := cur.Err(); err != nil {
 		return cur.ResumeToken(), fmt.Errorf("error cur.Err(): %s", err)
 	}
@@ -412,11 +441,13 @@ func (collWrapper *collectionWrapper) AggregateWithContext(ctx context.Context,
 	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
 	defer cancel()
 
+	started := time.Now()
 	cursor, err := collWrapper.coll.Aggregate(ctx, pipeline, ops)
 
 	if err == nil {
 		err = cursor.All(ctx, result)
 	}
+	collWrapper.observe("aggregate", started, err)
 
 	return err
 }
@@ -431,3 +462,11 @@ func (collWrapper *collectionWrapper) Drop() error {
 	}
 	return nil
 }
+
+// observe records the latency and the failure of an operation. Not finding a document is not a failure.
+func (collWrapper *collectionWrapper) observe(operation string, started time.Time, err error) {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = nil
+	}
+	utils.ObserveStorageOperation(collWrapper.coll.Name(), operation, started, err)
+}
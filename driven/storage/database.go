@@ -17,11 +17,13 @@ package storage
 import (
 	"context"
 	"log"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -31,6 +33,8 @@ type database struct {
 	mongoDBName  string
 	mongoTimeout time.Duration
 
+	logger *logs.Logger
+
 	db       *mongo.Database
 	dbClient *mongo.Client
 
@@ -38,6 +42,23 @@ type database struct {
 	todoEntries    *collectionWrapper
 	rings          *collectionWrapper
 	ringsRecords   *collectionWrapper
+
+	notificationDeleteFailures *collectionWrapper
+	deletionAudits             *collectionWrapper
+	locks                      *collectionWrapper
+	migrations                 *collectionWrapper
+	notificationTemplates      *collectionWrapper
+	userPreferences            *collectionWrapper
+	ringNudges                 *collectionWrapper
+	todoTemplates              *collectionWrapper
+
+	configs *collectionWrapper
+
+	listeners     []Listener
+	listenersLock sync.RWMutex
+
+	stopWatch context.CancelFunc
+	watchDone chan struct{}
 }
 
 func (m *database) start() error {
@@ -88,18 +109,115 @@ func (m *database) start() error {
 		return err
 	}
 
+	notificationDeleteFailures := &collectionWrapper{database: m, coll: db.Collection("notification_delete_failures")}
+	err = m.applyNotificationDeleteFailuresChecks(notificationDeleteFailures)
+	if err != nil {
+		return err
+	}
+
+	deletionAudits := &collectionWrapper{database: m, coll: db.Collection("deletion_audits")}
+	err = m.applyDeletionAuditsChecks(deletionAudits)
+	if err != nil {
+		return err
+	}
+
+	locks := &collectionWrapper{database: m, coll: db.Collection("locks")}
+	err = m.applyLocksChecks(locks)
+	if err != nil {
+		return err
+	}
+
+	migrations := &collectionWrapper{database: m, coll: db.Collection("migrations")}
+
+	notificationTemplates := &collectionWrapper{database: m, coll: db.Collection("notification_templates")}
+	err = m.applyNotificationTemplatesChecks(notificationTemplates)
+	if err != nil {
+		return err
+	}
+
+	userPreferences := &collectionWrapper{database: m, coll: db.Collection("user_preferences")}
+	err = m.applyUserPreferencesChecks(userPreferences)
+	if err != nil {
+		return err
+	}
+
+	ringNudges := &collectionWrapper{database: m, coll: db.Collection("ring_nudges")}
+	err = m.applyRingNudgesChecks(ringNudges)
+	if err != nil {
+		return err
+	}
+
+	todoTemplates := &collectionWrapper{database: m, coll: db.Collection("todo_templates")}
+	err = m.applyTodoTemplatesChecks(todoTemplates)
+	if err != nil {
+		return err
+	}
+
+	configs := &collectionWrapper{database: m, coll: db.Collection("configs")}
+	err = m.applyConfigsChecks(configs)
+	if err != nil {
+		return err
+	}
+
 	m.todoCategories = todoCategories
 	m.todoEntries = todoEntries
 	m.rings = rings
 	m.ringsRecords = ringsRecords
+	m.notificationDeleteFailures = notificationDeleteFailures
+	m.deletionAudits = deletionAudits
+	m.locks = locks
+	m.migrations = migrations
+	m.notificationTemplates = notificationTemplates
+	m.userPreferences = userPreferences
+	m.ringNudges = ringNudges
+	m.todoTemplates = todoTemplates
+	m.configs = configs
 
 	//asign the db, db client and the collections
 	m.db = db
 	m.dbClient = client
 
+	//the configs and the notification templates are changed by the admins of any instance, all the instances reload them
+	watchContext, stopWatch := context.WithCancel(context.Background())
+	m.stopWatch = stopWatch
+	m.watchDone = make(chan struct{})
+	var watches sync.WaitGroup
+	for _, collection := range []*collectionWrapper{m.configs, m.notificationTemplates} {
+		watches.Add(1)
+		go func(collection *collectionWrapper) {
+			defer watches.Done()
+			collection.Watch(watchContext, nil, m.logger)
+		}(collection)
+	}
+	go func() {
+		watches.Wait()
+		close(m.watchDone)
+	}()
+
 	return nil
 }
 
+// stop stops watching the collections and disconnects from the database
+func (m *database) stop(ctx context.Context) error {
+	if m.stopWatch != nil {
+		m.stopWatch()
+		select {
+		case <-m.watchDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return m.dbClient.Disconnect(ctx)
+}
+
+// addListener registers a listener of the storage changes
+func (m *database) addListener(listener Listener) {
+	m.listenersLock.Lock()
+	defer m.listenersLock.Unlock()
+
+	m.listeners = append(m.listeners, listener)
+}
+
 // Event
 
 func (m *database) onDataChanged(changeDoc map[string]interface{}) {
@@ -116,6 +234,20 @@ func (m *database) onDataChanged(changeDoc map[string]interface{}) {
 
 	if "configs" == coll {
 		log.Println("configs collection changed")
+
+		m.listenersLock.RLock()
+		defer m.listenersLock.RUnlock()
+		for _, listener := range m.listeners {
+			go listener.OnConfigsUpdated()
+		}
+	} else if "notification_templates" == coll {
+		log.Println("notification_templates collection changed")
+
+		m.listenersLock.RLock()
+		defer m.listenersLock.RUnlock()
+		for _, listener := range m.listeners {
+			go listener.OnNotificationTemplatesUpdated()
+		}
 	} else {
 		log.Println("other collection changed")
 	}
@@ -334,3 +466,188 @@ func (m *database) applyRingsRecordsChecks(entries *collectionWrapper) error {
 	log.Println("rings_records passed")
 	return nil
 }
+
+func (m *database) applyNotificationDeleteFailuresChecks(failures *collectionWrapper) error {
+	log.Println("apply notification_delete_failures checks.....")
+
+	//Add org_id + app_id index
+	err := failures.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+			primitive.E{Key: "user_id", Value: 1},
+		},
+		false)
+	if err != nil {
+		return err
+	}
+
+	//Add date_created index
+	err = failures.AddIndex(
+		bson.D{primitive.E{Key: "date_created", Value: -1}},
+		false)
+	if err != nil {
+		return err
+	}
+
+	log.Println("notification_delete_failures passed")
+	return nil
+}
+
+func (m *database) applyDeletionAuditsChecks(audits *collectionWrapper) error {
+	log.Println("apply deletion_audits checks.....")
+
+	//Add date_created index
+	err := audits.AddIndex(
+		bson.D{primitive.E{Key: "date_created", Value: -1}},
+		false)
+	if err != nil {
+		return err
+	}
+
+	//Add org_id + app_id index
+	err = audits.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+			primitive.E{Key: "date_created", Value: -1},
+		},
+		false)
+	if err != nil {
+		return err
+	}
+
+	log.Println("deletion_audits passed")
+	return nil
+}
+
+func (m *database) applyNotificationTemplatesChecks(templates *collectionWrapper) error {
+	log.Println("apply notification templates checks.....")
+
+	//Add org_id + app_id + language + kind index - one template per app/org, language and kind
+	err := templates.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+			primitive.E{Key: "language", Value: 1},
+			primitive.E{Key: "kind", Value: 1},
+		},
+		true)
+	if err != nil {
+		return err
+	}
+
+	log.Println("notification templates passed")
+	return nil
+}
+
+func (m *database) applyTodoTemplatesChecks(templates *collectionWrapper) error {
+	log.Println("apply todo templates checks.....")
+
+	//Add org_id + app_id + user_id index - the templates of a user and of the app/org
+	err := templates.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+			primitive.E{Key: "user_id", Value: 1},
+		},
+		false)
+	if err != nil {
+		return err
+	}
+
+	//Add user_id index
+	err = templates.AddIndex(
+		bson.D{primitive.E{Key: "user_id", Value: 1}},
+		false)
+	if err != nil {
+		return err
+	}
+
+	log.Println("todo templates passed")
+	return nil
+}
+
+func (m *database) applyUserPreferencesChecks(preferences *collectionWrapper) error {
+	log.Println("apply user preferences checks.....")
+
+	//Add org_id + app_id + user_id index - one preferences document per user
+	err := preferences.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+			primitive.E{Key: "user_id", Value: 1},
+		},
+		true)
+	if err != nil {
+		return err
+	}
+
+	log.Println("user preferences passed")
+	return nil
+}
+
+func (m *database) applyRingNudgesChecks(nudges *collectionWrapper) error {
+	log.Println("apply ring nudges checks.....")
+
+	//Add user_id + ring_id + date index - one nudge per ring a day
+	err := nudges.AddIndex(
+		bson.D{
+			primitive.E{Key: "user_id", Value: 1},
+			primitive.E{Key: "ring_id", Value: 1},
+			primitive.E{Key: "date", Value: 1},
+		},
+		true)
+	if err != nil {
+		return err
+	}
+
+	//Add org_id + app_id + user_id + date_created index - the nudges of a user in the last days
+	err = nudges.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+			primitive.E{Key: "user_id", Value: 1},
+			primitive.E{Key: "date_created", Value: 1},
+		},
+		false)
+	if err != nil {
+		return err
+	}
+
+	log.Println("ring nudges passed")
+	return nil
+}
+
+func (m *database) applyConfigsChecks(configs *collectionWrapper) error {
+	log.Println("apply configs checks.....")
+
+	//Add org_id + app_id index - one config per app/org
+	err := configs.AddIndex(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "app_id", Value: 1},
+		},
+		true)
+	if err != nil {
+		return err
+	}
+
+	log.Println("configs passed")
+	return nil
+}
+
+func (m *database) applyLocksChecks(locks *collectionWrapper) error {
+	log.Println("apply locks checks.....")
+
+	//Add expires_at TTL index - the locks of dead instances are removed once they expire
+	err := locks.AddIndexWithOptions(
+		bson.D{primitive.E{Key: "expires_at", Value: 1}},
+		options.Index().SetExpireAfterSeconds(0))
+	if err != nil {
+		return err
+	}
+
+	log.Println("locks passed")
+	return nil
+}
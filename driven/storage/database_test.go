@@ -0,0 +1,64 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+type configsListener struct {
+	updated chan struct{}
+}
+
+func (l *configsListener) OnConfigsUpdated() {
+	l.updated <- struct{}{}
+}
+
+func (l *configsListener) OnNotificationTemplatesUpdated() {}
+
+func TestAdapterWatchesConfigsUntilStopped(t *testing.T) {
+	adapter := newTestAdapter(t)
+	listener := &configsListener{updated: make(chan struct{}, 1)}
+	adapter.RegisterStorageListener(listener)
+
+	//the change stream may start after the insert, so the config is inserted until it is noticed
+	deadline := time.Now().Add(10 * time.Second)
+	noticed := false
+	for !noticed && time.Now().Before(deadline) {
+		err := adapter.InsertAppOrgConfig(model.AppOrgConfig{ID: uuid.NewString(), AppID: uuid.NewString(), OrgID: "org", DateCreated: time.Now().UTC()})
+		if err != nil {
+			t.Fatalf("InsertAppOrgConfig() error = %v", err)
+		}
+		select {
+		case <-listener.updated:
+			noticed = true
+		case <-time.After(time.Second):
+		}
+	}
+	if !noticed {
+		t.Fatal("the listener was not notified of the configs change")
+	}
+
+	adapter.db.stopWatch()
+	select {
+	case <-adapter.db.watchDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the configs are still watched after the watch is stopped")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+// lockStorage is the storage of the distributed locks
+type lockStorage interface {
+	AcquireLock(name string, owner string, expiresAt time.Time) (bool, error)
+	ReleaseLock(name string, owner string) error
+}
+
+// newTestAdapter connects a database adapter to a new database, which is dropped after the test. The test is skipped
+// when WELLNESS_TEST_MONGO_URL is not set.
+func newTestAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	url := os.Getenv("WELLNESS_TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("WELLNESS_TEST_MONGO_URL is not set")
+	}
+
+	name := "wellness_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	adapter := NewStorageAdapter(url, name, "5000", "app", "org", logs.NewLogger("test", &logs.LoggerOpts{}))
+	err := adapter.Start()
+	if err != nil {
+		t.Fatalf("error starting the storage adapter - %s", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := adapter.db.db.Drop(ctx)
+		if err != nil {
+			t.Errorf("error dropping the test database - %s", err)
+		}
+		err = adapter.Stop(ctx)
+		if err != nil {
+			t.Errorf("error stopping the storage adapter - %s", err)
+		}
+	})
+	return adapter
+}
+
+func TestMemoryAdapterLocks(t *testing.T) {
+	testLockStorage(t, NewMemoryAdapter())
+}
+
+func TestAdapterLocks(t *testing.T) {
+	testLockStorage(t, newTestAdapter(t))
+}
+
+func testLockStorage(t *testing.T, store lockStorage) {
+	acquire := func(t *testing.T, name string, owner string, expiresAt time.Time, want bool) {
+		t.Helper()
+		acquired, err := store.AcquireLock(name, owner, expiresAt)
+		if err != nil {
+			t.Fatalf("AcquireLock(%s, %s) error = %v", name, owner, err)
+		}
+		if acquired != want {
+			t.Fatalf("AcquireLock(%s, %s) = %v, want %v", name, owner, acquired, want)
+		}
+	}
+	release := func(t *testing.T, name string, owner string) {
+		t.Helper()
+		err := store.ReleaseLock(name, owner)
+		if err != nil {
+			t.Fatalf("ReleaseLock(%s, %s) error = %v", name, owner, err)
+		}
+	}
+	hour := time.Now().Add(time.Hour)
+
+	t.Run("free lock", func(t *testing.T) {
+		acquire(t, "free", "first", hour, true)
+	})
+
+	t.Run("renewed by its owner", func(t *testing.T) {
+		acquire(t, "renewed", "first", hour, true)
+		acquire(t, "renewed", "first", hour.Add(time.Hour), true)
+	})
+
+	t.Run("held by another owner", func(t *testing.T) {
+		//the database gives a duplicate key error for the existing lock, it is not a failure
+		acquire(t, "held", "first", hour, true)
+		acquire(t, "held", "second", hour, false)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		acquire(t, "expired", "first", time.Now().Add(-time.Second), true)
+		acquire(t, "expired", "second", hour, true)
+		acquire(t, "expired", "first", hour, false)
+	})
+
+	t.Run("released by its owner", func(t *testing.T) {
+		acquire(t, "released", "first", hour, true)
+		release(t, "released", "first")
+		acquire(t, "released", "second", hour, true)
+	})
+
+	t.Run("not released by another owner", func(t *testing.T) {
+		acquire(t, "kept", "first", hour, true)
+		release(t, "kept", "second")
+		acquire(t, "kept", "second", hour, false)
+	})
+
+	t.Run("release of a missing lock", func(t *testing.T) {
+		release(t, "missing", "first")
+	})
+}
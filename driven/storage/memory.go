@@ -0,0 +1,1521 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/errors"
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logutils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MemoryAdapter implements the Storage interface keeping the data in memory.
+//
+// It is meant for local development and tests - the data is lost when the service stops.
+// The transactions do not need a replica set: they are performed one at a time, the first write of a document made with
+// the context of a transaction keeps its original and all the originals are restored if the transaction fails. As in the
+// database, the writes made without the context are not part of the transaction. The collections keep copies of the
+// documents, so the callers never share their slices with the stored ones.
+type MemoryAdapter struct {
+	lock sync.Mutex
+	//held during a whole transaction, the transactions must not be nested
+	transactionLock sync.Mutex
+
+	todoCategories             *memoryCollection[model.TodoCategory]
+	todoEntries                *memoryCollection[model.TodoEntry]
+	rings                      *memoryCollection[model.Ring]
+	ringsRecords               *memoryCollection[model.RingRecord]
+	notificationDeleteFailures *memoryCollection[model.NotificationDeleteFailure]
+	deletionAudits             *memoryCollection[model.DeletionAudit]
+	locks                      *memoryCollection[memoryLock]
+	migrations                 *memoryCollection[model.Migration]
+	notificationTemplates      *memoryCollection[model.NotificationTemplate]
+	userPreferences            *memoryCollection[model.UserPreferences]
+	ringNudges                 *memoryCollection[model.RingNudge]
+	todoTemplates              *memoryCollection[model.TodoTemplate]
+	configs                    *memoryCollection[model.AppOrgConfig]
+
+	//notified on the configs changes as the change stream does for the database
+	listeners []Listener
+}
+
+// memoryCollection keeps the documents of a collection by their ids
+type memoryCollection[T any] struct {
+	name string
+	docs map[string]T
+	//copies the slices, the maps and the pointed structs of a document, nil when it has none
+	clone func(item T) T
+}
+
+func newMemoryCollection[T any](name string, clone func(item T) T) *memoryCollection[T] {
+	return &memoryCollection[T]{name: name, docs: map[string]T{}, clone: clone}
+}
+
+// get gives a copy of the document. The caller must hold the adapter lock.
+func (c *memoryCollection[T]) get(id string) (T, bool) {
+	item, ok := c.docs[id]
+	return c.copy(item), ok
+}
+
+func (c *memoryCollection[T]) copy(item T) T {
+	if c.clone == nil {
+		return item
+	}
+	return c.clone(item)
+}
+
+// memoryTransaction is the context of a transaction given to its callback. It keeps what is needed to restore the
+// documents written with it, the session of the context is not used by the memory adapter.
+type memoryTransaction struct {
+	mongo.SessionContext
+
+	written map[string]bool
+	undo    []func()
+}
+
+type memoryLock struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// Ping checks that the storage is reachable - the memory is always reachable
+func (m *MemoryAdapter) Ping() error {
+	return nil
+}
+
+// Stop stops the storage - there is nothing to release for the memory
+func (m *MemoryAdapter) Stop(ctx context.Context) error {
+	return nil
+}
+
+// PerformTransaction performs a transaction
+func (m *MemoryAdapter) PerformTransaction(transaction func(context TransactionContext) error) error {
+	//a failed transaction must not restore the documents written by another one
+	m.transactionLock.Lock()
+	defer m.transactionLock.Unlock()
+
+	tx := &memoryTransaction{written: map[string]bool{}}
+	err := transaction(tx)
+	if err != nil {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		//restore the originals in the reverse order of the writes
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		if model.AsError(err) != nil {
+			//the typed errors of the transaction are given to the caller as they are
+			return err
+		}
+		return errors.WrapErrorAction("performing", logutils.TypeTransaction, nil, err)
+	}
+	return nil
+}
+
+// GetTodoCategories gets all user defined todo categories
+func (m *MemoryAdapter) GetTodoCategories(context TransactionContext, appID string, orgID string, userID string) ([]model.TodoCategory, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.todoCategories, func(item model.TodoCategory) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Position != result[j].Position {
+			return result[i].Position < result[j].Position
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// GetTodoCategoriesByUserID gets all user defined todo categories
+func (m *MemoryAdapter) GetTodoCategoriesByUserID(userID string) ([]model.TodoCategory, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return findDocuments(m.todoCategories, func(item model.TodoCategory) bool { return item.UserID == userID }), nil
+}
+
+// GetTodoCategory gets a single user defined todo category by id
+func (m *MemoryAdapter) GetTodoCategory(context TransactionContext, appID string, orgID string, userID string, id string) (*model.TodoCategory, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getTodoCategory(appID, orgID, userID, id), nil
+}
+
+func (m *MemoryAdapter) getTodoCategory(appID string, orgID string, userID string, id string) *model.TodoCategory {
+	item, ok := m.todoCategories.get(id)
+	if !ok || item.AppID != appID || item.OrgID != orgID || item.UserID != userID {
+		return nil
+	}
+	return &item
+}
+
+// GetLastTodoCategoryPosition gets the highest position of the user's todo categories, 0 when the user has none
+func (m *MemoryAdapter) GetLastTodoCategoryPosition(context TransactionContext, appID string, orgID string, userID string) (float64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	categories := findDocuments(m.todoCategories, func(item model.TodoCategory) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+	if len(categories) == 0 {
+		return 0, nil
+	}
+	last := categories[0].Position
+	for _, category := range categories[1:] {
+		if category.Position > last {
+			last = category.Position
+		}
+	}
+	return last, nil
+}
+
+// CreateTodoCategory create a new user defined todo category
+func (m *MemoryAdapter) CreateTodoCategory(context TransactionContext, appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	category.ID = uuid.NewString()
+	category.OrgID = orgID
+	category.AppID = appID
+	category.UserID = userID
+	category.DateCreated = time.Now().UTC()
+
+	writeDocument(m, context, m.todoCategories, category.ID, *category)
+	return category, nil
+}
+
+// UpdateTodoCategory updates a user defined todo category
+func (m *MemoryAdapter) UpdateTodoCategory(context TransactionContext, appID string, orgID string, userID string, category *model.TodoCategory) (*model.TodoCategory, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current := m.getTodoCategory(appID, orgID, userID, category.ID)
+	if current == nil {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	current.Name = category.Name
+	current.Color = category.Color
+	current.DateUpdated = &now
+	writeDocument(m, context, m.todoCategories, current.ID, *current)
+
+	//keep the category of the todo entries up to date
+	ref := current.ToCategoryRef()
+	for _, todo := range m.findUserTodoEntries(appID, orgID, userID) {
+		if todo.Category != nil && todo.Category.ID == current.ID {
+			todo.Category = &ref
+			writeDocument(m, context, m.todoEntries, todo.ID, todo)
+		}
+	}
+
+	return current, nil
+}
+
+// DeleteTodoCategory deletes a user defined todo category
+func (m *MemoryAdapter) DeleteTodoCategory(appID string, orgID string, userID string, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.getTodoCategory(appID, orgID, userID, id) != nil {
+		deleteDocument(m, nil, m.todoCategories, id)
+	}
+
+	for _, todo := range m.findUserTodoEntries(appID, orgID, userID) {
+		if todo.Category != nil && todo.Category.ID == id {
+			todo.Category = nil
+			writeDocument(m, nil, m.todoEntries, todo.ID, todo)
+		}
+	}
+	return nil
+}
+
+// DeleteTodoCategoriesForUsers the todo categories for users
+func (m *MemoryAdapter) DeleteTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.todoCategories, func(item model.TodoCategory) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountTodoCategoriesForUsers counts the todo categories for users
+func (m *MemoryAdapter) CountTodoCategoriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.todoCategories, func(item model.TodoCategory) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+// GetTodoEntriesWithCurrentReminderTime Gets all todo entries that are applied for the specified reminder datetime
+func (m *MemoryAdapter) GetTodoEntriesWithCurrentReminderTime(context TransactionContext, reminderTime time.Time) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	startDate := reminderTime.Truncate(time.Minute)
+	endDate := startDate.Add(time.Minute)
+	return m.findTodoEntries(func(item model.TodoEntry) bool {
+		return !item.Completed && item.ReminderDateTime != nil &&
+			!item.ReminderDateTime.Before(startDate) && item.ReminderDateTime.Before(endDate) &&
+			(item.TaskTime == nil || item.TaskTime.Before(startDate))
+	}), nil
+}
+
+// GetTodoEntriesWithCurrentDueTime Gets all todo entries that are applied for the specified due datetime
+func (m *MemoryAdapter) GetTodoEntriesWithCurrentDueTime(context TransactionContext, dueTime time.Time) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	startDate := dueTime.Truncate(time.Minute)
+	endDate := startDate.Add(time.Minute)
+	return m.findTodoEntries(func(item model.TodoEntry) bool {
+		return !item.Completed && item.HasDueTime && item.DueDateTime != nil &&
+			!item.DueDateTime.Before(startDate) && item.DueDateTime.Before(endDate) &&
+			(item.TaskTime == nil || item.TaskTime.Before(startDate))
+	}), nil
+}
+
+// GetTodoEntriesDueBefore Gets the user's incomplete todo entries due before the specified datetime
+func (m *MemoryAdapter) GetTodoEntriesDueBefore(appID string, orgID string, userID string, dueTime time.Time) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := m.findTodoEntries(func(item model.TodoEntry) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID &&
+			!item.Completed && item.DueDateTime != nil && item.DueDateTime.Before(dueTime)
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DueDateTime.Before(*result[j].DueDateTime) })
+	return result, nil
+}
+
+// GetTodoEntries gets user's todo entries
+func (m *MemoryAdapter) GetTodoEntries(context TransactionContext, appID string, orgID string, userID string) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := m.findUserTodoEntries(appID, orgID, userID)
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Position < result[j].Position })
+	return result, nil
+}
+
+// GetTodoEntriesByUserID gets user's todo entries
+func (m *MemoryAdapter) GetTodoEntriesByUserID(userID string) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.findTodoEntries(func(item model.TodoEntry) bool { return item.UserID == userID }), nil
+}
+
+// GetTodoEntriesForMigration gets all todo entries
+func (m *MemoryAdapter) GetTodoEntriesForMigration() ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	return m.findTodoEntries(func(item model.TodoEntry) bool {
+		return (item.MessageIDs.DueDateMessageID == nil && item.DueDateTime != nil && item.DueDateTime.After(now)) ||
+			(item.MessageIDs.ReminderDateMessageID == nil && item.ReminderDateTime != nil && item.ReminderDateTime.After(now))
+	}), nil
+}
+
+// GetTodoEntry get a single todo entry
+func (m *MemoryAdapter) GetTodoEntry(context TransactionContext, appID string, orgID string, userID string, id string) (*model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getTodoEntry(appID, orgID, userID, id), nil
+}
+
+// GetLastTodoEntryPosition gets the highest position of the user's todo entries, 0 when the user has none
+func (m *MemoryAdapter) GetLastTodoEntryPosition(context TransactionContext, appID string, orgID string, userID string) (float64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	entries := m.findUserTodoEntries(appID, orgID, userID)
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	last := entries[0].Position
+	for _, entry := range entries[1:] {
+		if entry.Position > last {
+			last = entry.Position
+		}
+	}
+	return last, nil
+}
+
+func (m *MemoryAdapter) getTodoEntry(appID string, orgID string, userID string, id string) *model.TodoEntry {
+	item, ok := m.todoEntries.get(id)
+	if !ok || item.AppID != appID || item.OrgID != orgID || item.UserID != userID {
+		return nil
+	}
+	return &item
+}
+
+// CreateTodoEntry create a todo entry
+func (m *MemoryAdapter) CreateTodoEntry(context TransactionContext, appID string, orgID string, userID string, todo *model.TodoEntry, messageIDs model.MessageIDs, entityID string) (*model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, exists := m.todoEntries.docs[entityID]; exists {
+		return nil, errors.ErrorData(logutils.StatusFound, "todo entry", &logutils.FieldArgs{"id": entityID})
+	}
+
+	todo.ID = entityID
+	todo.OrgID = orgID
+	todo.AppID = appID
+	todo.UserID = userID
+	todo.DateCreated = time.Now().UTC()
+	todo.MessageIDs = messageIDs
+
+	writeDocument(m, context, m.todoEntries, todo.ID, *todo)
+	return todo, nil
+}
+
+// UpdateTodoEntry updates a todo entry
+func (m *MemoryAdapter) UpdateTodoEntry(context TransactionContext, appID string, orgID string, userID string, todo *model.TodoEntry, id string) (*model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current := m.getTodoEntry(appID, orgID, userID, id)
+	if current == nil {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	current.Title = todo.Title
+	current.Description = todo.Description
+	current.Category = todo.Category
+	current.Completed = todo.Completed
+	current.HasDueTime = todo.HasDueTime
+	current.DueDateTime = todo.DueDateTime
+	current.ReminderType = todo.ReminderType
+	current.ReminderDateTime = todo.ReminderDateTime
+	current.Recurrence = todo.Recurrence
+	current.Overdue = todo.Overdue
+	current.SnoozeCount = todo.SnoozeCount
+	current.WorkDays = todo.WorkDays
+	current.Location = todo.Location
+	current.TaskTime = todo.TaskTime
+	current.MessageIDs = todo.MessageIDs
+	current.ReminderAdjustments = todo.ReminderAdjustments
+	current.DateUpdated = &now
+
+	writeDocument(m, context, m.todoEntries, id, *current)
+	return current, nil
+}
+
+// UpdateTodoEntriesTaskTime Updates task time field for the desired todo ids
+func (m *MemoryAdapter) UpdateTodoEntriesTaskTime(context TransactionContext, ids []string, taskTime time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, id := range ids {
+		item, ok := m.todoEntries.get(id)
+		if !ok {
+			continue
+		}
+		item.TaskTime = &taskTime
+		writeDocument(m, context, m.todoEntries, id, item)
+	}
+	return nil
+}
+
+// UpdateTodoEntryPositions Updates the positions of the user todo entries by their ids
+func (m *MemoryAdapter) UpdateTodoEntryPositions(context TransactionContext, appID string, orgID string, userID string, positions map[string]float64) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for id, position := range positions {
+		item := m.getTodoEntry(appID, orgID, userID, id)
+		if item == nil {
+			continue
+		}
+		item.Position = position
+		writeDocument(m, context, m.todoEntries, id, *item)
+	}
+	return nil
+}
+
+// UpdateTodoCategoryPositions Updates the positions of the user todo categories by their ids
+func (m *MemoryAdapter) UpdateTodoCategoryPositions(context TransactionContext, appID string, orgID string, userID string, positions map[string]float64) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for id, position := range positions {
+		item := m.getTodoCategory(appID, orgID, userID, id)
+		if item == nil {
+			continue
+		}
+		item.Position = position
+		writeDocument(m, context, m.todoCategories, id, *item)
+	}
+	return nil
+}
+
+// GetTodoEntriesToMarkOverdue Gets at most limit todo entries after the id, ordered by id, which could be overdue at the
+// specified datetime but are not marked yet, see Adapter.GetTodoEntriesToMarkOverdue
+func (m *MemoryAdapter) GetTodoEntriesToMarkOverdue(context TransactionContext, at time.Time, afterID string, limit int64) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	//the day of an entry without a due time is over first in the time zone with the latest offset
+	earliest := time.FixedZone("earliest", int(model.EarliestDayEndOffset.Seconds()))
+	result := m.findTodoEntries(func(item model.TodoEntry) bool {
+		return item.ID > afterID && !item.Overdue && item.IsOverdue(at, earliest)
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	if int64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// MarkTodoEntriesOverdue Marks the desired todo ids as overdue
+func (m *MemoryAdapter) MarkTodoEntriesOverdue(context TransactionContext, ids []string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, id := range ids {
+		item, ok := m.todoEntries.get(id)
+		if !ok {
+			continue
+		}
+		item.Overdue = true
+		writeDocument(m, context, m.todoEntries, id, item)
+	}
+	return nil
+}
+
+// DeleteTodoEntry deletes a todo entry
+func (m *MemoryAdapter) DeleteTodoEntry(context TransactionContext, appID string, orgID string, userID string, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.getTodoEntry(appID, orgID, userID, id) != nil {
+		deleteDocument(m, context, m.todoEntries, id)
+	}
+	return nil
+}
+
+// DeleteCompletedTodoEntries deletes a completed todo entries
+func (m *MemoryAdapter) DeleteCompletedTodoEntries(appID string, orgID string, userID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleteDocuments(m, nil, m.todoEntries, func(item model.TodoEntry) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID && item.Completed
+	})
+	return nil
+}
+
+// DeleteTodoEntriesForUsers deletes todo entries for users
+func (m *MemoryAdapter) DeleteTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.todoEntries, func(item model.TodoEntry) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountTodoEntriesForUsers counts the todo entries for users
+func (m *MemoryAdapter) CountTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(m.findTodoEntries(func(item model.TodoEntry) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+// GetTodoEntriesForUsers gets the todo entries for users
+func (m *MemoryAdapter) GetTodoEntriesForUsers(appID string, orgID string, accountsIDs []string) ([]model.TodoEntry, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.findTodoEntries(func(item model.TodoEntry) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+func (m *MemoryAdapter) findUserTodoEntries(appID string, orgID string, userID string) []model.TodoEntry {
+	return m.findTodoEntries(func(item model.TodoEntry) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+}
+
+func (m *MemoryAdapter) findTodoEntries(match func(item model.TodoEntry) bool) []model.TodoEntry {
+	result := findDocuments(m.todoEntries, match)
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DateCreated.Before(result[j].DateCreated) })
+	return result
+}
+
+// Wellness Rings
+
+// GetRings gets user's wellness rings
+func (m *MemoryAdapter) GetRings(appID string, orgID string, userID string) ([]model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.findRings(func(item model.Ring) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	}), nil
+}
+
+// GetRingsByUserID gets user's wellness rings
+func (m *MemoryAdapter) GetRingsByUserID(userID string) ([]model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.findRings(func(item model.Ring) bool { return item.UserID == userID }), nil
+}
+
+// GetRing get a single user wellness ring
+func (m *MemoryAdapter) GetRing(appID string, orgID string, userID string, id string) (*model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getRing(appID, orgID, userID, id), nil
+}
+
+func (m *MemoryAdapter) getRing(appID string, orgID string, userID string, id string) *model.Ring {
+	item, ok := m.rings.get(id)
+	if !ok || item.AppID != appID || item.OrgID != orgID || item.UserID != userID {
+		return nil
+	}
+	return &item
+}
+
+// CreateRing create a user wellness ring
+func (m *MemoryAdapter) CreateRing(appID string, orgID string, userID string, ring *model.Ring) (*model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ring.ID = uuid.NewString()
+	ring.OrgID = orgID
+	ring.AppID = appID
+	ring.UserID = userID
+	ring.DateCreated = time.Now().UTC()
+
+	for index := range ring.History {
+		ring.History[index].RingID = ring.ID
+	}
+
+	writeDocument(m, nil, m.rings, ring.ID, *ring)
+	return ring, nil
+}
+
+// DeleteRing deletes a user wellness ring
+func (m *MemoryAdapter) DeleteRing(appID string, orgID string, userID string, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.getRing(appID, orgID, userID, id) != nil {
+		deleteDocument(m, nil, m.rings, id)
+	}
+
+	deleteDocuments(m, nil, m.ringsRecords, func(item model.RingRecord) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID && item.RingID == id
+	})
+	return nil
+}
+
+// CreateRingHistory create a new ring history item
+func (m *MemoryAdapter) CreateRingHistory(appID string, orgID string, userID string, ringID string, ringHistory *model.RingHistoryEntry) (*model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ringHistory.ID = uuid.NewString()
+	ringHistory.RingID = ringID
+	ringHistory.DateCreated = time.Now().UTC()
+
+	ring := m.getRing(appID, orgID, userID, ringID)
+	if ring == nil {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	ring.History = append(ring.History, *ringHistory)
+	ring.DateUpdated = &now
+
+	writeDocument(m, nil, m.rings, ring.ID, *ring)
+	return ring, nil
+}
+
+// DeleteRingHistory deletes a ring history item
+func (m *MemoryAdapter) DeleteRingHistory(appID string, orgID string, userID string, ringID string, ringHistoryID string) (*model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ring := m.getRing(appID, orgID, userID, ringID)
+	if ring == nil {
+		return nil, model.NewNotFoundError("ring", ringID)
+	}
+	if len(ring.History) < 2 {
+		return nil, model.NewConflictError("the last history item of a ring cannot be deleted")
+	}
+
+	now := time.Now().UTC()
+	ring.History = slices.DeleteFunc(ring.History, func(item model.RingHistoryEntry) bool { return item.ID == ringHistoryID })
+	ring.DateUpdated = &now
+
+	writeDocument(m, nil, m.rings, ring.ID, *ring)
+	return ring, nil
+}
+
+// DeleteRingsForUsers deletes rings for users
+func (m *MemoryAdapter) DeleteRingsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.rings, func(item model.Ring) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountRingsForUsers counts the rings for users
+func (m *MemoryAdapter) CountRingsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.rings, func(item model.Ring) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+// GetRingsWithNudges gets a batch of the rings with nudges - the ones after the id, sorted by id. The rings of the users who
+// got the most nudges since the time - the cap of their app/org, the default cap when the app/org has none - are skipped.
+func (m *MemoryAdapter) GetRingsWithNudges(afterID string, limit int64, since time.Time, caps []model.RingNudgesCap, defaultMax int) ([]model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := m.findRings(func(item model.Ring) bool {
+		if !item.NudgesEnabled || item.ID <= afterID {
+			return false
+		}
+		maxNudges := defaultMax
+		for _, nudgesCap := range caps {
+			if nudgesCap.AppID == item.AppID && nudgesCap.OrgID == item.OrgID {
+				maxNudges = nudgesCap.Max
+			}
+		}
+		nudges := findDocuments(m.ringNudges, func(nudge model.RingNudge) bool {
+			return nudge.AppID == item.AppID && nudge.OrgID == item.OrgID && nudge.UserID == item.UserID && !nudge.DateCreated.Before(since)
+		})
+		return len(nudges) < maxNudges
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	if int64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// UpdateRingNudges turns the nudges of a user wellness ring on or off. It gives nil if there is no such ring.
+func (m *MemoryAdapter) UpdateRingNudges(appID string, orgID string, userID string, id string, enabled bool) (*model.Ring, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ring := m.getRing(appID, orgID, userID, id)
+	if ring == nil {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	ring.NudgesEnabled = enabled
+	ring.DateUpdated = &now
+
+	writeDocument(m, nil, m.rings, ring.ID, *ring)
+	return ring, nil
+}
+
+// InsertRingNudge records a ring nudge. It gives false if the ring has already been nudged on the date.
+func (m *MemoryAdapter) InsertRingNudge(nudge model.RingNudge) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing := findDocuments(m.ringNudges, func(item model.RingNudge) bool {
+		return item.UserID == nudge.UserID && item.RingID == nudge.RingID && item.Date == nudge.Date
+	})
+	if len(existing) > 0 {
+		return false, nil
+	}
+	writeDocument(m, nil, m.ringNudges, nudge.ID, nudge)
+	return true, nil
+}
+
+// CountRingNudges counts the ring nudges sent to a user since a time
+func (m *MemoryAdapter) CountRingNudges(appID string, orgID string, userID string, since time.Time) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.ringNudges, func(item model.RingNudge) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID && !item.DateCreated.Before(since)
+	}))), nil
+}
+
+// DeleteRingNudgesForUsers deletes the ring nudges of the users
+func (m *MemoryAdapter) DeleteRingNudgesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.ringNudges, func(item model.RingNudge) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountRingNudgesForUsers counts the ring nudges of the users
+func (m *MemoryAdapter) CountRingNudgesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.ringNudges, func(item model.RingNudge) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+func (m *MemoryAdapter) findRings(match func(item model.Ring) bool) []model.Ring {
+	result := findDocuments(m.rings, match)
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DateCreated.Before(result[j].DateCreated) })
+	return result
+}
+
+// GetRingsRecords Get all ring records for the corresponding ring id
+func (m *MemoryAdapter) GetRingsRecords(appID string, orgID string, userID string, ringID *string, startDateEpoch *int64, endDateEpoch *int64, offset *int64, limit *int64, order *string) ([]model.RingRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.ringsRecords, func(item model.RingRecord) bool {
+		if item.AppID != appID || item.OrgID != orgID || item.UserID != userID {
+			return false
+		}
+		if ringID != nil && item.RingID != *ringID {
+			return false
+		}
+		if startDateEpoch != nil && item.DateCreated.Before(time.Unix(*startDateEpoch/1000, 0)) {
+			return false
+		}
+		if endDateEpoch != nil && item.DateCreated.After(time.Unix(*endDateEpoch/1000, 0)) {
+			return false
+		}
+		return true
+	})
+
+	if order != nil && *order == "asc" {
+		sort.SliceStable(result, func(i, j int) bool { return result[i].DateCreated.Before(result[j].DateCreated) })
+	} else {
+		sort.SliceStable(result, func(i, j int) bool { return result[i].DateCreated.After(result[j].DateCreated) })
+	}
+	return pageDocuments(result, offset, limit), nil
+}
+
+// GetRingsRecordsByUserID Get all ring records for the corresponding ring id
+func (m *MemoryAdapter) GetRingsRecordsByUserID(userID string) ([]model.RingRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return findDocuments(m.ringsRecords, func(item model.RingRecord) bool { return item.UserID == userID }), nil
+}
+
+// GetRingsRecord gets a single ring record by id
+func (m *MemoryAdapter) GetRingsRecord(appID string, orgID string, userID string, id string) (*model.RingRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getRingsRecord(appID, orgID, userID, id), nil
+}
+
+func (m *MemoryAdapter) getRingsRecord(appID string, orgID string, userID string, id string) *model.RingRecord {
+	item, ok := m.ringsRecords.get(id)
+	if !ok || item.AppID != appID || item.OrgID != orgID || item.UserID != userID {
+		return nil
+	}
+	return &item
+}
+
+// CreateRingsRecord creates a new ring record
+func (m *MemoryAdapter) CreateRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	record.ID = uuid.NewString()
+	record.OrgID = orgID
+	record.AppID = appID
+	record.UserID = userID
+	record.DateCreated = time.Now().UTC()
+
+	writeDocument(m, nil, m.ringsRecords, record.ID, *record)
+	return m.getRingsRecord(appID, orgID, userID, record.ID), nil
+}
+
+// UpdateRingsRecord updates a ring record
+func (m *MemoryAdapter) UpdateRingsRecord(appID string, orgID string, userID string, record *model.RingRecord) (*model.RingRecord, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now().UTC()
+	record.DateUpdated = &now
+
+	current := m.getRingsRecord(appID, orgID, userID, record.ID)
+	if current == nil {
+		return nil, nil
+	}
+	current.Value = record.Value
+	current.DateUpdated = &now
+
+	writeDocument(m, nil, m.ringsRecords, current.ID, *current)
+	return current, nil
+}
+
+// DeleteRingsRecords deletes a ring record
+func (m *MemoryAdapter) DeleteRingsRecords(appID string, orgID string, userID string, ringID *string, recordID *string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleteDocuments(m, nil, m.ringsRecords, func(item model.RingRecord) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID &&
+			(ringID == nil || item.RingID == *ringID) && (recordID == nil || item.ID == *recordID)
+	})
+	return nil
+}
+
+// DeleteRingsRecordsForUsers deletes a rings records for users
+func (m *MemoryAdapter) DeleteRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.ringsRecords, func(item model.RingRecord) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountRingsRecordsForUsers counts the rings records for users
+func (m *MemoryAdapter) CountRingsRecordsForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.ringsRecords, func(item model.RingRecord) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+// CreateNotificationDeleteFailures stores the notifications which could not be deleted
+func (m *MemoryAdapter) CreateNotificationDeleteFailures(failures []model.NotificationDeleteFailure) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, failure := range failures {
+		writeDocument(m, nil, m.notificationDeleteFailures, failure.ID, failure)
+	}
+	return nil
+}
+
+// CreateDeletionAudit stores the audit of a deleted users data processing
+func (m *MemoryAdapter) CreateDeletionAudit(audit model.DeletionAudit) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	writeDocument(m, nil, m.deletionAudits, audit.ID, audit)
+	return nil
+}
+
+// GetDeletionAudits gets the deletion audits, the newest first
+func (m *MemoryAdapter) GetDeletionAudits(appID *string, orgID *string, offset *int64, limit *int64) ([]model.DeletionAudit, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.deletionAudits, func(item model.DeletionAudit) bool {
+		return (appID == nil || item.AppID == *appID) && (orgID == nil || item.OrgID == *orgID)
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DateCreated.After(result[j].DateCreated) })
+	return pageDocuments(result, offset, limit), nil
+}
+
+// GetUserPreferences gets the preferences of a user. It gives nil if the user has no preferences.
+func (m *MemoryAdapter) GetUserPreferences(appID string, orgID string, userID string) (*model.UserPreferences, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.userPreferences, func(item model.UserPreferences) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// GetUserPreferencesByUserID gets the preferences of a user in all the apps/orgs
+func (m *MemoryAdapter) GetUserPreferencesByUserID(userID string) ([]model.UserPreferences, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return findDocuments(m.userPreferences, func(item model.UserPreferences) bool { return item.UserID == userID }), nil
+}
+
+// SaveUserPreferences inserts the preferences of a user or updates the fields the user edits
+func (m *MemoryAdapter) SaveUserPreferences(appID string, orgID string, userID string, preferences model.UserPreferences) (*model.UserPreferences, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now().UTC()
+	preferences.AppID = appID
+	preferences.OrgID = orgID
+	preferences.UserID = userID
+	preferences.DateUpdated = &now
+	current := findDocuments(m.userPreferences, func(item model.UserPreferences) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+	if len(current) == 0 {
+		preferences.ID = uuid.NewString()
+		preferences.LastDigestDate = ""
+		preferences.DateCreated = now
+	} else {
+		preferences.ID = current[0].ID
+		preferences.LastDigestDate = current[0].LastDigestDate
+		preferences.DateCreated = current[0].DateCreated
+	}
+
+	writeDocument(m, nil, m.userPreferences, preferences.ID, preferences)
+	result := m.userPreferences.copy(preferences)
+	return &result, nil
+}
+
+// DeleteUserPreferences deletes the preferences of a user
+func (m *MemoryAdapter) DeleteUserPreferences(appID string, orgID string, userID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleteDocuments(m, nil, m.userPreferences, func(item model.UserPreferences) bool {
+		return item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+	return nil
+}
+
+// DeleteUserPreferencesForUsers deletes the preferences of the users
+func (m *MemoryAdapter) DeleteUserPreferencesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.userPreferences, func(item model.UserPreferences) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountUserPreferencesForUsers counts the preferences of the users
+func (m *MemoryAdapter) CountUserPreferencesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.userPreferences, func(item model.UserPreferences) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+// GetUserPreferencesWithDigest gets a batch of the preferences of the users who want the daily digest - the ones after the
+// id, sorted by id
+func (m *MemoryAdapter) GetUserPreferencesWithDigest(afterID string, limit int64) ([]model.UserPreferences, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.userPreferences, func(item model.UserPreferences) bool { return item.ID > afterID && item.DigestTime != "" })
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	if int64(len(result)) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// MarkDigestSent sets the date of the last daily digest of the user. It gives false if the digest of the date has
+// already been marked.
+func (m *MemoryAdapter) MarkDigestSent(preferencesID string, date string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	preferences, ok := m.userPreferences.get(preferencesID)
+	if !ok || preferences.LastDigestDate == date {
+		return false, nil
+	}
+	preferences.LastDigestDate = date
+	writeDocument(m, nil, m.userPreferences, preferencesID, preferences)
+	return true, nil
+}
+
+// GetNotificationTemplates gets the notification templates of an app/org
+func (m *MemoryAdapter) GetNotificationTemplates(appID string, orgID string) ([]model.NotificationTemplate, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.notificationTemplates, func(item model.NotificationTemplate) bool {
+		return item.AppID == appID && item.OrgID == orgID
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Language < result[j].Language })
+	return result, nil
+}
+
+// GetNotificationTemplate gets a notification template of an app/org. It gives nil if there is no such template.
+func (m *MemoryAdapter) GetNotificationTemplate(appID string, orgID string, id string) (*model.NotificationTemplate, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	template, ok := m.notificationTemplates.get(id)
+	if !ok || template.AppID != appID || template.OrgID != orgID {
+		return nil, nil
+	}
+	return &template, nil
+}
+
+// InsertNotificationTemplate inserts a notification template
+func (m *MemoryAdapter) InsertNotificationTemplate(template model.NotificationTemplate) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.hasNotificationTemplate(template) {
+		return model.NewConflictError("the app/org already has a " + template.Kind + " template in " + template.Language)
+	}
+	writeDocument(m, nil, m.notificationTemplates, template.ID, template)
+	m.notifyNotificationTemplatesUpdated()
+	return nil
+}
+
+// UpdateNotificationTemplate replaces a notification template
+func (m *MemoryAdapter) UpdateNotificationTemplate(template model.NotificationTemplate) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.notificationTemplates.docs[template.ID]; !ok {
+		return fmt.Errorf("no notification template %s", template.ID)
+	}
+	if m.hasNotificationTemplate(template) {
+		return model.NewConflictError("the app/org already has a " + template.Kind + " template in " + template.Language)
+	}
+	writeDocument(m, nil, m.notificationTemplates, template.ID, template)
+	m.notifyNotificationTemplatesUpdated()
+	return nil
+}
+
+// DeleteNotificationTemplate deletes a notification template of an app/org
+func (m *MemoryAdapter) DeleteNotificationTemplate(appID string, orgID string, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleteDocuments(m, nil, m.notificationTemplates, func(item model.NotificationTemplate) bool {
+		return item.ID == id && item.AppID == appID && item.OrgID == orgID
+	})
+	m.notifyNotificationTemplatesUpdated()
+	return nil
+}
+
+// hasNotificationTemplate checks if another template of the app/org has the same kind and language
+func (m *MemoryAdapter) hasNotificationTemplate(template model.NotificationTemplate) bool {
+	return len(findDocuments(m.notificationTemplates, func(item model.NotificationTemplate) bool {
+		return item.ID != template.ID && item.AppID == template.AppID && item.OrgID == template.OrgID && item.Kind == template.Kind && item.Language == template.Language
+	})) > 0
+}
+
+// GetTodoTemplates gets the todo templates of the users of an app/org - the empty user id is for the templates of the app/org
+func (m *MemoryAdapter) GetTodoTemplates(appID string, orgID string, userIDs []string) ([]model.TodoTemplate, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := m.findTodoTemplates(func(item model.TodoTemplate) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(userIDs, item.UserID)
+	})
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// GetTodoTemplatesByUserID gets all the todo templates of a user
+func (m *MemoryAdapter) GetTodoTemplatesByUserID(userID string) ([]model.TodoTemplate, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.findTodoTemplates(func(item model.TodoTemplate) bool { return item.UserID == userID }), nil
+}
+
+// GetTodoTemplate gets a todo template of an app/org. It gives nil if there is no such template.
+func (m *MemoryAdapter) GetTodoTemplate(appID string, orgID string, id string) (*model.TodoTemplate, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	template, ok := m.todoTemplates.get(id)
+	if !ok || template.AppID != appID || template.OrgID != orgID {
+		return nil, nil
+	}
+	return &template, nil
+}
+
+// InsertTodoTemplate inserts a todo template
+func (m *MemoryAdapter) InsertTodoTemplate(template model.TodoTemplate) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.todoTemplates.docs[template.ID]; ok {
+		return fmt.Errorf("duplicate todo template %s", template.ID)
+	}
+	writeDocument(m, nil, m.todoTemplates, template.ID, template)
+	return nil
+}
+
+// UpdateTodoTemplate replaces a todo template
+func (m *MemoryAdapter) UpdateTodoTemplate(template model.TodoTemplate) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.todoTemplates.docs[template.ID]; !ok {
+		return fmt.Errorf("no todo template %s", template.ID)
+	}
+	writeDocument(m, nil, m.todoTemplates, template.ID, template)
+	return nil
+}
+
+// DeleteTodoTemplate deletes a todo template of a user - of the app/org when the user id is empty
+func (m *MemoryAdapter) DeleteTodoTemplate(appID string, orgID string, userID string, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleteDocuments(m, nil, m.todoTemplates, func(item model.TodoTemplate) bool {
+		return item.ID == id && item.AppID == appID && item.OrgID == orgID && item.UserID == userID
+	})
+	return nil
+}
+
+// DeleteTodoTemplatesForUsers deletes the todo templates of the users
+func (m *MemoryAdapter) DeleteTodoTemplatesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return deleteDocuments(m, nil, m.todoTemplates, func(item model.TodoTemplate) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}), nil
+}
+
+// CountTodoTemplatesForUsers counts the todo templates of the users
+func (m *MemoryAdapter) CountTodoTemplatesForUsers(appID string, orgID string, accountsIDs []string) (int64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return int64(len(findDocuments(m.todoTemplates, func(item model.TodoTemplate) bool {
+		return item.AppID == appID && item.OrgID == orgID && slices.Contains(accountsIDs, item.UserID)
+	}))), nil
+}
+
+func (m *MemoryAdapter) findTodoTemplates(match func(item model.TodoTemplate) bool) []model.TodoTemplate {
+	result := findDocuments(m.todoTemplates, match)
+	return result
+}
+
+// RegisterStorageListener registers a listener of the storage changes
+func (m *MemoryAdapter) RegisterStorageListener(listener Listener) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.listeners = append(m.listeners, listener)
+}
+
+// GetAppOrgConfigs gets the configs of all the apps/orgs
+func (m *MemoryAdapter) GetAppOrgConfigs() ([]model.AppOrgConfig, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := findDocuments(m.configs, func(item model.AppOrgConfig) bool { return true })
+	return result, nil
+}
+
+// GetAppOrgConfig gets the config of an app/org. It gives nil if the app/org has no config.
+func (m *MemoryAdapter) GetAppOrgConfig(appID string, orgID string) (*model.AppOrgConfig, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getAppOrgConfig(appID, orgID), nil
+}
+
+// InsertAppOrgConfig inserts the config of an app/org
+func (m *MemoryAdapter) InsertAppOrgConfig(config model.AppOrgConfig) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.getAppOrgConfig(config.AppID, config.OrgID) != nil {
+		return model.NewConflictError("the app/org already has a config")
+	}
+	writeDocument(m, nil, m.configs, config.ID, config)
+	m.notifyConfigsUpdated()
+	return nil
+}
+
+// UpdateAppOrgConfig replaces the config of an app/org
+func (m *MemoryAdapter) UpdateAppOrgConfig(config model.AppOrgConfig) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.configs.docs[config.ID]; !ok {
+		return fmt.Errorf("no config %s", config.ID)
+	}
+	writeDocument(m, nil, m.configs, config.ID, config)
+	m.notifyConfigsUpdated()
+	return nil
+}
+
+// DeleteAppOrgConfig deletes the config of an app/org
+func (m *MemoryAdapter) DeleteAppOrgConfig(appID string, orgID string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	deleteDocuments(m, nil, m.configs, func(item model.AppOrgConfig) bool { return item.AppID == appID && item.OrgID == orgID })
+	m.notifyConfigsUpdated()
+	return nil
+}
+
+func (m *MemoryAdapter) getAppOrgConfig(appID string, orgID string) *model.AppOrgConfig {
+	result := findDocuments(m.configs, func(item model.AppOrgConfig) bool { return item.AppID == appID && item.OrgID == orgID })
+	if len(result) == 0 {
+		return nil
+	}
+	return &result[0]
+}
+
+// notifyConfigsUpdated notifies the listeners without waiting for them as they read the configs again
+func (m *MemoryAdapter) notifyConfigsUpdated() {
+	for _, listener := range m.listeners {
+		go listener.OnConfigsUpdated()
+	}
+}
+
+// notifyNotificationTemplatesUpdated notifies the listeners without waiting for them
+func (m *MemoryAdapter) notifyNotificationTemplatesUpdated() {
+	for _, listener := range m.listeners {
+		go listener.OnNotificationTemplatesUpdated()
+	}
+}
+
+// AcquireLock acquires the named lock for the owner until the expiration time.
+// The owner can acquire it again to change the expiration time. It gives false if the lock is held by another owner.
+func (m *MemoryAdapter) AcquireLock(name string, owner string, expiresAt time.Time) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current, ok := m.locks.get(name)
+	if ok && current.owner != owner && current.expiresAt.After(time.Now()) {
+		return false, nil
+	}
+	writeDocument(m, nil, m.locks, name, memoryLock{owner: owner, expiresAt: expiresAt.UTC()})
+	return true, nil
+}
+
+// ReleaseLock releases the named lock if it is held by the owner
+func (m *MemoryAdapter) ReleaseLock(name string, owner string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current, ok := m.locks.get(name)
+	if ok && current.owner == owner {
+		deleteDocument(m, nil, m.locks, name)
+	}
+	return nil
+}
+
+// ApplyMigrations marks the pending migrations as applied. There is no data stored by older versions of the service
+// in memory, so there is nothing to migrate and the tasks are not run.
+func (m *MemoryAdapter) ApplyMigrations(dryRun bool, tasks map[string]model.MigrationTask) ([]model.Migration, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var result []model.Migration
+	for _, item := range sortedMigrations() {
+		id := fmt.Sprint(item.version)
+		if _, applied := m.migrations.docs[id]; applied {
+			continue
+		}
+
+		record := model.Migration{Version: item.version, Name: item.name, Summary: "in-memory storage - nothing to migrate",
+			DryRun: dryRun, DateApplied: time.Now().UTC()}
+		if !dryRun {
+			writeDocument(m, nil, m.migrations, id, record)
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// findDocuments gives copies of the matching documents
+func findDocuments[T any](collection *memoryCollection[T], match func(item T) bool) []T {
+	result := []T{}
+	for _, item := range collection.docs {
+		if match(item) {
+			result = append(result, collection.copy(item))
+		}
+	}
+	return result
+}
+
+// writeDocument inserts or replaces a document with a copy of the item. The context is nil outside of a transaction.
+// The caller must hold the adapter lock.
+func writeDocument[T any](m *MemoryAdapter, context TransactionContext, collection *memoryCollection[T], id string, item T) {
+	keepOriginal(context, collection, id)
+	collection.docs[id] = collection.copy(item)
+}
+
+// deleteDocument deletes a document. The context is nil outside of a transaction. The caller must hold the adapter lock.
+func deleteDocument[T any](m *MemoryAdapter, context TransactionContext, collection *memoryCollection[T], id string) {
+	keepOriginal(context, collection, id)
+	delete(collection.docs, id)
+}
+
+// deleteDocuments deletes the matching documents and gives their count. The context is nil outside of a transaction.
+// The caller must hold the adapter lock.
+func deleteDocuments[T any](m *MemoryAdapter, context TransactionContext, collection *memoryCollection[T], match func(item T) bool) int64 {
+	var count int64
+	for id, item := range collection.docs {
+		if match(item) {
+			deleteDocument(m, context, collection, id)
+			count++
+		}
+	}
+	return count
+}
+
+// keepOriginal keeps the document as it was before its first write in the transaction of the context
+func keepOriginal(context TransactionContext, collection memoryDocuments, id string) {
+	tx, ok := context.(*memoryTransaction)
+	if !ok {
+		return
+	}
+	key := collection.collectionName() + "/" + id
+	if tx.written[key] {
+		return
+	}
+	tx.written[key] = true
+	tx.undo = append(tx.undo, collection.restorer(id))
+}
+
+// memoryDocuments allows keeping the originals of the documents of any collection
+type memoryDocuments interface {
+	collectionName() string
+	restorer(id string) func()
+}
+
+func (c *memoryCollection[T]) collectionName() string {
+	return c.name
+}
+
+// restorer gives a function which restores the current state of the document
+func (c *memoryCollection[T]) restorer(id string) func() {
+	original, existed := c.docs[id]
+	return func() {
+		if existed {
+			c.docs[id] = original
+		} else {
+			delete(c.docs, id)
+		}
+	}
+}
+
+func pageDocuments[T any](items []T, offset *int64, limit *int64) []T {
+	if offset != nil && *offset > 0 {
+		if *offset >= int64(len(items)) {
+			return []T{}
+		}
+		items = items[*offset:]
+	}
+	if limit != nil && *limit > 0 && *limit < int64(len(items)) {
+		items = items[:*limit]
+	}
+	return items
+}
+
+// copyTodoEntry copies the slices and the category of the entry
+func copyTodoEntry(item model.TodoEntry) model.TodoEntry {
+	item.Category = clonePointer(item.Category)
+	item.WorkDays = slices.Clone(item.WorkDays)
+	item.ReminderAdjustments = slices.Clone(item.ReminderAdjustments)
+	return item
+}
+
+// copyTodoTemplate copies the slices and the category of the template
+func copyTodoTemplate(item model.TodoTemplate) model.TodoTemplate {
+	item.Category = clonePointer(item.Category)
+	item.Subtasks = slices.Clone(item.Subtasks)
+	item.ReminderOffsets = slices.Clone(item.ReminderOffsets)
+	item.WorkDayOffsets = slices.Clone(item.WorkDayOffsets)
+	return item
+}
+
+// copyRing copies the history of the ring
+func copyRing(item model.Ring) model.Ring {
+	item.History = slices.Clone(item.History)
+	return item
+}
+
+// copyAppOrgConfig copies the features of the config
+func copyAppOrgConfig(item model.AppOrgConfig) model.AppOrgConfig {
+	item.Settings.Features = maps.Clone(item.Settings.Features)
+	return item
+}
+
+// copyUserPreferences copies the slices and the quiet hours of the preferences
+func copyUserPreferences(item model.UserPreferences) model.UserPreferences {
+	item.DefaultReminderOffsets = slices.Clone(item.DefaultReminderOffsets)
+	item.QuietHours = clonePointer(item.QuietHours)
+	item.NotificationOptOuts = slices.Clone(item.NotificationOptOuts)
+	return item
+}
+
+// copyDeletionAudit copies the counts and the errors of the audit
+func copyDeletionAudit(item model.DeletionAudit) model.DeletionAudit {
+	item.DeletedCounts = maps.Clone(item.DeletedCounts)
+	item.Errors = slices.Clone(item.Errors)
+	return item
+}
+
+func clonePointer[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	result := *value
+	return &result
+}
+
+// NewMemoryAdapter creates a new in-memory storage adapter instance
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{
+		todoCategories:             newMemoryCollection[model.TodoCategory]("todo_categories", nil),
+		todoEntries:                newMemoryCollection[model.TodoEntry]("todo_entries", copyTodoEntry),
+		rings:                      newMemoryCollection[model.Ring]("rings", copyRing),
+		ringsRecords:               newMemoryCollection[model.RingRecord]("rings_records", nil),
+		notificationDeleteFailures: newMemoryCollection[model.NotificationDeleteFailure]("notification_delete_failures", nil),
+		deletionAudits:             newMemoryCollection[model.DeletionAudit]("deletion_audits", copyDeletionAudit),
+		locks:                      newMemoryCollection[memoryLock]("locks", nil),
+		migrations:                 newMemoryCollection[model.Migration]("migrations", nil),
+		notificationTemplates:      newMemoryCollection[model.NotificationTemplate]("notification_templates", nil),
+		userPreferences:            newMemoryCollection[model.UserPreferences]("user_preferences", copyUserPreferences),
+		ringNudges:                 newMemoryCollection[model.RingNudge]("ring_nudges", nil),
+		todoTemplates:              newMemoryCollection[model.TodoTemplate]("todo_templates", copyTodoTemplate),
+		configs:                    newMemoryCollection[model.AppOrgConfig]("configs", copyAppOrgConfig),
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"wellness/core/model"
+)
+
+func createTestTodoEntry(t *testing.T, m *MemoryAdapter, id string, title string) {
+	t.Helper()
+	todo := model.TodoEntry{Title: title, WorkDays: []string{"monday"}}
+	_, err := m.CreateTodoEntry(nil, "app", "org", "user", &todo, model.MessageIDs{}, id)
+	if err != nil {
+		t.Fatalf("CreateTodoEntry(%s) error = %v", id, err)
+	}
+}
+
+func checkTestTodoEntry(t *testing.T, m *MemoryAdapter, id string, title string) {
+	t.Helper()
+	todo, err := m.GetTodoEntry(nil, "app", "org", "user", id)
+	if err != nil {
+		t.Fatalf("GetTodoEntry(%s) error = %v", id, err)
+	}
+	if title == "" {
+		if todo != nil {
+			t.Fatalf("GetTodoEntry(%s) = %s, want none", id, todo.Title)
+		}
+		return
+	}
+	if todo == nil {
+		t.Fatalf("GetTodoEntry(%s) = none, want %s", id, title)
+	}
+	if todo.Title != title {
+		t.Fatalf("GetTodoEntry(%s) = %s, want %s", id, todo.Title, title)
+	}
+}
+
+// updateTestTodoEntry sets the title of the entry, it does not fail the test as it is called by other goroutines
+func updateTestTodoEntry(m *MemoryAdapter, context TransactionContext, id string, title string) error {
+	todo, err := m.GetTodoEntry(context, "app", "org", "user", id)
+	if err != nil {
+		return err
+	}
+	if todo == nil {
+		return fmt.Errorf("todo entry %s not found", id)
+	}
+	todo.Title = title
+	_, err = m.UpdateTodoEntry(context, "app", "org", "user", todo, id)
+	return err
+}
+
+func TestMemoryAdapterTransactionCommit(t *testing.T) {
+	m := NewMemoryAdapter()
+	createTestTodoEntry(t, m, "first", "first")
+
+	err := m.PerformTransaction(func(context TransactionContext) error {
+		return updateTestTodoEntry(m, context, "first", "updated")
+	})
+	if err != nil {
+		t.Fatalf("PerformTransaction() error = %v", err)
+	}
+	checkTestTodoEntry(t, m, "first", "updated")
+}
+
+func TestMemoryAdapterTransactionRollback(t *testing.T) {
+	m := NewMemoryAdapter()
+	createTestTodoEntry(t, m, "updated", "updated")
+	createTestTodoEntry(t, m, "deleted", "deleted")
+
+	err := m.PerformTransaction(func(context TransactionContext) error {
+		err := updateTestTodoEntry(m, context, "updated", "first")
+		if err != nil {
+			return err
+		}
+		err = updateTestTodoEntry(m, context, "updated", "second")
+		if err != nil {
+			return err
+		}
+		err = m.DeleteTodoEntry(context, "app", "org", "user", "deleted")
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("failed")
+	})
+	if err == nil {
+		t.Fatal("PerformTransaction() error = nil, want the error of the transaction")
+	}
+	checkTestTodoEntry(t, m, "updated", "updated")
+	checkTestTodoEntry(t, m, "deleted", "deleted")
+}
+
+func TestMemoryAdapterTransactionTypedError(t *testing.T) {
+	m := NewMemoryAdapter()
+
+	err := m.PerformTransaction(func(context TransactionContext) error {
+		return model.NewNotFoundError("todo entry", "missing")
+	})
+	if modelErr := model.AsError(err); modelErr == nil || modelErr.Type != model.ErrorTypeNotFound {
+		t.Fatalf("PerformTransaction() error = %v, want the not found error", err)
+	}
+}
+
+func TestMemoryAdapterTransactionIsolation(t *testing.T) {
+	m := NewMemoryAdapter()
+	createTestTodoEntry(t, m, "transaction", "transaction")
+	createTestTodoEntry(t, m, "outside", "outside")
+
+	started := make(chan struct{})
+	fail := make(chan struct{})
+	failed := make(chan error)
+	go func() {
+		failed <- m.PerformTransaction(func(context TransactionContext) error {
+			err := updateTestTodoEntry(m, context, "transaction", "failed")
+			close(started)
+			if err != nil {
+				return err
+			}
+			<-fail
+			return fmt.Errorf("failed")
+		})
+	}()
+	<-started
+
+	//a write without the context is not part of the failing transaction
+	err := updateTestTodoEntry(m, nil, "outside", "kept")
+	if err != nil {
+		t.Fatalf("UpdateTodoEntry() error = %v", err)
+	}
+
+	//another transaction waits for the failing one, so it is not restored by it
+	committed := make(chan error)
+	go func() {
+		committed <- m.PerformTransaction(func(context TransactionContext) error {
+			return updateTestTodoEntry(m, context, "transaction", "committed")
+		})
+	}()
+	select {
+	case err = <-committed:
+		t.Fatalf("PerformTransaction() = %v while another transaction is performed", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(fail)
+	if err = <-failed; err == nil {
+		t.Fatal("PerformTransaction() error = nil, want the error of the transaction")
+	}
+	if err = <-committed; err != nil {
+		t.Fatalf("PerformTransaction() error = %v", err)
+	}
+	checkTestTodoEntry(t, m, "outside", "kept")
+	checkTestTodoEntry(t, m, "transaction", "committed")
+}
+
+func TestMemoryAdapterCopies(t *testing.T) {
+	m := NewMemoryAdapter()
+
+	todo := model.TodoEntry{Title: "todo", WorkDays: []string{"monday"},
+		ReminderAdjustments: []model.ReminderAdjustment{{Kind: model.ReminderKindDue}}}
+	_, err := m.CreateTodoEntry(nil, "app", "org", "user", &todo, model.MessageIDs{}, "todo")
+	if err != nil {
+		t.Fatalf("CreateTodoEntry() error = %v", err)
+	}
+	todo.WorkDays[0] = "written"
+	todo.ReminderAdjustments[0].Kind = "written"
+
+	read, err := m.GetTodoEntry(nil, "app", "org", "user", "todo")
+	if err != nil {
+		t.Fatalf("GetTodoEntry() error = %v", err)
+	}
+	read.WorkDays[0] = "read"
+	entries, err := m.GetTodoEntries(nil, "app", "org", "user")
+	if err != nil {
+		t.Fatalf("GetTodoEntries() error = %v", err)
+	}
+	entries[0].ReminderAdjustments[0].Kind = "read"
+
+	stored, _ := m.GetTodoEntry(nil, "app", "org", "user", "todo")
+	if stored.WorkDays[0] != "monday" || stored.ReminderAdjustments[0].Kind != model.ReminderKindDue {
+		t.Fatalf("stored entry = %v %v, changed by the callers", stored.WorkDays, stored.ReminderAdjustments)
+	}
+
+	ring := model.Ring{History: []model.RingHistoryEntry{{Name: "ring"}}}
+	_, err = m.CreateRing("app", "org", "user", &ring)
+	if err != nil {
+		t.Fatalf("CreateRing() error = %v", err)
+	}
+	ring.History[0].Name = "written"
+	rings, err := m.GetRings("app", "org", "user")
+	if err != nil {
+		t.Fatalf("GetRings() error = %v", err)
+	}
+	rings[0].History[0].Name = "read"
+	storedRing, _ := m.GetRing("app", "org", "user", ring.ID)
+	if storedRing.History[0].Name != "ring" {
+		t.Fatalf("stored ring history = %v, changed by the callers", storedRing.History)
+	}
+}
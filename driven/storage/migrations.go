@@ -0,0 +1,215 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"wellness/core/model"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/errors"
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration is a versioned change of the stored data.
+//
+// The migrations are applied in the order of their versions and every applied version is recorded in the migrations collection.
+// A migration must be idempotent - it could be interrupted and run again. In dry run mode it must not change anything,
+// just report what would be changed.
+//
+// A migration without migrate function is applied by the application task with the same name.
+type migration struct {
+	version int
+	name    string
+	migrate func(sa *Adapter, dryRun bool) (string, error)
+}
+
+// migrations is the ordered list of all the migrations. Never change or remove an already released migration - add a new one instead.
+var migrations = []migration{
+	{version: 1, name: "baseline", migrate: func(sa *Adapter, dryRun bool) (string, error) {
+		//the collections and the indexes are applied on start, this only marks the schema the migrations start from
+		return "baseline schema", nil
+	}},
+	{version: multiTenancyBackfillVersion, name: "multi_tenancy_backfill", migrate: backfillMultiTenancy},
+	{version: 3, name: MessageIDsMigrationName},
+}
+
+// MessageIDsMigrationName is the name of the migration which schedules the notifications of the todo entries created
+// before the message ids were stored
+const MessageIDsMigrationName = "message_ids"
+
+// multiTenancyBackfillVersion is the version of the migration which assigns the legacy data to the multi-tenancy app and org
+const multiTenancyBackfillVersion = 2
+
+// backfillMultiTenancy sets the multi-tenancy app and org on the documents created before the multi-tenancy
+func backfillMultiTenancy(sa *Adapter, dryRun bool) (string, error) {
+	if sa.multiTenancyAppID == "" || sa.multiTenancyOrgID == "" {
+		return "", fmt.Errorf("the multi-tenancy app id and org id are required")
+	}
+
+	collections := []struct {
+		name string
+		coll *collectionWrapper
+	}{
+		{"todo_categories", sa.db.todoCategories},
+		{"todo_entries", sa.db.todoEntries},
+		{"rings", sa.db.rings},
+		{"rings_records", sa.db.ringsRecords},
+	}
+	fields := []struct {
+		key   string
+		value string
+	}{
+		{"app_id", sa.multiTenancyAppID},
+		{"org_id", sa.multiTenancyOrgID},
+	}
+
+	var summary []string
+	for _, collection := range collections {
+		for _, field := range fields {
+			filter := bson.D{primitive.E{Key: field.key, Value: bson.M{"$in": bson.A{nil, ""}}}}
+
+			var count int64
+			if dryRun {
+				var err error
+				count, err = collection.coll.CountDocuments(filter)
+				if err != nil {
+					return "", errors.WrapErrorAction(logutils.ActionCount, logutils.MessageDataType(collection.name), &logutils.FieldArgs{"field": field.key}, err)
+				}
+			} else {
+				update := bson.D{primitive.E{Key: "$set", Value: bson.D{primitive.E{Key: field.key, Value: field.value}}}}
+				result, err := collection.coll.UpdateMany(filter, update, nil)
+				if err != nil {
+					return "", errors.WrapErrorAction(logutils.ActionUpdate, logutils.MessageDataType(collection.name), &logutils.FieldArgs{"field": field.key}, err)
+				}
+				count = result.ModifiedCount
+			}
+			summary = append(summary, fmt.Sprintf("%s.%s:%d", collection.name, field.key, count))
+		}
+	}
+
+	if !dryRun {
+		//all the data is assigned to a tenant now, so it is read by the exact app and org
+		sa.multiTenancyBackfilled.Store(true)
+	}
+	return strings.Join(summary, " "), nil
+}
+
+func latestMigrationVersion() int {
+	latest := 0
+	for _, item := range migrations {
+		if item.version > latest {
+			latest = item.version
+		}
+	}
+	return latest
+}
+
+// ApplyMigrations applies the pending migrations in order. The tasks apply the migrations which need the application logic.
+// It gives the applied migrations or the ones which would be applied in dry run mode.
+// The caller must guarantee that only one service instance applies the migrations at a time.
+func (sa *Adapter) ApplyMigrations(dryRun bool, tasks map[string]model.MigrationTask) ([]model.Migration, error) {
+	applied, err := sa.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedVersions := map[int]bool{}
+	for _, item := range applied {
+		appliedVersions[item.Version] = true
+	}
+
+	var result []model.Migration
+	for _, item := range sortedMigrations() {
+		if appliedVersions[item.version] {
+			continue
+		}
+
+		log.Printf("apply migration %d %s (dry run:%t).....", item.version, item.name, dryRun)
+		started := time.Now()
+		var summary string
+		if item.migrate != nil {
+			summary, err = item.migrate(sa, dryRun)
+		} else if task, ok := tasks[item.name]; ok {
+			summary, err = task(dryRun)
+		} else {
+			err = fmt.Errorf("no task is given")
+		}
+		if err != nil {
+			return result, fmt.Errorf("error applying migration %d %s - %s", item.version, item.name, err)
+		}
+
+		record := model.Migration{Version: item.version, Name: item.name, Summary: summary, DryRun: dryRun,
+			Duration: time.Since(started).Milliseconds(), DateApplied: time.Now().UTC()}
+		if !dryRun {
+			_, err = sa.db.migrations.InsertOne(record)
+			if err != nil {
+				return result, errors.WrapErrorAction(logutils.ActionInsert, "migration", &logutils.FieldArgs{"version": item.version}, err)
+			}
+		}
+		log.Printf("migration %d %s passed - %s", item.version, item.name, summary)
+
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// IsMigrationApplied checks if the migration with the given version has been applied
+func (sa *Adapter) IsMigrationApplied(version int) (bool, error) {
+	count, err := sa.db.migrations.CountDocuments(bson.D{primitive.E{Key: "_id", Value: version}})
+	if err != nil {
+		return false, errors.WrapErrorAction(logutils.ActionCount, "migration", &logutils.FieldArgs{"version": version}, err)
+	}
+	return count > 0, nil
+}
+
+// checkMigrationsVersion fails if the database has migrations applied by a newer version of the service
+func (sa *Adapter) checkMigrationsVersion() error {
+	applied, err := sa.getAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	latest := latestMigrationVersion()
+	for _, item := range applied {
+		if item.Version > latest {
+			return fmt.Errorf("the database is ahead of the service - migration %d %s is applied but the latest known migration is %d",
+				item.Version, item.Name, latest)
+		}
+	}
+	return nil
+}
+
+func (sa *Adapter) getAppliedMigrations() ([]model.Migration, error) {
+	var result []model.Migration
+	err := sa.db.migrations.Find(bson.D{}, &result, options.Find().SetSort(bson.D{primitive.E{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, errors.WrapErrorAction(logutils.ActionFind, "migration", nil, err)
+	}
+	return result, nil
+}
+
+func sortedMigrations() []migration {
+	sorted := make([]migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].version < sorted[j].version })
+	return sorted
+}
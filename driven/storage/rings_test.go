@@ -0,0 +1,87 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+// ringNudgesStorage is the storage of the rings with nudges
+type ringNudgesStorage interface {
+	CreateRing(appID string, orgID string, userID string, ring *model.Ring) (*model.Ring, error)
+	InsertRingNudge(nudge model.RingNudge) (bool, error)
+	GetRingsWithNudges(afterID string, limit int64, since time.Time, caps []model.RingNudgesCap, defaultMax int) ([]model.Ring, error)
+}
+
+func TestMemoryAdapterGetRingsWithNudges(t *testing.T) {
+	testGetRingsWithNudges(t, NewMemoryAdapter())
+}
+
+func TestAdapterGetRingsWithNudges(t *testing.T) {
+	testGetRingsWithNudges(t, newTestAdapter(t))
+}
+
+func testGetRingsWithNudges(t *testing.T, store ringNudgesStorage) {
+	for _, userID := range []string{"capped", "user-1", "user-2", "user-3"} {
+		_, err := store.CreateRing("app", "org", userID, &model.Ring{NudgesEnabled: true})
+		if err != nil {
+			t.Fatalf("CreateRing(%s) error = %v", userID, err)
+		}
+	}
+	_, err := store.CreateRing("app", "org", "no-nudges", &model.Ring{})
+	if err != nil {
+		t.Fatalf("CreateRing() error = %v", err)
+	}
+	now := time.Now().UTC()
+	_, err = store.InsertRingNudge(model.RingNudge{ID: uuid.NewString(), AppID: "app", OrgID: "org", UserID: "capped", RingID: "ring",
+		Date: now.Format("2006-01-02"), DateCreated: now})
+	if err != nil {
+		t.Fatalf("InsertRingNudge() error = %v", err)
+	}
+	since := now.AddDate(0, 0, -7)
+	caps := []model.RingNudgesCap{{AppID: "app", OrgID: "org", Max: 1}}
+
+	var users []string
+	afterID := ""
+	for {
+		rings, err := store.GetRingsWithNudges(afterID, 2, since, caps, 3)
+		if err != nil {
+			t.Fatalf("GetRingsWithNudges() error = %v", err)
+		}
+		for _, ring := range rings {
+			if ring.ID <= afterID {
+				t.Fatalf("GetRingsWithNudges() after %s gave %s", afterID, ring.ID)
+			}
+			users = append(users, ring.UserID)
+		}
+		if len(rings) < 2 {
+			break
+		}
+		afterID = rings[len(rings)-1].ID
+	}
+	if len(users) != 3 || slices.Contains(users, "capped") {
+		t.Fatalf("GetRingsWithNudges() gave the rings of %v, want the 3 users below the cap", users)
+	}
+
+	rings, err := store.GetRingsWithNudges("", 10, since, nil, 3)
+	if err != nil || len(rings) != 4 {
+		t.Fatalf("GetRingsWithNudges() with the default cap = %d rings, %v, want 4", len(rings), err)
+	}
+}
@@ -15,9 +15,11 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 	"wellness/core"
 	"wellness/core/model"
 	"wellness/driver/web/rest"
@@ -39,6 +41,11 @@ type Adapter struct {
 	adminApisHandler    rest.AdminApisHandler
 	internalApisHandler rest.InternalApisHandler
 
+	//inspection of the fake building blocks, nil when the real ones are used
+	fakesHandler http.Handler
+
+	server *http.Server
+
 	app *core.Application
 }
 
@@ -69,7 +76,14 @@ type Adapter struct {
 
 // Start starts the module
 func (we Adapter) Start() {
+	err := we.server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
 
+// newRouter routes the requests to the handlers
+func (we Adapter) newRouter() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 
 	// handle apis
@@ -77,6 +91,35 @@ func (we Adapter) Start() {
 	subRouter.PathPrefix("/doc/ui").Handler(we.serveDocUI())
 	subRouter.HandleFunc("/doc", we.serveDoc)
 	subRouter.HandleFunc("/version", we.wrapFunc(we.apisHandler.Version)).Methods("GET")
+	subRouter.HandleFunc("/health", we.wrapFunc(we.apisHandler.Health)).Methods("GET")
+	subRouter.HandleFunc("/ready", we.wrapFunc(we.apisHandler.Ready)).Methods("GET")
+	subRouter.HandleFunc("/metrics", we.internalAuthWrapFunc(utils.MetricsHandler().ServeHTTP)).Methods("GET")
+
+	// handle admin apis
+	adminSubRouter := subRouter.PathPrefix("/admin").Subrouter()
+	adminSubRouter.HandleFunc("/deletion_audits", we.coreAuthWrapFunc(we.adminApisHandler.GetDeletionAudits, we.auth.coreAuth.permissionsAuth)).Methods("GET")
+	adminSubRouter.HandleFunc("/config", we.coreAuthWrapFunc(we.adminApisHandler.GetConfig, we.auth.coreAuth.permissionsAuth)).Methods("GET")
+	adminSubRouter.HandleFunc("/config", we.coreAuthWrapFunc(we.adminApisHandler.CreateConfig, we.auth.coreAuth.permissionsAuth)).Methods("POST")
+	adminSubRouter.HandleFunc("/config", we.coreAuthWrapFunc(we.adminApisHandler.UpdateConfig, we.auth.coreAuth.permissionsAuth)).Methods("PUT")
+	adminSubRouter.HandleFunc("/config", we.coreAuthWrapFunc(we.adminApisHandler.DeleteConfig, we.auth.coreAuth.permissionsAuth)).Methods("DELETE")
+	adminSubRouter.HandleFunc("/notification_templates", we.coreAuthWrapFunc(we.adminApisHandler.GetNotificationTemplates, we.auth.coreAuth.permissionsAuth)).Methods("GET")
+	adminSubRouter.HandleFunc("/notification_templates", we.coreAuthWrapFunc(we.adminApisHandler.CreateNotificationTemplate, we.auth.coreAuth.permissionsAuth)).Methods("POST")
+	adminSubRouter.HandleFunc("/notification_templates/{id}", we.coreAuthWrapFunc(we.adminApisHandler.UpdateNotificationTemplate, we.auth.coreAuth.permissionsAuth)).Methods("PUT")
+	adminSubRouter.HandleFunc("/notification_templates/{id}", we.coreAuthWrapFunc(we.adminApisHandler.DeleteNotificationTemplate, we.auth.coreAuth.permissionsAuth)).Methods("DELETE")
+	adminSubRouter.HandleFunc("/todo_templates", we.coreAuthWrapFunc(we.adminApisHandler.GetTodoTemplates, we.auth.coreAuth.permissionsAuth)).Methods("GET")
+	adminSubRouter.HandleFunc("/todo_templates", we.coreAuthWrapFunc(we.adminApisHandler.CreateTodoTemplate, we.auth.coreAuth.permissionsAuth)).Methods("POST")
+	adminSubRouter.HandleFunc("/todo_templates/{id}", we.coreAuthWrapFunc(we.adminApisHandler.UpdateTodoTemplate, we.auth.coreAuth.permissionsAuth)).Methods("PUT")
+	adminSubRouter.HandleFunc("/todo_templates/{id}", we.coreAuthWrapFunc(we.adminApisHandler.DeleteTodoTemplate, we.auth.coreAuth.permissionsAuth)).Methods("DELETE")
+
+	// handle system apis - the jobs run for all the apps/orgs, so they need the system permission
+	systemSubRouter := subRouter.PathPrefix("/system").Subrouter()
+	systemSubRouter.HandleFunc("/delete_data/run", we.coreAuthWrapFunc(we.adminApisHandler.RunDeleteData, we.auth.coreAuth.permissionsAuth)).Methods("POST")
+
+	// handle the inspection of the fake building blocks
+	if we.fakesHandler != nil {
+		fakesHandler := http.StripPrefix("/wellness/int/fakes", we.fakesHandler)
+		subRouter.PathPrefix("/int/fakes/").HandlerFunc(we.internalAuthWrapFunc(fakesHandler.ServeHTTP))
+	}
 
 	subRouter = subRouter.PathPrefix("/api").Subrouter()
 
@@ -85,15 +128,30 @@ func (we Adapter) Start() {
 	subRouter.HandleFunc("/user/todo_categories", we.coreAuthWrapFunc(we.apisHandler.CreateUserTodoCategory, we.auth.coreAuth.standardAuth)).Methods("POST")
 	subRouter.HandleFunc("/user/todo_categories/{id}", we.coreAuthWrapFunc(we.apisHandler.GetUserTodoCategory, we.auth.coreAuth.standardAuth)).Methods("GET")
 	subRouter.HandleFunc("/user/todo_categories/{id}", we.coreAuthWrapFunc(we.apisHandler.UpdateUserTodoCategory, we.auth.coreAuth.standardAuth)).Methods("PUT")
+	subRouter.HandleFunc("/user/todo_categories/{id}", we.coreAuthWrapFunc(we.apisHandler.PatchUserTodoCategory, we.auth.coreAuth.standardAuth)).Methods("PATCH")
 	subRouter.HandleFunc("/user/todo_categories/{id}", we.coreAuthWrapFunc(we.apisHandler.DeleteUserTodoCategory, we.auth.coreAuth.standardAuth)).Methods("DELETE")
+	subRouter.HandleFunc("/user/todo_categories/{id}/position", we.coreAuthWrapFunc(we.apisHandler.MoveUserTodoCategory, we.auth.coreAuth.standardAuth)).Methods("PUT")
 
 	// handle user todo entries apis
 	subRouter.HandleFunc("/user/todo_entries", we.coreAuthWrapFunc(we.apisHandler.GetUserTodoEntries, we.auth.coreAuth.standardAuth)).Methods("GET")
 	subRouter.HandleFunc("/user/todo_entries", we.coreAuthWrapFunc(we.apisHandler.CreateUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("POST")
 	subRouter.HandleFunc("/user/todo_entries/clear_completed_entries", we.coreAuthWrapFunc(we.apisHandler.DeleteCompletedUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("DELETE")
+	subRouter.HandleFunc("/user/todo_entries/bulk", we.coreAuthWrapFunc(we.apisHandler.BulkUserTodoEntries, we.auth.coreAuth.standardAuth)).Methods("POST")
+	subRouter.HandleFunc("/user/todo_entries/overdue", we.coreAuthWrapFunc(we.apisHandler.GetUserOverdueTodoEntries, we.auth.coreAuth.standardAuth)).Methods("GET")
 	subRouter.HandleFunc("/user/todo_entries/{id}", we.coreAuthWrapFunc(we.apisHandler.GetUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("GET")
 	subRouter.HandleFunc("/user/todo_entries/{id}", we.coreAuthWrapFunc(we.apisHandler.UpdateUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("PUT")
+	subRouter.HandleFunc("/user/todo_entries/{id}", we.coreAuthWrapFunc(we.apisHandler.PatchUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("PATCH")
 	subRouter.HandleFunc("/user/todo_entries/{id}", we.coreAuthWrapFunc(we.apisHandler.DeleteUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("DELETE")
+	subRouter.HandleFunc("/user/todo_entries/{id}/position", we.coreAuthWrapFunc(we.apisHandler.MoveUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("PUT")
+	subRouter.HandleFunc("/user/todo_entries/{id}/snooze", we.coreAuthWrapFunc(we.apisHandler.SnoozeUserTodoEntry, we.auth.coreAuth.standardAuth)).Methods("POST")
+	subRouter.HandleFunc("/user/todo_entries/{id}/template", we.coreAuthWrapFunc(we.apisHandler.SaveUserTodoEntryAsTemplate, we.auth.coreAuth.standardAuth)).Methods("POST")
+
+	// handle user todo templates apis
+	subRouter.HandleFunc("/user/todo_templates", we.coreAuthWrapFunc(we.apisHandler.GetUserTodoTemplates, we.auth.coreAuth.standardAuth)).Methods("GET")
+	subRouter.HandleFunc("/user/todo_templates", we.coreAuthWrapFunc(we.apisHandler.CreateUserTodoTemplate, we.auth.coreAuth.standardAuth)).Methods("POST")
+	subRouter.HandleFunc("/user/todo_templates/{id}", we.coreAuthWrapFunc(we.apisHandler.UpdateUserTodoTemplate, we.auth.coreAuth.standardAuth)).Methods("PUT")
+	subRouter.HandleFunc("/user/todo_templates/{id}", we.coreAuthWrapFunc(we.apisHandler.DeleteUserTodoTemplate, we.auth.coreAuth.standardAuth)).Methods("DELETE")
+	subRouter.HandleFunc("/user/todo_templates/{id}/instantiate", we.coreAuthWrapFunc(we.apisHandler.InstantiateUserTodoTemplate, we.auth.coreAuth.standardAuth)).Methods("POST")
 
 	// handle user wellness rings apis
 	subRouter.HandleFunc("/user/rings", we.coreAuthWrapFunc(we.apisHandler.GetUserRings, we.auth.coreAuth.standardAuth)).Methods("GET")
@@ -102,6 +160,8 @@ func (we Adapter) Start() {
 	subRouter.HandleFunc("/user/rings/{id}", we.coreAuthWrapFunc(we.apisHandler.DeleteUserRing, we.auth.coreAuth.standardAuth)).Methods("DELETE")
 	subRouter.HandleFunc("/user/rings/{id}/history", we.coreAuthWrapFunc(we.apisHandler.CreateUserRingHistoryEntry, we.auth.coreAuth.standardAuth)).Methods("POST")
 	subRouter.HandleFunc("/user/rings/{id}/history/{history-id}", we.coreAuthWrapFunc(we.apisHandler.DeleteUserRingHistoryEntry, we.auth.coreAuth.standardAuth)).Methods("DELETE")
+	subRouter.HandleFunc("/user/rings/{id}/summary", we.coreAuthWrapFunc(we.apisHandler.GetUserRingSummary, we.auth.coreAuth.standardAuth)).Methods("GET")
+	subRouter.HandleFunc("/user/rings/{id}/nudges", we.coreAuthWrapFunc(we.apisHandler.UpdateUserRingNudges, we.auth.coreAuth.standardAuth)).Methods("PUT")
 
 	// handle user wellness rings records apis
 	subRouter.HandleFunc("/user/all_rings_records", we.coreAuthWrapFunc(we.apisHandler.GetUserAllRingRecords, we.auth.coreAuth.standardAuth)).Methods("GET")
@@ -115,7 +175,18 @@ func (we Adapter) Start() {
 
 	subRouter.HandleFunc("/user-data", we.coreAuthWrapFunc(we.apisHandler.GetUserData, we.auth.coreAuth.standardAuth)).Methods("GET")
 
-	log.Fatal(http.ListenAndServe(":"+we.port, router))
+	// handle user preferences apis
+	subRouter.HandleFunc("/user/preferences", we.coreAuthWrapFunc(we.apisHandler.GetUserPreferences, we.auth.coreAuth.standardAuth)).Methods("GET")
+	subRouter.HandleFunc("/user/preferences", we.coreAuthWrapFunc(we.apisHandler.UpdateUserPreferences, we.auth.coreAuth.standardAuth)).Methods("PUT")
+	subRouter.HandleFunc("/user/preferences", we.coreAuthWrapFunc(we.apisHandler.DeleteUserPreferences, we.auth.coreAuth.standardAuth)).Methods("DELETE")
+	subRouter.HandleFunc("/config", we.coreAuthWrapFunc(we.apisHandler.GetConfig, we.auth.coreAuth.standardAuth)).Methods("GET")
+
+	return router
+}
+
+// Shutdown stops accepting new requests and waits for the in-flight ones to finish or until the context is done
+func (we Adapter) Shutdown(ctx context.Context) error {
+	return we.server.Shutdown(ctx)
 }
 
 func (we Adapter) serveDoc(w http.ResponseWriter, r *http.Request) {
@@ -131,6 +202,10 @@ func (we Adapter) serveDocUI() http.Handler {
 func (we Adapter) wrapFunc(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		utils.LogRequest(req)
+		metricsWriter := newMetricsResponseWriter(w, req)
+		defer metricsWriter.observe()
+		w = metricsWriter
+		req = rest.WithRequestID(w, req)
 
 		handler(w, req)
 	}
@@ -141,11 +216,15 @@ type coreAuthFunc = func(*tokenauth.Claims, http.ResponseWriter, *http.Request)
 func (we Adapter) coreAuthWrapFunc(handler coreAuthFunc, authorization Authorization) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		utils.LogRequest(req)
+		metricsWriter := newMetricsResponseWriter(w, req)
+		defer metricsWriter.observe()
+		w = metricsWriter
+		req = rest.WithRequestID(w, req)
 
 		responseStatus, claims, err := authorization.check(req)
 		if err != nil {
 			log.Printf("error authorization check - %s", err)
-			http.Error(w, http.StatusText(responseStatus), responseStatus)
+			rest.WriteErrorStatus(w, req, responseStatus)
 			return
 		}
 		handler(claims, w, req)
@@ -157,11 +236,15 @@ type internalAuthFunc = func(http.ResponseWriter, *http.Request)
 func (we Adapter) internalAuthWrapFunc(handler internalAuthFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		utils.LogRequest(req)
+		metricsWriter := newMetricsResponseWriter(w, req)
+		defer metricsWriter.observe()
+		w = metricsWriter
+		req = rest.WithRequestID(w, req)
 
 		status, err := we.auth.internalAuth.check(req)
 		if err != nil {
 			log.Printf("error authorization check - %s", err)
-			http.Error(w, http.StatusText(status), status)
+			rest.WriteErrorStatus(w, req, status)
 			return
 		}
 
@@ -169,15 +252,55 @@ func (we Adapter) internalAuthWrapFunc(handler internalAuthFunc) http.HandlerFun
 	}
 }
 
+// metricsResponseWriter keeps the status of the response for the metrics
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	route   string
+	method  string
+	status  int
+	started time.Time
+}
+
+func (w *metricsResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// observe records the request in the metrics once it is handled
+func (w *metricsResponseWriter) observe() {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	utils.ObserveHTTPRequest(w.route, w.method, status, w.started)
+}
+
+// newMetricsResponseWriter gives a response writer for the request. The route is the path template of the matched route.
+func newMetricsResponseWriter(w http.ResponseWriter, req *http.Request) *metricsResponseWriter {
+	route := req.URL.Path
+	if current := mux.CurrentRoute(req); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+	return &metricsResponseWriter{ResponseWriter: w, route: route, method: req.Method, started: time.Now()}
+}
+
 // NewWebAdapter creates new WebAdapter instance
-func NewWebAdapter(host string, port string, app *core.Application, config model.Config, serviceRegManager *auth.ServiceRegManager) Adapter {
+func NewWebAdapter(host string, port string, app *core.Application, config model.Config, serviceRegManager *auth.ServiceRegManager,
+	fakesHandler http.Handler) Adapter {
 	auth := NewAuth(app, config, serviceRegManager)
 
 	apisHandler := rest.NewApisHandler(app)
 	adminApisHandler := rest.NewAdminApisHandler(app)
 	internalApisHandler := rest.NewInternalApisHandler(app)
-	return Adapter{host: host, port: port, auth: auth, apisHandler: apisHandler, adminApisHandler: adminApisHandler,
-		internalApisHandler: internalApisHandler, app: app}
+	adapter := Adapter{host: host, port: port, auth: auth, apisHandler: apisHandler, adminApisHandler: adminApisHandler,
+		internalApisHandler: internalApisHandler, fakesHandler: fakesHandler, server: &http.Server{Addr: ":" + port}, app: app}
+	//the server is ready before Start, so it can be shut down any time
+	adapter.server.Handler = adapter.newRouter()
+	return adapter
 }
 
 // AppListener implements core.ApplicationListener interface
@@ -0,0 +1,230 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wellness/core"
+	"wellness/core/model"
+	"wellness/driven/fakes"
+	"wellness/driven/storage"
+
+	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth"
+	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
+)
+
+const testInternalAPIKey = "internal-api-key"
+
+// testService is the service with the in-memory storage and the fake building blocks, as started by WELLNESS_FAKE_BBS=true
+type testService struct {
+	t       *testing.T
+	adapter Adapter
+	server  *httptest.Server
+}
+
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+	//the authorization policies and the docs are read relative to the repository root
+	t.Chdir("../..")
+
+	authService := auth.Service{ServiceID: "wellness", ServiceHost: "http://wellness.test", FirstParty: true, AuthBaseURL: "http://core.test"}
+	fakeAuth, err := fakes.NewAuthLoader(authService.ServiceID, authService.ServiceHost, authService.AuthBaseURL)
+	if err != nil {
+		t.Fatalf("error creating the fake auth - %s", err)
+	}
+	serviceRegManager, err := auth.NewServiceRegManager(&authService, fakeAuth, false)
+	if err != nil {
+		t.Fatalf("error creating the service registration manager - %s", err)
+	}
+
+	fakeCore := fakes.NewCoreAdapter(nil)
+	fakeNotifications := fakes.NewNotificationsAdapter()
+
+	//the background jobs run once a year, so they do not interfere
+	yearly := "0 0 1 1 *"
+	app := core.NewApplication("test", "test", logs.NewLogger("test", &logs.LoggerOpts{}), storage.NewMemoryAdapter(),
+		fakeCore, fakeNotifications, "app", "org",
+		model.DeleteDataConfig{Schedule: yearly, Timezone: "UTC"}, model.DigestConfig{Schedule: yearly},
+		model.RingNudgesConfig{Schedule: yearly}, model.OverdueConfig{Schedule: yearly}, false, false)
+	app.Start()
+
+	config := model.Config{InternalAPIKey: testInternalAPIKey, CoreBBHost: authService.AuthBaseURL, ServiceURL: authService.ServiceHost}
+	adapter := NewWebAdapter("", "0", app, config, serviceRegManager, fakes.NewHandler(fakeNotifications, fakeCore, fakeAuth))
+	server := httptest.NewServer(adapter.server.Handler)
+	t.Cleanup(func() {
+		server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := app.Stop(ctx)
+		if err != nil {
+			t.Errorf("error stopping the application - %s", err)
+		}
+	})
+	return &testService{t: t, adapter: adapter, server: server}
+}
+
+// request sends the request and decodes the response into result when it is not nil. It gives the status of the response.
+func (s *testService) request(method string, path string, headers map[string]string, body interface{}, result interface{}) int {
+	s.t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("error marshaling the %s %s request - %s", method, path, err)
+		}
+	}
+
+	req, err := http.NewRequest(method, s.server.URL+path, bytes.NewReader(data))
+	if err != nil {
+		s.t.Fatalf("error creating the %s %s request - %s", method, path, err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := s.server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("error sending the %s %s request - %s", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if result != nil && resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			s.t.Fatalf("error decoding the %s %s response - %s", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (s *testService) internal(method string, path string, body interface{}, result interface{}) {
+	s.t.Helper()
+	status := s.request(method, "/wellness/int/fakes"+path, map[string]string{"INTERNAL-API-KEY": testInternalAPIKey}, body, result)
+	if status != http.StatusOK {
+		s.t.Fatalf("%s %s status = %d, want %d", method, path, status, http.StatusOK)
+	}
+}
+
+func (s *testService) user(token string, method string, path string, body interface{}, result interface{}) int {
+	s.t.Helper()
+	return s.request(method, "/wellness/api"+path, map[string]string{"Authorization": "Bearer " + token}, body, result)
+}
+
+func TestTodoEntryRemindersWithFakes(t *testing.T) {
+	service := newTestService(t)
+
+	var token map[string]string
+	service.internal(http.MethodPost, "/tokens", map[string]string{"app_id": "app", "org_id": "org", "user_id": "user"}, &token)
+	if token["access_token"] == "" {
+		t.Fatal("POST /tokens gave no access token")
+	}
+
+	status := service.user("invalid", http.MethodGet, "/user/todo_entries", nil, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("GET /user/todo_entries with an invalid token status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	var created model.TodoEntry
+	status = service.user(token["access_token"], http.MethodPost, "/user/todo_entries",
+		map[string]interface{}{"title": "Walk", "has_due_time": true, "due_date_time": due, "reminder_type": "at_due_time"}, &created)
+	if status != http.StatusOK {
+		t.Fatalf("POST /user/todo_entries status = %d, want %d", status, http.StatusOK)
+	}
+	if created.ID == "" || created.UserID != "user" || created.MessageIDs.DueDateMessageID == nil {
+		t.Fatalf("POST /user/todo_entries = %+v, want an entry of the user with the due message", created)
+	}
+
+	var messages []fakes.Message
+	service.internal(http.MethodGet, "/messages", nil, &messages)
+	if len(messages) != 1 || messages[0].ID != *created.MessageIDs.DueDateMessageID || messages[0].Time == nil || *messages[0].Time != due.Unix() {
+		t.Fatalf("GET /messages = %+v, want the due message of the entry at %d", messages, due.Unix())
+	}
+
+	var entries []model.TodoEntry
+	status = service.user(token["access_token"], http.MethodGet, "/user/todo_entries", nil, &entries)
+	if status != http.StatusOK || len(entries) != 1 || entries[0].ID != created.ID {
+		t.Fatalf("GET /user/todo_entries = %d %+v, want the created entry", status, entries)
+	}
+
+	status = service.user(token["access_token"], http.MethodDelete, "/user/todo_entries/"+created.ID, nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("DELETE /user/todo_entries/%s status = %d, want %d", created.ID, status, http.StatusOK)
+	}
+	service.internal(http.MethodGet, "/messages", nil, &messages)
+	if len(messages) != 1 || !messages[0].Deleted {
+		t.Fatalf("GET /messages = %+v, want the due message deleted", messages)
+	}
+}
+
+func TestMetricsRequireInternalAPIKey(t *testing.T) {
+	service := newTestService(t)
+
+	status := service.request(http.MethodGet, "/wellness/metrics", nil, nil, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("GET /metrics without the internal API key status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	status = service.request(http.MethodGet, "/wellness/metrics", map[string]string{"INTERNAL-API-KEY": testInternalAPIKey}, nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestAdapterShutdownWhileStarting(t *testing.T) {
+	service := newTestService(t)
+
+	started := make(chan struct{})
+	go func() {
+		service.adapter.Start()
+		close(started)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := service.adapter.Shutdown(ctx)
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("Start() did not return after Shutdown()")
+	}
+}
+
+func TestRunDeleteDataRequiresSystemPermission(t *testing.T) {
+	service := newTestService(t)
+
+	for _, item := range []struct {
+		permission string
+		status     int
+	}{
+		{"all_admin_content", http.StatusForbidden},
+		{"all_system_content", http.StatusOK},
+	} {
+		var token map[string]string
+		service.internal(http.MethodPost, "/tokens", map[string]interface{}{"app_id": "app", "org_id": "org", "user_id": "admin",
+			"permissions": []string{item.permission}}, &token)
+		status := service.request(http.MethodPost, "/wellness/system/delete_data/run", map[string]string{"Authorization": "Bearer " + token["access_token"]}, nil, nil)
+		if status != item.status {
+			t.Errorf("POST /system/delete_data/run with %s status = %d, want %d", item.permission, status, item.status)
+		}
+	}
+}
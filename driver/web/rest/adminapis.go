@@ -15,10 +15,467 @@
 package rest
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
 	"wellness/core"
+	"wellness/core/model"
+
+	"github.com/gorilla/mux"
+	"github.com/rokwire/rokwire-building-block-sdk-go/services/core/auth/tokenauth"
 )
 
 // AdminApisHandler handles the rest Admin APIs implementation
 type AdminApisHandler struct {
 	app *core.Application
 }
+
+// GetDeletionAudits Retrieves the audits of the deleted users data processing
+// @Description Retrieves the audits of the deleted users data processing for the admin app/org. The newest come first.
+// @Tags Admin-DeletionAudits
+// @ID AdminGetDeletionAudits
+// @Param offset query string false "offset"
+// @Param limit query string false "limit - limit the result"
+// @Success 200 {array} model.DeletionAudit
+// @Security AdminUserAuth
+// @Router /admin/deletion_audits [get]
+func (h AdminApisHandler) GetDeletionAudits(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	offsetFilter := getInt64QueryParam(r, "offset")
+	limitFilter := getInt64QueryParam(r, "limit")
+
+	resData, err := h.app.Administration.GetDeletionAudits(claims.AppID, claims.OrgID, offsetFilter, limitFilter)
+	if err != nil {
+		log.Printf("Error on getting deletion audits - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.DeletionAudit{}
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		log.Printf("Error on marshal the deletion audits: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// RunDeleteData Triggers the deleted users data processing
+// @Description Triggers an immediate run of the deleted users data processing. The run happens in the background once the job lock
+// @Description is acquired - 409 is given when the job is running, or has just run, on this or another instance. The run covers
+// @Description all the apps/orgs, so it needs the system permission.
+// @Tags Admin-DeletionAudits
+// @ID AdminRunDeleteData
+// @Success 200
+// @Security AdminUserAuth
+// @Router /system/delete_data/run [post]
+func (h AdminApisHandler) RunDeleteData(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	err := h.app.Administration.RunDeleteData()
+	if err != nil {
+		log.Printf("Error on triggering the delete data processing - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// configRequestBody represents the settings of the app/org config
+type configRequestBody struct {
+	ReminderText         string          `json:"reminder_text" validate:"max=500"`
+	NotificationTitle    string          `json:"notification_title" validate:"max=100"`
+	BackfillWindowDays   int             `json:"backfill_window_days" validate:"gte=0"`
+	MaxRings             int             `json:"max_rings" validate:"gte=0"`
+	Features             map[string]bool `json:"features" validate:"dive,keys,oneof=rings ring_nudges digest,endkeys"`
+	Language             string          `json:"language" validate:"omitempty,bcp47_language_tag"`
+	Timezone             string          `json:"timezone" validate:"omitempty,timezone"`
+	RingNudgeTime        string          `json:"ring_nudge_time" validate:"omitempty,datetime=15:04"`
+	MaxRingNudgesPerWeek int             `json:"max_ring_nudges_per_week" validate:"gte=0"`
+	RollForwardRecurring bool            `json:"roll_forward_recurring"`
+} // @name configRequestBody
+
+func (b configRequestBody) toConfigSettings() model.ConfigSettings {
+	return model.ConfigSettings{ReminderText: b.ReminderText, NotificationTitle: b.NotificationTitle,
+		BackfillWindowDays: b.BackfillWindowDays, MaxRings: b.MaxRings, Features: b.Features, Language: b.Language, Timezone: b.Timezone,
+		RingNudgeTime: b.RingNudgeTime, MaxRingNudgesPerWeek: b.MaxRingNudgesPerWeek,
+		RollForwardRecurring: b.RollForwardRecurring}
+}
+
+// GetConfig Retrieves the config of the admin app/org
+// @Description Retrieves the wellness settings of the admin app/org
+// @Tags Admin-Config
+// @ID AdminGetConfig
+// @Success 200 {object} model.AppOrgConfig
+// @Security AdminUserAuth
+// @Router /admin/config [get]
+func (h AdminApisHandler) GetConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	config, err := h.app.Administration.GetAppOrgConfig(claims.AppID, claims.OrgID)
+	if err != nil {
+		log.Printf("Error on getting the config - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeConfig(w, r, http.StatusOK, config)
+}
+
+// CreateConfig Creates the config of the admin app/org
+// @Description Creates the wellness settings of the admin app/org. The empty settings fall back to the defaults.
+// @Tags Admin-Config
+// @ID AdminCreateConfig
+// @Accept json
+// @Param data body configRequestBody true "body json"
+// @Success 201 {object} model.AppOrgConfig
+// @Security AdminUserAuth
+// @Router /admin/config [post]
+func (h AdminApisHandler) CreateConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	settings, err := readConfigRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the config request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	config, err := h.app.Administration.CreateAppOrgConfig(claims.AppID, claims.OrgID, *settings)
+	if err != nil {
+		log.Printf("Error on creating the config - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeConfig(w, r, http.StatusCreated, config)
+}
+
+// UpdateConfig Updates the config of the admin app/org
+// @Description Replaces the wellness settings of the admin app/org. The change is applied by all the instances without a redeploy.
+// @Tags Admin-Config
+// @ID AdminUpdateConfig
+// @Accept json
+// @Param data body configRequestBody true "body json"
+// @Success 200 {object} model.AppOrgConfig
+// @Security AdminUserAuth
+// @Router /admin/config [put]
+func (h AdminApisHandler) UpdateConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	settings, err := readConfigRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the config request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	config, err := h.app.Administration.UpdateAppOrgConfig(claims.AppID, claims.OrgID, *settings)
+	if err != nil {
+		log.Printf("Error on updating the config - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeConfig(w, r, http.StatusOK, config)
+}
+
+// DeleteConfig Deletes the config of the admin app/org
+// @Description Deletes the wellness settings of the admin app/org, so the defaults are applied
+// @Tags Admin-Config
+// @ID AdminDeleteConfig
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/config [delete]
+func (h AdminApisHandler) DeleteConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	err := h.app.Administration.DeleteAppOrgConfig(claims.AppID, claims.OrgID)
+	if err != nil {
+		log.Printf("Error on deleting the config - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h AdminApisHandler) writeConfig(w http.ResponseWriter, r *http.Request, status int, config *model.AppOrgConfig) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		log.Printf("Error on marshal the config: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func readConfigRequestBody(r *http.Request) (*model.ConfigSettings, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, model.NewValidationError("the request body cannot be read")
+	}
+
+	var body configRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, model.NewValidationError("invalid request body - " + err.Error())
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := body.toConfigSettings()
+	return &settings, nil
+}
+
+// notificationTemplateRequestBody represents the notification template of a kind and a language - the todo reminders
+// when the kind is empty
+type notificationTemplateRequestBody struct {
+	Kind     string `json:"kind" validate:"omitempty,oneof=todo_reminder digest ring_nudge"`
+	Language string `json:"language" validate:"required,bcp47_language_tag"`
+	Subject  string `json:"subject" validate:"notblank,max=500"`
+	Body     string `json:"body" validate:"notblank,max=2000"`
+} // @name notificationTemplateRequestBody
+
+// GetNotificationTemplates Retrieves the notification templates of the admin app/org
+// @Description Retrieves the todo reminders templates of the admin app/org in all the languages
+// @Tags Admin-NotificationTemplates
+// @ID AdminGetNotificationTemplates
+// @Success 200 {array} model.NotificationTemplate
+// @Security AdminUserAuth
+// @Router /admin/notification_templates [get]
+func (h AdminApisHandler) GetNotificationTemplates(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Administration.GetNotificationTemplates(claims.AppID, claims.OrgID)
+	if err != nil {
+		log.Printf("Error on getting the notification templates - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.NotificationTemplate{}
+	}
+
+	h.writeNotificationTemplate(w, r, http.StatusOK, resData)
+}
+
+// CreateNotificationTemplate Creates a notification template of the admin app/org
+// @Description Creates the todo reminders template of the admin app/org in a language. The subject and the body are Go text/template
+// @Description templates with the Kind, Title, Description, Category, Location, Due, DueTime, Reminder and ReminderTime variables.
+// @Tags Admin-NotificationTemplates
+// @ID AdminCreateNotificationTemplate
+// @Accept json
+// @Param data body notificationTemplateRequestBody true "body json"
+// @Success 201 {object} model.NotificationTemplate
+// @Security AdminUserAuth
+// @Router /admin/notification_templates [post]
+func (h AdminApisHandler) CreateNotificationTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	item, err := readNotificationTemplateRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the notification template request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	created, err := h.app.Administration.CreateNotificationTemplate(claims.AppID, claims.OrgID, *item)
+	if err != nil {
+		log.Printf("Error on creating the notification template - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeNotificationTemplate(w, r, http.StatusCreated, created)
+}
+
+// UpdateNotificationTemplate Updates a notification template of the admin app/org
+// @Description Replaces the language, the subject and the body of a todo reminders template of the admin app/org
+// @Tags Admin-NotificationTemplates
+// @ID AdminUpdateNotificationTemplate
+// @Accept json
+// @Param id path string true "id"
+// @Param data body notificationTemplateRequestBody true "body json"
+// @Success 200 {object} model.NotificationTemplate
+// @Security AdminUserAuth
+// @Router /admin/notification_templates/{id} [put]
+func (h AdminApisHandler) UpdateNotificationTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	item, err := readNotificationTemplateRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the notification template request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	updated, err := h.app.Administration.UpdateNotificationTemplate(claims.AppID, claims.OrgID, id, *item)
+	if err != nil {
+		log.Printf("Error on updating the notification template %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeNotificationTemplate(w, r, http.StatusOK, updated)
+}
+
+// DeleteNotificationTemplate Deletes a notification template of the admin app/org
+// @Description Deletes a todo reminders template of the admin app/org
+// @Tags Admin-NotificationTemplates
+// @ID AdminDeleteNotificationTemplate
+// @Param id path string true "id"
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/notification_templates/{id} [delete]
+func (h AdminApisHandler) DeleteNotificationTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	err := h.app.Administration.DeleteNotificationTemplate(claims.AppID, claims.OrgID, id)
+	if err != nil {
+		log.Printf("Error on deleting the notification template %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h AdminApisHandler) writeNotificationTemplate(w http.ResponseWriter, r *http.Request, status int, resData interface{}) {
+	data, err := json.Marshal(resData)
+	if err != nil {
+		log.Printf("Error on marshal the notification template: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func readNotificationTemplateRequestBody(r *http.Request) (*model.NotificationTemplate, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, model.NewValidationError("the request body cannot be read")
+	}
+
+	var body notificationTemplateRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, model.NewValidationError("invalid request body - " + err.Error())
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NotificationTemplate{Kind: body.Kind, Language: body.Language, Subject: body.Subject, Body: body.Body}, nil
+}
+
+// GetTodoTemplates Retrieves the todo templates of the admin app/org
+// @Description Retrieves the todo templates published for all the users of the admin app/org
+// @Tags Admin-TodoTemplates
+// @ID AdminGetTodoTemplates
+// @Success 200 {array} model.TodoTemplate
+// @Security AdminUserAuth
+// @Router /admin/todo_templates [get]
+func (h AdminApisHandler) GetTodoTemplates(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Administration.GetTodoTemplates(claims.AppID, claims.OrgID)
+	if err != nil {
+		log.Printf("Error on getting the todo templates - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.TodoTemplate{}
+	}
+
+	writeTodoTemplates(w, r, http.StatusOK, resData)
+}
+
+// CreateTodoTemplate Creates a todo template of the admin app/org
+// @Description Publishes a todo template for all the users of the admin app/org. The templates of the app/org have no category,
+// @Description `category_id` is ignored.
+// @Tags Admin-TodoTemplates
+// @ID AdminCreateTodoTemplate
+// @Accept json
+// @Param data body todoTemplateRequestBody true "body json"
+// @Success 201 {object} model.TodoTemplate
+// @Security AdminUserAuth
+// @Router /admin/todo_templates [post]
+func (h AdminApisHandler) CreateTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	item, err := readTodoTemplateRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the todo template request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	created, err := h.app.Administration.CreateTodoTemplate(claims.AppID, claims.OrgID, *item)
+	if err != nil {
+		log.Printf("Error on creating the todo template - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	writeTodoTemplates(w, r, http.StatusCreated, created)
+}
+
+// UpdateTodoTemplate Updates a todo template of the admin app/org
+// @Description Replaces a todo template of the admin app/org
+// @Tags Admin-TodoTemplates
+// @ID AdminUpdateTodoTemplate
+// @Accept json
+// @Param id path string true "id"
+// @Param data body todoTemplateRequestBody true "body json"
+// @Success 200 {object} model.TodoTemplate
+// @Security AdminUserAuth
+// @Router /admin/todo_templates/{id} [put]
+func (h AdminApisHandler) UpdateTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	item, err := readTodoTemplateRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the todo template request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	updated, err := h.app.Administration.UpdateTodoTemplate(claims.AppID, claims.OrgID, id, *item)
+	if err != nil {
+		log.Printf("Error on updating the todo template %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	writeTodoTemplates(w, r, http.StatusOK, updated)
+}
+
+// DeleteTodoTemplate Deletes a todo template of the admin app/org
+// @Description Deletes a todo template of the admin app/org. The todo entries created from it are kept.
+// @Tags Admin-TodoTemplates
+// @ID AdminDeleteTodoTemplate
+// @Param id path string true "id"
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/todo_templates/{id} [delete]
+func (h AdminApisHandler) DeleteTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	err := h.app.Administration.DeleteTodoTemplate(claims.AppID, claims.OrgID, id)
+	if err != nil {
+		log.Printf("Error on deleting the todo template %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
@@ -47,6 +47,46 @@ func (h ApisHandler) Version(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(h.app.Services.GetVersion()))
 }
 
+// Health gives the liveness of the service
+// @Description Gives the liveness of the service. It does not check the dependencies.
+// @Tags Client
+// @ID Health
+// @Produce json
+// @Success 200
+// @Router /health [get]
+func (h ApisHandler) Health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"` + model.HealthStatusUp + `"}`))
+}
+
+// Ready gives the readiness of the service
+// @Description Gives the readiness of the service with the status of every dependency. It responds with 503 when a dependency does not work.
+// @Tags Client
+// @ID Ready
+// @Produce json
+// @Success 200 {object} model.Readiness
+// @Failure 503 {object} model.Readiness
+// @Router /ready [get]
+func (h ApisHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	readiness := h.app.Services.GetReadiness()
+
+	data, err := json.Marshal(readiness)
+	if err != nil {
+		log.Printf("Error on marshal the readiness: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if readiness.Status != model.HealthStatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
 // GetUserTodoCategories Retrieves all user todo categories
 // @Description Retrieves all user todo categories
 // @Tags Client-TodoCategories
@@ -60,7 +100,7 @@ func (h ApisHandler) GetUserTodoCategories(claims *tokenauth.Claims, w http.Resp
 	resData, err := h.app.Services.GetTodoCategories(claims.AppID, claims.OrgID, claims.Subject)
 	if err != nil {
 		log.Printf("Error on getting user todo categories - %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -71,7 +111,7 @@ func (h ApisHandler) GetUserTodoCategories(claims *tokenauth.Claims, w http.Resp
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal all user todo categories: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -96,14 +136,14 @@ func (h ApisHandler) GetUserTodoCategory(claims *tokenauth.Claims, w http.Respon
 	resData, err := h.app.Services.GetTodoCategory(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on getting user todo category by id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal user todo category: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -112,13 +152,28 @@ func (h ApisHandler) GetUserTodoCategory(claims *tokenauth.Claims, w http.Respon
 	w.Write(data)
 }
 
+// todoCategoryRequestBody is the data of a todo category sent by the clients
+type todoCategoryRequestBody struct {
+	ID    string `json:"id"`
+	Name  string `json:"name" validate:"notblank"`
+	Color string `json:"color" validate:"omitempty,hexcolor"`
+} // @name todoCategoryRequestBody
+
+func newTodoCategoryRequestBody(category model.TodoCategory) todoCategoryRequestBody {
+	return todoCategoryRequestBody{ID: category.ID, Name: category.Name, Color: category.Color}
+}
+
+func (b todoCategoryRequestBody) toTodoCategory() *model.TodoCategory {
+	return &model.TodoCategory{ID: b.ID, Name: b.Name, Color: b.Color}
+}
+
 // UpdateUserTodoCategory Updates a user todo category with the specified id
 // @Description Updates a user todo category with the specified id
 // @Tags Client-TodoCategories
 // @ID UpdateUserTodoCategory
 // @Accept json
 // @Produce json
-// @Param data body model.TodoCategory true "body json"
+// @Param data body todoCategoryRequestBody true "body json"
 // @Success 200 {object} model.TodoCategory
 // @Security UserAuth
 // @Router /api/user/todo_categories/{id} [put]
@@ -129,35 +184,92 @@ func (h ApisHandler) UpdateUserTodoCategory(claims *tokenauth.Claims, w http.Res
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user todo category - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
-	var item model.TodoCategory
+	var item todoCategoryRequestBody
 	err = json.Unmarshal(data, &item)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user todo category request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(item)
+	if err != nil {
+		log.Printf("Error on validating the user todo category request data - %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
 	if id != item.ID {
 		log.Printf("Inconsistent attempt to update query param  and json id are not equal")
-		http.Error(w, "Inconsistent attempt to update query param  and json id are not equal", http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the id in the path and the id in the body are not equal"))
 		return
 	}
 
-	resData, err := h.app.Services.UpdateTodoCategory(claims.AppID, claims.OrgID, claims.Subject, &item)
+	resData, err := h.app.Services.UpdateTodoCategory(claims.AppID, claims.OrgID, claims.Subject, item.toTodoCategory())
 	if err != nil {
 		log.Printf("Error on updating user todo category with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal the updated user todo category: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// PatchUserTodoCategory Partially updates a user todo category with the specified id
+// @Description Partially updates a user todo category with the specified id. The body is a JSON merge patch (RFC 7396) - the fields which are not in it are kept.
+// @Tags Client-TodoCategories
+// @ID PatchUserTodoCategory
+// @Accept json
+// @Produce json
+// @Param data body todoCategoryRequestBody true "body json"
+// @Success 200 {object} model.TodoCategory
+// @Security UserAuth
+// @Router /api/user/todo_categories/{id} [patch]
+func (h ApisHandler) PatchUserTodoCategory(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the patch of a user todo category - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	resData, err := h.app.Services.PatchTodoCategory(claims.AppID, claims.OrgID, claims.Subject, id, func(current model.TodoCategory) (*model.TodoCategory, error) {
+		var item todoCategoryRequestBody
+		err := applyMergePatch(newTodoCategoryRequestBody(current), patch, &item)
+		if err != nil {
+			return nil, err
+		}
+		if item.ID != id {
+			return nil, model.NewValidationError("the id of a todo category cannot be changed")
+		}
+		return item.toTodoCategory(), nil
+	})
+	if err != nil {
+		log.Printf("Error on patching user todo category with id - %s\n %s", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(resData)
+	if err != nil {
+		log.Printf("Error on marshal the patched user todo category: %s", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -170,7 +282,7 @@ func (h ApisHandler) UpdateUserTodoCategory(claims *tokenauth.Claims, w http.Res
 // @Description Creates a user todo category
 // @Tags Client-TodoCategories
 // @ID CreateUserTodoCategory
-// @Param data body model.TodoCategory true "body json"
+// @Param data body todoCategoryRequestBody true "body json"
 // @Success 200 {object} model.TodoCategory
 // @Security UserAuth
 // @Router /api/user/todo_categories [post]
@@ -179,29 +291,36 @@ func (h ApisHandler) CreateUserTodoCategory(claims *tokenauth.Claims, w http.Res
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user todo category - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
-	var item model.TodoCategory
+	var item todoCategoryRequestBody
 	err = json.Unmarshal(data, &item)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user todo category request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
 		return
 	}
 
-	createdItem, err := h.app.Services.CreateTodoCategory(claims.AppID, claims.OrgID, claims.Subject, &item)
+	err = validateRequestBody(item)
+	if err != nil {
+		log.Printf("Error on validating the user todo category request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	createdItem, err := h.app.Services.CreateTodoCategory(claims.AppID, claims.OrgID, claims.Subject, item.toTodoCategory())
 	if err != nil {
 		log.Printf("Error on creating user todo category: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
 		log.Printf("Error on marshal the new user todo category: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -224,7 +343,7 @@ func (h ApisHandler) DeleteUserTodoCategory(claims *tokenauth.Claims, w http.Res
 	err := h.app.Services.DeleteTodoCategory(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on deleting user todo category with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -245,7 +364,7 @@ func (h ApisHandler) GetUserTodoEntries(claims *tokenauth.Claims, w http.Respons
 	resData, err := h.app.Services.GetTodoEntries(claims.AppID, claims.OrgID, claims.Subject)
 	if err != nil {
 		log.Printf("Error on getting user todo entries - %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -256,7 +375,35 @@ func (h ApisHandler) GetUserTodoEntries(claims *tokenauth.Claims, w http.Respons
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal all user todo entries: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetUserOverdueTodoEntries Retrieves the number of the overdue user todo entries
+// @Description Counts the todo entries which are not completed and whose due time has passed - the entries without a due time
+// @Description are overdue once their due day is over. The entries are also counted by category.
+// @Tags Client-TodoEntries
+// @ID GetUserOverdueTodoEntries
+// @Success 200 {object} model.OverdueSummary
+// @Security UserAuth
+// @Router /api/user/todo_entries/overdue [get]
+func (h ApisHandler) GetUserOverdueTodoEntries(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	summary, err := h.app.Services.GetOverdueSummary(claims.AppID, claims.OrgID, claims.Subject)
+	if err != nil {
+		log.Printf("Error on counting the overdue user todo entries - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		log.Printf("Error on marshal the overdue user todo entries: %s", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -280,20 +427,14 @@ func (h ApisHandler) GetUserTodoEntry(claims *tokenauth.Claims, w http.ResponseW
 	resData, err := h.app.Services.GetTodoEntry(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on getting user todo entry by id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if resData == nil {
-		log.Printf("Error on getting user todo entry by id - %s (Not found)", id)
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		WriteError(w, r, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal user todo entry: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -302,13 +443,42 @@ func (h ApisHandler) GetUserTodoEntry(claims *tokenauth.Claims, w http.ResponseW
 	w.Write(data)
 }
 
+// todoEntryRequestBody is the data of a todo entry sent by the clients. The reminders messages are managed by the service.
+type todoEntryRequestBody struct {
+	ID               string             `json:"id"`
+	Title            string             `json:"title" validate:"notblank"`
+	Description      string             `json:"description"`
+	Category         *model.CategoryRef `json:"category"`
+	WorkDays         []string           `json:"work_days" validate:"dive,datetime=2006-01-02"`
+	Location         *string            `json:"location"`
+	Completed        bool               `json:"completed"`
+	HasDueTime       bool               `json:"has_due_time"`
+	DueDateTime      *time.Time         `json:"due_date_time"`
+	ReminderType     string             `json:"reminder_type"`
+	ReminderDateTime *time.Time         `json:"reminder_date_time"`
+	Recurrence       string             `json:"recurrence" validate:"omitempty,oneof=daily weekly monthly"`
+	TaskTime         *time.Time         `json:"task_time"`
+} // @name todoEntryRequestBody
+
+func newTodoEntryRequestBody(todo model.TodoEntry) todoEntryRequestBody {
+	return todoEntryRequestBody{ID: todo.ID, Title: todo.Title, Description: todo.Description, Category: todo.Category, WorkDays: todo.WorkDays,
+		Location: todo.Location, Completed: todo.Completed, HasDueTime: todo.HasDueTime, DueDateTime: todo.DueDateTime, ReminderType: todo.ReminderType,
+		ReminderDateTime: todo.ReminderDateTime, Recurrence: todo.Recurrence, TaskTime: todo.TaskTime}
+}
+
+func (b todoEntryRequestBody) toTodoEntry() *model.TodoEntry {
+	return &model.TodoEntry{ID: b.ID, Title: b.Title, Description: b.Description, Category: b.Category, WorkDays: b.WorkDays,
+		Location: b.Location, Completed: b.Completed, HasDueTime: b.HasDueTime, DueDateTime: b.DueDateTime, ReminderType: b.ReminderType,
+		ReminderDateTime: b.ReminderDateTime, Recurrence: b.Recurrence, TaskTime: b.TaskTime}
+}
+
 // UpdateUserTodoEntry Updates a user todo entry with the specified id
 // @Description Updates a user todo entry with the specified id
 // @Tags Client-TodoEntries
 // @ID UpdateUserTodoEntry
 // @Accept json
 // @Produce json
-// @Param data body model.TodoEntry true "body json"
+// @Param data body todoEntryRequestBody true "body json"
 // @Success 200 {object} model.TodoEntry
 // @Security UserAuth
 // @Router /api/user/todo_entries/{id} [put]
@@ -318,35 +488,92 @@ func (h ApisHandler) UpdateUserTodoEntry(claims *tokenauth.Claims, w http.Respon
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user todo entry - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
-	var item model.TodoEntry
+	var item todoEntryRequestBody
 	err = json.Unmarshal(data, &item)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user todo entry request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(item)
+	if err != nil {
+		log.Printf("Error on validating the user todo entry request data - %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
 	if id != item.ID {
 		log.Printf("Inconsistent attempt to update todo entry - query param  and json id are not equal")
-		http.Error(w, "Inconsistent attempt to update todo entry - query param  and json id are not equal", http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the id in the path and the id in the body are not equal"))
 		return
 	}
 
-	resData, err := h.app.Services.UpdateTodoEntry(claims.AppID, claims.OrgID, claims.Subject, &item, id)
+	resData, err := h.app.Services.UpdateTodoEntry(claims.AppID, claims.OrgID, claims.Subject, item.toTodoEntry(), id)
 	if err != nil {
 		log.Printf("Error on updating user todo entry with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal the updated user todo entry: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// PatchUserTodoEntry Partially updates a user todo entry with the specified id
+// @Description Partially updates a user todo entry with the specified id. The body is a JSON merge patch (RFC 7396) - the fields which are not in it are kept.
+// @Tags Client-TodoEntries
+// @ID PatchUserTodoEntry
+// @Accept json
+// @Produce json
+// @Param data body todoEntryRequestBody true "body json"
+// @Success 200 {object} model.TodoEntry
+// @Security UserAuth
+// @Router /api/user/todo_entries/{id} [patch]
+func (h ApisHandler) PatchUserTodoEntry(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the patch of a user todo entry - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	resData, err := h.app.Services.PatchTodoEntry(claims.AppID, claims.OrgID, claims.Subject, id, func(current model.TodoEntry) (*model.TodoEntry, error) {
+		var item todoEntryRequestBody
+		err := applyMergePatch(newTodoEntryRequestBody(current), patch, &item)
+		if err != nil {
+			return nil, err
+		}
+		if item.ID != id {
+			return nil, model.NewValidationError("the id of a todo entry cannot be changed")
+		}
+		return item.toTodoEntry(), nil
+	})
+	if err != nil {
+		log.Printf("Error on patching user todo entry with id - %s\n %s", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(resData)
+	if err != nil {
+		log.Printf("Error on marshal the patched user todo entry: %s", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -361,7 +588,7 @@ func (h ApisHandler) UpdateUserTodoEntry(claims *tokenauth.Claims, w http.Respon
 // @ID CreateUserTodoEntry
 // @Accept json
 // @Produce json
-// @Param data body model.TodoEntry true "body json"
+// @Param data body todoEntryRequestBody true "body json"
 // @Success 200 {object} model.TodoEntry
 // @Security UserAuth
 // @Router /api/user/todo_entries [post]
@@ -370,29 +597,272 @@ func (h ApisHandler) CreateUserTodoEntry(claims *tokenauth.Claims, w http.Respon
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user todo entry - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
-	var item model.TodoEntry
+	var item todoEntryRequestBody
 	err = json.Unmarshal(data, &item)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user todo entry request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
 		return
 	}
 
-	createdItem, err := h.app.Services.CreateTodoEntry(claims.AppID, claims.OrgID, claims.Subject, &item)
+	err = validateRequestBody(item)
+	if err != nil {
+		log.Printf("Error on validating the user todo entry request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	createdItem, err := h.app.Services.CreateTodoEntry(claims.AppID, claims.OrgID, claims.Subject, item.toTodoEntry())
 	if err != nil {
 		log.Printf("Error on creating user todo entry: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
 		log.Printf("Error on marshal the new user todo entry: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// positionRequestBody moves an item of a manually ordered list right after another item - to the top when after_id is empty
+type positionRequestBody struct {
+	AfterID *string `json:"after_id"`
+} // @name positionRequestBody
+
+// MoveUserTodoEntry Moves a user todo entry in the manual order
+// @Description Moves a todo entry right after the entry with after_id - to the top of the list when after_id is empty. Only the
+// @Description moved entry gets a new position, the entries are listed by their positions.
+// @Tags Client-TodoEntries
+// @ID MoveUserTodoEntry
+// @Accept json
+// @Param id path string true "id"
+// @Param data body positionRequestBody true "body json"
+// @Success 200 {object} model.TodoEntry
+// @Security UserAuth
+// @Router /api/user/todo_entries/{id}/position [put]
+func (h ApisHandler) MoveUserTodoEntry(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	body, err := readPositionRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the todo entry position - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	todo, err := h.app.Services.MoveTodoEntry(claims.AppID, claims.OrgID, claims.Subject, id, body.AfterID)
+	if err != nil {
+		log.Printf("Error on moving the user todo entry %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(todo)
+	if err != nil {
+		log.Printf("Error on marshal the user todo entry: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// MoveUserTodoCategory Moves a user todo category in the manual order
+// @Description Moves a todo category right after the category with after_id - to the top of the list when after_id is empty. Only the
+// @Description moved category gets a new position, the categories are listed by their positions.
+// @Tags Client-TodoCategories
+// @ID MoveUserTodoCategory
+// @Accept json
+// @Param id path string true "id"
+// @Param data body positionRequestBody true "body json"
+// @Success 200 {object} model.TodoCategory
+// @Security UserAuth
+// @Router /api/user/todo_categories/{id}/position [put]
+func (h ApisHandler) MoveUserTodoCategory(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	body, err := readPositionRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the todo category position - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	category, err := h.app.Services.MoveTodoCategory(claims.AppID, claims.OrgID, claims.Subject, id, body.AfterID)
+	if err != nil {
+		log.Printf("Error on moving the user todo category %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(category)
+	if err != nil {
+		log.Printf("Error on marshal the user todo category: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+func readPositionRequestBody(r *http.Request) (*positionRequestBody, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, model.NewValidationError("the request body cannot be read")
+	}
+
+	var body positionRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, model.NewValidationError("invalid request body - " + err.Error())
+	}
+	if body.AfterID != nil && *body.AfterID == "" {
+		body.AfterID = nil
+	}
+	return &body, nil
+}
+
+// bulkTodoEntriesRequestBody applies an action to many todo entries
+type bulkTodoEntriesRequestBody struct {
+	Action           string     `json:"action" validate:"required,oneof=complete uncomplete delete move reschedule"`
+	IDs              []string   `json:"ids" validate:"required,min=1,max=100,dive,notblank"`
+	CategoryID       *string    `json:"category_id"`
+	DueDateTime      *time.Time `json:"due_date_time"`
+	ReminderDateTime *time.Time `json:"reminder_date_time"`
+} // @name bulkTodoEntriesRequestBody
+
+func (b bulkTodoEntriesRequestBody) toBulkTodoOperation() model.BulkTodoOperation {
+	return model.BulkTodoOperation{Action: b.Action, IDs: b.IDs, CategoryID: b.CategoryID, DueDateTime: b.DueDateTime,
+		ReminderDateTime: b.ReminderDateTime}
+}
+
+// BulkUserTodoEntries Applies an action to many user todo entries
+// @Description Completes, uncompletes, deletes, moves to a category (`category_id`, no category when empty) or reschedules (`due_date_time`
+// @Description and optionally `reminder_date_time`, otherwise the reminders keep their offset to the due time) up to 100 todo entries at once.
+// @Description All the changes are applied in one transaction and the result of every entry is given - `ok`, `not_found` or `invalid`.
+// @Tags Client-TodoEntries
+// @ID BulkUserTodoEntries
+// @Accept json
+// @Param data body bulkTodoEntriesRequestBody true "body json"
+// @Success 200 {array} model.BulkTodoResult
+// @Security UserAuth
+// @Router /api/user/todo_entries/bulk [post]
+func (h ApisHandler) BulkUserTodoEntries(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the bulk todo entries - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	var body bulkTodoEntriesRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		log.Printf("Error on unmarshal the bulk todo entries request data - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		log.Printf("Error on validating the bulk todo entries request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	results, err := h.app.Services.BulkTodoEntries(claims.AppID, claims.OrgID, claims.Subject, body.toBulkTodoOperation())
+	if err != nil {
+		log.Printf("Error on applying %s to the user todo entries - %s\n", body.Action, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		log.Printf("Error on marshal the bulk todo entries results: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// snoozeRequestBody moves the reminder of a todo entry by a number of minutes from now or to a time
+type snoozeRequestBody struct {
+	Minutes int        `json:"minutes" validate:"required_without=Until,excluded_with=Until,omitempty,gt=0,lte=10080"`
+	Until   *time.Time `json:"until"`
+} // @name snoozeRequestBody
+
+// SnoozeUserTodoEntry Snoozes the reminder of a user todo entry
+// @Description Moves the reminder of a todo entry by a number of minutes from now or to a time - the current reminder message is
+// @Description replaced and the snooze is counted on the entry. The reminder notifications carry the id of the entry in entity_id.
+// @Description The entries without a reminder before the due time cannot be snoozed.
+// @Tags Client-TodoEntries
+// @ID SnoozeUserTodoEntry
+// @Accept json
+// @Param id path string true "id"
+// @Param data body snoozeRequestBody true "body json"
+// @Success 200 {object} model.TodoEntry
+// @Security UserAuth
+// @Router /api/user/todo_entries/{id}/snooze [post]
+func (h ApisHandler) SnoozeUserTodoEntry(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the snooze - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	var body snoozeRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		log.Printf("Error on unmarshal the snooze request data - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		log.Printf("Error on validating the snooze request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	until := time.Now().Add(time.Duration(body.Minutes) * time.Minute).UTC()
+	if body.Until != nil {
+		until = body.Until.UTC()
+	}
+
+	todo, err := h.app.Services.SnoozeTodoEntry(claims.AppID, claims.OrgID, claims.Subject, id, until)
+	if err != nil {
+		log.Printf("Error on snoozing the user todo entry %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(todo)
+	if err != nil {
+		log.Printf("Error on marshal the user todo entry: %s", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -415,7 +885,7 @@ func (h ApisHandler) DeleteUserTodoEntry(claims *tokenauth.Claims, w http.Respon
 	err := h.app.Services.DeleteTodoEntry(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on deleting user todo entry with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -437,7 +907,145 @@ func (h ApisHandler) DeleteCompletedUserTodoEntry(claims *tokenauth.Claims, w ht
 	err := h.app.Services.DeleteCompletedTodoEntries(claims.AppID, claims.OrgID, claims.Subject)
 	if err != nil {
 		log.Printf("Error on deleting user todo entry with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// todoTemplateRequestBody is the data of a todo template. The reminder offsets are minutes and the work day offsets are days
+// before the due time given when the template is used.
+type todoTemplateRequestBody struct {
+	Name            string   `json:"name" validate:"notblank,max=100"`
+	Title           string   `json:"title" validate:"notblank"`
+	Description     string   `json:"description"`
+	CategoryID      *string  `json:"category_id"`
+	Subtasks        []string `json:"subtasks" validate:"max=20,dive,notblank"`
+	Location        *string  `json:"location"`
+	ReminderType    string   `json:"reminder_type"`
+	ReminderOffsets []int    `json:"reminder_offsets" validate:"max=5,dive,gte=0,lte=10080"`
+	WorkDayOffsets  []int    `json:"work_day_offsets" validate:"max=14,dive,gte=0,lte=365"`
+	Recurrence      string   `json:"recurrence" validate:"omitempty,oneof=daily weekly monthly"`
+} // @name todoTemplateRequestBody
+
+func (b todoTemplateRequestBody) toTodoTemplate() model.TodoTemplate {
+	var category *model.CategoryRef
+	if b.CategoryID != nil && *b.CategoryID != "" {
+		category = &model.CategoryRef{ID: *b.CategoryID}
+	}
+	return model.TodoTemplate{Name: b.Name, Title: b.Title, Description: b.Description, Category: category, Subtasks: b.Subtasks,
+		Location: b.Location, ReminderType: b.ReminderType, ReminderOffsets: b.ReminderOffsets, WorkDayOffsets: b.WorkDayOffsets,
+		Recurrence: b.Recurrence}
+}
+
+// todoTemplateNameRequestBody names the template saved from a todo entry - the title of the entry when empty
+type todoTemplateNameRequestBody struct {
+	Name string `json:"name" validate:"max=100"`
+} // @name todoTemplateNameRequestBody
+
+// instantiateTodoTemplateRequestBody gives the due time and optionally the category of the todo entries created from a template
+type instantiateTodoTemplateRequestBody struct {
+	DueDateTime *time.Time `json:"due_date_time"`
+	HasDueTime  bool       `json:"has_due_time"`
+	CategoryID  *string    `json:"category_id"`
+} // @name instantiateTodoTemplateRequestBody
+
+// GetUserTodoTemplates Retrieves the todo templates of the user and of the app/org
+// @Description Retrieves the todo templates of the user and the ones published by the admins for the app/org - the latter have an empty user_id
+// @Tags Client-TodoTemplates
+// @ID GetUserTodoTemplates
+// @Success 200 {array} model.TodoTemplate
+// @Security UserAuth
+// @Router /api/user/todo_templates [get]
+func (h ApisHandler) GetUserTodoTemplates(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.GetTodoTemplates(claims.AppID, claims.OrgID, claims.Subject)
+	if err != nil {
+		log.Printf("Error on getting the user todo templates - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.TodoTemplate{}
+	}
+
+	writeTodoTemplates(w, r, http.StatusOK, resData)
+}
+
+// CreateUserTodoTemplate Creates a user todo template
+// @Description Creates a todo template of the user with an optional category of the user and the titles of its subtasks
+// @Tags Client-TodoTemplates
+// @ID CreateUserTodoTemplate
+// @Accept json
+// @Param data body todoTemplateRequestBody true "body json"
+// @Success 201 {object} model.TodoTemplate
+// @Security UserAuth
+// @Router /api/user/todo_templates [post]
+func (h ApisHandler) CreateUserTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	item, err := readTodoTemplateRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the todo template request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	created, err := h.app.Services.CreateTodoTemplate(claims.AppID, claims.OrgID, claims.Subject, *item)
+	if err != nil {
+		log.Printf("Error on creating the user todo template - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	writeTodoTemplates(w, r, http.StatusCreated, created)
+}
+
+// UpdateUserTodoTemplate Updates a user todo template
+// @Description Replaces a todo template of the user. The templates of the app/org cannot be changed by the users.
+// @Tags Client-TodoTemplates
+// @ID UpdateUserTodoTemplate
+// @Accept json
+// @Param id path string true "id"
+// @Param data body todoTemplateRequestBody true "body json"
+// @Success 200 {object} model.TodoTemplate
+// @Security UserAuth
+// @Router /api/user/todo_templates/{id} [put]
+func (h ApisHandler) UpdateUserTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	item, err := readTodoTemplateRequestBody(r)
+	if err != nil {
+		log.Printf("Error on reading the todo template request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	updated, err := h.app.Services.UpdateTodoTemplate(claims.AppID, claims.OrgID, claims.Subject, id, *item)
+	if err != nil {
+		log.Printf("Error on updating the user todo template %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	writeTodoTemplates(w, r, http.StatusOK, updated)
+}
+
+// DeleteUserTodoTemplate Deletes a user todo template
+// @Description Deletes a todo template of the user. The templates of the app/org cannot be deleted by the users.
+// @Tags Client-TodoTemplates
+// @ID DeleteUserTodoTemplate
+// @Param id path string true "id"
+// @Success 200
+// @Security UserAuth
+// @Router /api/user/todo_templates/{id} [delete]
+func (h ApisHandler) DeleteUserTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	err := h.app.Services.DeleteTodoTemplate(claims.AppID, claims.OrgID, claims.Subject, id)
+	if err != nil {
+		log.Printf("Error on deleting the user todo template %s - %s\n", id, err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -445,6 +1053,140 @@ func (h ApisHandler) DeleteCompletedUserTodoEntry(claims *tokenauth.Claims, w ht
 	w.WriteHeader(http.StatusOK)
 }
 
+// SaveUserTodoEntryAsTemplate Saves a user todo entry as a todo template
+// @Description Creates a todo template of the user from a todo entry. The reminder and the work days of the entry are kept as
+// @Description offsets to its due time.
+// @Tags Client-TodoTemplates
+// @ID SaveUserTodoEntryAsTemplate
+// @Accept json
+// @Param id path string true "id"
+// @Param data body todoTemplateNameRequestBody true "body json"
+// @Success 201 {object} model.TodoTemplate
+// @Security UserAuth
+// @Router /api/user/todo_entries/{id}/template [post]
+func (h ApisHandler) SaveUserTodoEntryAsTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the todo template name - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	var body todoTemplateNameRequestBody
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &body)
+		if err != nil {
+			log.Printf("Error on unmarshal the todo template name request data - %s\n", err.Error())
+			WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+			return
+		}
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		log.Printf("Error on validating the todo template name request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	created, err := h.app.Services.SaveTodoEntryAsTemplate(claims.AppID, claims.OrgID, claims.Subject, id, body.Name)
+	if err != nil {
+		log.Printf("Error on saving the user todo entry %s as a template - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	writeTodoTemplates(w, r, http.StatusCreated, created)
+}
+
+// InstantiateUserTodoTemplate Creates the todo entries of a todo template
+// @Description Creates the todo entry of a template of the user or of the app/org and an entry for every subtask, all due at
+// @Description `due_date_time`. The first reminder offset gives the reminder and the work day offsets give the work days of the
+// @Description entry. The category of the template is replaced by `category_id` - no category when empty.
+// @Tags Client-TodoTemplates
+// @ID InstantiateUserTodoTemplate
+// @Accept json
+// @Param id path string true "id"
+// @Param data body instantiateTodoTemplateRequestBody true "body json"
+// @Success 201 {array} model.TodoEntry
+// @Security UserAuth
+// @Router /api/user/todo_templates/{id}/instantiate [post]
+func (h ApisHandler) InstantiateUserTodoTemplate(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the todo template instance - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	var body instantiateTodoTemplateRequestBody
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &body)
+		if err != nil {
+			log.Printf("Error on unmarshal the todo template instance request data - %s\n", err.Error())
+			WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+			return
+		}
+	}
+
+	instance := model.TodoTemplateInstance{DueDateTime: body.DueDateTime, HasDueTime: body.HasDueTime, CategoryID: body.CategoryID}
+	entries, err := h.app.Services.InstantiateTodoTemplate(claims.AppID, claims.OrgID, claims.Subject, id, instance)
+	if err != nil {
+		log.Printf("Error on instantiating the user todo template %s - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(entries)
+	if err != nil {
+		log.Printf("Error on marshal the todo entries of the template: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonData)
+}
+
+func writeTodoTemplates(w http.ResponseWriter, r *http.Request, status int, resData interface{}) {
+	data, err := json.Marshal(resData)
+	if err != nil {
+		log.Printf("Error on marshal the todo template: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func readTodoTemplateRequestBody(r *http.Request) (*model.TodoTemplate, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, model.NewValidationError("the request body cannot be read")
+	}
+
+	var body todoTemplateRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, model.NewValidationError("invalid request body - " + err.Error())
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	item := body.toTodoTemplate()
+	return &item, nil
+}
+
 // GetUserRings Retrieves all user wellness ring entries
 // @Description Retrieves all user wellness ring entries
 // @Tags Client-Rings
@@ -458,7 +1200,7 @@ func (h ApisHandler) GetUserRings(claims *tokenauth.Claims, w http.ResponseWrite
 	resData, err := h.app.Services.GetRings(claims.AppID, claims.OrgID, claims.Subject)
 	if err != nil {
 		log.Printf("Error on getting user wellness ring entries - %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -469,7 +1211,7 @@ func (h ApisHandler) GetUserRings(claims *tokenauth.Claims, w http.ResponseWrite
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal all wellness ring todo entries: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -495,20 +1237,14 @@ func (h ApisHandler) GetUserRing(claims *tokenauth.Claims, w http.ResponseWriter
 	resData, err := h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on getting user wellness ring entry by id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if resData == nil {
-		log.Printf("Error on getting user wellness ring entry by id - %s (Not found)", id)
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		WriteError(w, r, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal user wellness ring entry: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -519,10 +1255,10 @@ func (h ApisHandler) GetUserRing(claims *tokenauth.Claims, w http.ResponseWriter
 
 // createUserRingRequestBody represents request body data which is required for the initial state
 type createUserRingRequestBody struct {
-	Color string  `json:"color_hex" bson:"color_hex"`
-	Name  string  `json:"name" bson:"name"`
+	Color string  `json:"color_hex" bson:"color_hex" validate:"required,hexcolor"`
+	Name  string  `json:"name" bson:"name" validate:"notblank"`
 	Unit  string  `json:"unit" bson:"unit"`
-	Value float64 `json:"value" bson:"value"`
+	Value float64 `json:"value" bson:"value" validate:"gt=0"`
 } // @name createUserRingRequestBody
 
 // CreateUserRing Creates a user wellness ring entry
@@ -539,7 +1275,7 @@ func (h ApisHandler) CreateUserRing(claims *tokenauth.Claims, w http.ResponseWri
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user wellness ring entry - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
@@ -547,7 +1283,14 @@ func (h ApisHandler) CreateUserRing(claims *tokenauth.Claims, w http.ResponseWri
 	err = json.Unmarshal(data, &historyEntry)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user wellness ring entry request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(historyEntry)
+	if err != nil {
+		log.Printf("Error on validating the user wellness ring entry request data - %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -563,14 +1306,14 @@ func (h ApisHandler) CreateUserRing(claims *tokenauth.Claims, w http.ResponseWri
 	})
 	if err != nil {
 		log.Printf("Error on creating user wellness ring entry: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
 		log.Printf("Error on marshal the new user wellness ring entry: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -592,7 +1335,7 @@ func (h ApisHandler) DeleteUserRing(claims *tokenauth.Claims, w http.ResponseWri
 	err := h.app.Services.DeleteRing(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on deleting user wellness ring entry with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -616,7 +1359,7 @@ func (h ApisHandler) CreateUserRingHistoryEntry(claims *tokenauth.Claims, w http
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user wellness ring history entry - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
@@ -624,20 +1367,21 @@ func (h ApisHandler) CreateUserRingHistoryEntry(claims *tokenauth.Claims, w http
 	err = json.Unmarshal(data, &historyEntry)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user wellness ring history entry request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
 		return
 	}
 
-	resData, err := h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
+	err = validateRequestBody(historyEntry)
 	if err != nil {
-		log.Printf("Error on creating user wellness ring history entry: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error on validating the user wellness ring history entry request data - %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
-	if resData == nil {
-		log.Printf("Error on creating user wellness ring history entry: %s\n Not found", err)
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	_, err = h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
+	if err != nil {
+		log.Printf("Error on creating user wellness ring history entry: %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -651,14 +1395,14 @@ func (h ApisHandler) CreateUserRingHistoryEntry(claims *tokenauth.Claims, w http
 	})
 	if err != nil {
 		log.Printf("Error on creating user wellness ring history entry: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
 		log.Printf("Error on marshal the new user wellness ring history entry: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -679,23 +1423,17 @@ func (h ApisHandler) DeleteUserRingHistoryEntry(claims *tokenauth.Claims, w http
 	id := vars["id"]
 	historyID := vars["history-id"]
 
-	resData, err := h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
+	_, err := h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
 	if err != nil {
 		log.Printf("Error on deleting user wellness ring history entry: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if resData == nil {
-		log.Printf("Error on deleting user wellness ring history entry: %s\n Not found", err)
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		WriteError(w, r, err)
 		return
 	}
 
 	_, err = h.app.Services.DeleteRingHistory(claims.AppID, claims.OrgID, claims.Subject, id, historyID)
 	if err != nil {
 		log.Printf("Error on deleting user wellness ring history entry with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -725,7 +1463,7 @@ func (h ApisHandler) GetUserAllRingRecords(claims *tokenauth.Claims, w http.Resp
 	resData, err := h.app.Services.GetRingsRecords(claims.AppID, claims.OrgID, claims.Subject, nil, startDateFilter, endDateFilter, offsetFilter, limitFilter, orderFilter)
 	if err != nil {
 		log.Printf("Error on getting user ring records- %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -736,7 +1474,7 @@ func (h ApisHandler) GetUserAllRingRecords(claims *tokenauth.Claims, w http.Resp
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal all user ring records: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -769,7 +1507,7 @@ func (h ApisHandler) GetUserRingRecords(claims *tokenauth.Claims, w http.Respons
 	resData, err := h.app.Services.GetRingsRecords(claims.AppID, claims.OrgID, claims.Subject, &id, startDateFilter, endDateFilter, offsetFilter, limitFilter, orderFilter)
 	if err != nil {
 		log.Printf("Error on getting user ring records- %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -780,7 +1518,7 @@ func (h ApisHandler) GetUserRingRecords(claims *tokenauth.Claims, w http.Respons
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal all user ring records: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -805,14 +1543,14 @@ func (h ApisHandler) GetUserGetUserRingRecord(claims *tokenauth.Claims, w http.R
 	resData, err := h.app.Services.GetRingsRecord(claims.AppID, claims.OrgID, claims.Subject, recordID)
 	if err != nil {
 		log.Printf("Error on getting user ring record by id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal user ring record: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -821,13 +1559,20 @@ func (h ApisHandler) GetUserGetUserRingRecord(claims *tokenauth.Claims, w http.R
 	w.Write(data)
 }
 
+// updateUserRingRecordRequestBody represents the update of an individual daily record as a request body
+type updateUserRingRecordRequestBody struct {
+	ID     string  `json:"id"`
+	RingID string  `json:"ring_id"`
+	Value  float64 `json:"value" validate:"gte=0"`
+} //@name updateUserRingRecordRequestBody
+
 // UpdateUserRingRecord Updates a user ring record with the specified id
 // @Description Updates a user ring record with the specified id
 // @Tags Client-RingsRecords
 // @ID UpdateUserRingRecord
 // @Accept json
 // @Produce json
-// @Param data body model.RingRecord true "body json"
+// @Param data body updateUserRingRecordRequestBody true "body json"
 // @Success 200 {array} model.RingRecord
 // @Security UserAuth
 // @Router /api/user/rings/{id}/records/{record-id} [put]
@@ -839,47 +1584,49 @@ func (h ApisHandler) UpdateUserRingRecord(claims *tokenauth.Claims, w http.Respo
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user ring record - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
-	ring, err := h.app.Services.GetRingsRecord(claims.AppID, claims.OrgID, claims.Subject, recordID)
-	if err != nil || ring == nil {
-		if err != nil {
-			log.Printf("Error on marshal create a user ring record - ring not found %s\n", err)
-		} else {
-			log.Printf("Error on marshal create a user ring record - ring not found\n")
-
-		}
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusBadRequest)
+	_, err = h.app.Services.GetRingsRecord(claims.AppID, claims.OrgID, claims.Subject, recordID)
+	if err != nil {
+		log.Printf("Error on getting the user ring record %s - %s\n", recordID, err)
+		WriteError(w, r, err)
 		return
 	}
 
-	var item model.RingRecord
+	var item updateUserRingRecordRequestBody
 	err = json.Unmarshal(data, &item)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user ring record request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(item)
+	if err != nil {
+		log.Printf("Error on validating the user ring record request data - %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
 	if recordID != item.ID || item.RingID != id {
 		log.Printf("Inconsistent attempt to update query param  and json id are not equal")
-		http.Error(w, "Inconsistent attempt to update query param  and json id are not equal", http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the id in the path and the id in the body are not equal"))
 		return
 	}
 
-	resData, err := h.app.Services.UpdateRingsRecord(claims.AppID, claims.OrgID, claims.Subject, &item)
+	resData, err := h.app.Services.UpdateRingsRecord(claims.AppID, claims.OrgID, claims.Subject, &model.RingRecord{ID: item.ID, RingID: item.RingID, Value: item.Value})
 	if err != nil {
 		log.Printf("Error on updating user ring record with id - %s\n %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
 		log.Printf("Error on marshal the updated user ring record: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -891,7 +1638,7 @@ func (h ApisHandler) UpdateUserRingRecord(claims *tokenauth.Claims, w http.Respo
 // createUserRingRecordRequestBody represents individual daily record for an individual ring as a request body
 type createUserRingRecordRequestBody struct {
 	RingID string  `json:"ring_id" bson:"ring_id"`
-	Value  float64 `json:"value" bson:"value"`
+	Value  float64 `json:"value" bson:"value" validate:"gte=0"`
 } //@name createUserRingRecordRequestBody
 
 // CreateUserRingRecord Creates a user ring record
@@ -910,14 +1657,14 @@ func (h ApisHandler) CreateUserRingRecord(claims *tokenauth.Claims, w http.Respo
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Error on marshal create a user ring record - %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
 		return
 	}
 
-	ring, err := h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
-	if err != nil || ring == nil {
-		log.Printf("Error on marshal create a user ring record - ring not found %s\n", err.Error())
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	_, err = h.app.Services.GetRing(claims.AppID, claims.OrgID, claims.Subject, id)
+	if err != nil {
+		log.Printf("Error on getting the user ring %s - %s\n", id, err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -925,7 +1672,14 @@ func (h ApisHandler) CreateUserRingRecord(claims *tokenauth.Claims, w http.Respo
 	err = json.Unmarshal(data, &item)
 	if err != nil {
 		log.Printf("Error on unmarshal the create user ring record request data - %s\n", err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(item)
+	if err != nil {
+		log.Printf("Error on validating the user ring record request data - %s\n", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -935,7 +1689,7 @@ func (h ApisHandler) CreateUserRingRecord(claims *tokenauth.Claims, w http.Respo
 
 	if item.RingID != id {
 		log.Printf("api.CreateUserRingRecord() - ring id is different")
-		http.Error(w, "api.CreateUserRingRecord() - ring id is different", http.StatusBadRequest)
+		WriteError(w, r, model.NewValidationError("the ring id in the path and the ring id in the body are not equal"))
 		return
 	}
 
@@ -945,14 +1699,14 @@ func (h ApisHandler) CreateUserRingRecord(claims *tokenauth.Claims, w http.Respo
 	})
 	if err != nil {
 		log.Printf("Error on creating user ring record: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
 		log.Printf("Error on marshal the new user ring record: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -972,7 +1726,7 @@ func (h ApisHandler) DeleteAllUserRingRecords(claims *tokenauth.Claims, w http.R
 	err := h.app.Services.DeleteRingsRecords(claims.AppID, claims.OrgID, claims.Subject, nil, nil)
 	if err != nil {
 		log.Printf("Error on deleting all user ring records - %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -994,7 +1748,7 @@ func (h ApisHandler) DeleteUserRingRecords(claims *tokenauth.Claims, w http.Resp
 	err := h.app.Services.DeleteRingsRecords(claims.AppID, claims.OrgID, claims.Subject, &ringID, nil)
 	if err != nil {
 		log.Printf("Error on deleting user ring records with ring_id - %s\n %s", ringID, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -1017,7 +1771,7 @@ func (h ApisHandler) DeleteUserRingRecord(claims *tokenauth.Claims, w http.Respo
 	err := h.app.Services.DeleteRingsRecords(claims.AppID, claims.OrgID, claims.Subject, &ringID, &recordID)
 	if err != nil {
 		log.Printf("Error on deleting user ring record with id - %s\n %s", recordID, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -1047,14 +1801,227 @@ func (h ApisHandler) GetUserData(claims *tokenauth.Claims, w http.ResponseWriter
 	userData, err := h.app.Services.GetUserData(claims.Subject)
 	if err != nil {
 		log.Printf("Error on creating user ring record: %s\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		WriteError(w, r, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(userData)
 	if err != nil {
 		log.Printf("Error on marshal the new user data: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// userPreferencesRequestBody represents the preferences of the user
+type userPreferencesRequestBody struct {
+	Timezone               string                 `json:"timezone" validate:"omitempty,timezone"`
+	Language               string                 `json:"language" validate:"omitempty,bcp47_language_tag"`
+	WeekStartDay           time.Weekday           `json:"week_start_day" validate:"gte=0,lte=6"`
+	DefaultReminderOffsets []int                  `json:"default_reminder_offsets" validate:"max=5,dive,gte=0,lte=10080"`
+	QuietHours             *quietHoursRequestBody `json:"quiet_hours"`
+	NotificationOptOuts    []string               `json:"notification_opt_outs" validate:"dive,oneof=due reminder ring_nudge"`
+	DigestTime             string                 `json:"digest_time" validate:"omitempty,datetime=15:04"`
+} // @name userPreferencesRequestBody
+
+// quietHoursRequestBody represents the quiet hours of the user
+type quietHoursRequestBody struct {
+	Start  string `json:"start" validate:"required,datetime=15:04"`
+	End    string `json:"end" validate:"required,datetime=15:04,nefield=Start"`
+	Action string `json:"action" validate:"required,oneof=shift drop"`
+} // @name quietHoursRequestBody
+
+func (b userPreferencesRequestBody) toUserPreferences() model.UserPreferences {
+	preferences := model.UserPreferences{Timezone: b.Timezone, Language: b.Language, WeekStartDay: b.WeekStartDay,
+		DefaultReminderOffsets: b.DefaultReminderOffsets, NotificationOptOuts: b.NotificationOptOuts, DigestTime: b.DigestTime}
+	if b.QuietHours != nil {
+		preferences.QuietHours = &model.QuietHours{Start: b.QuietHours.Start, End: b.QuietHours.End, Action: b.QuietHours.Action}
+	}
+	return preferences
+}
+
+// GetUserPreferences Retrieves the user preferences
+// @Description Retrieves the preferences of the user. The empty preferences fall back to the app/org config and the defaults.
+// @Tags Client-Preferences
+// @ID GetUserPreferences
+// @Success 200 {object} model.UserPreferences
+// @Security UserAuth
+// @Router /api/user/preferences [get]
+func (h ApisHandler) GetUserPreferences(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	preferences, err := h.app.Services.GetUserPreferences(claims.AppID, claims.OrgID, claims.Subject)
+	if err != nil {
+		log.Printf("Error on getting the user preferences - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeUserPreferences(w, r, preferences)
+}
+
+// UpdateUserPreferences Updates the user preferences
+// @Description Replaces the preferences of the user - the time zone and the language of the reminders, the first day of the week of
+// @Description the rings summaries, the default reminder offsets in minutes before the due time, the quiet hours and the notification opt-outs
+// @Tags Client-Preferences
+// @ID UpdateUserPreferences
+// @Accept json
+// @Param data body userPreferencesRequestBody true "body json"
+// @Success 200 {object} model.UserPreferences
+// @Security UserAuth
+// @Router /api/user/preferences [put]
+func (h ApisHandler) UpdateUserPreferences(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the user preferences - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	var body userPreferencesRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		log.Printf("Error on unmarshal the user preferences request data - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		log.Printf("Error on validating the user preferences request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	preferences, err := h.app.Services.UpdateUserPreferences(claims.AppID, claims.OrgID, claims.Subject, body.toUserPreferences())
+	if err != nil {
+		log.Printf("Error on updating the user preferences - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	h.writeUserPreferences(w, r, preferences)
+}
+
+// DeleteUserPreferences Deletes the user preferences
+// @Description Deletes the preferences of the user, so the app/org config and the defaults are applied
+// @Tags Client-Preferences
+// @ID DeleteUserPreferences
+// @Success 200
+// @Security UserAuth
+// @Router /api/user/preferences [delete]
+func (h ApisHandler) DeleteUserPreferences(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	err := h.app.Services.DeleteUserPreferences(claims.AppID, claims.OrgID, claims.Subject)
+	if err != nil {
+		log.Printf("Error on deleting the user preferences - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h ApisHandler) writeUserPreferences(w http.ResponseWriter, r *http.Request, preferences *model.UserPreferences) {
+	data, err := json.Marshal(preferences)
+	if err != nil {
+		log.Printf("Error on marshal the user preferences: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetUserRingSummary Retrieves the weekly summary of a user wellness ring
+// @Description Retrieves the daily totals of a ring with the goal of every day in the week of the date. The week starts on the day
+// @Description chosen in the user preferences and the days are in the time zone of the user.
+// @Tags Client-Rings
+// @ID GetUserRingSummary
+// @Param id path string true "id"
+// @Param date query string false "any day of the week like 2006-01-02 - today by default"
+// @Success 200 {object} model.RingSummary
+// @Security UserAuth
+// @Router /api/user/rings/{id}/summary [get]
+func (h ApisHandler) GetUserRingSummary(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	date := getStringQueryParam(r, "date")
+
+	summary, err := h.app.Services.GetRingSummary(claims.AppID, claims.OrgID, claims.Subject, id, date)
+	if err != nil {
+		log.Printf("Error on getting the user ring %s summary - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		log.Printf("Error on marshal the user ring summary: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ringNudgesRequestBody turns the evening nudges of a ring on or off
+type ringNudgesRequestBody struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+} // @name ringNudgesRequestBody
+
+// UpdateUserRingNudges Turns the nudges of a user wellness ring on or off
+// @Description Turns on or off the evening nudge sent when the total of the day is behind the goal of the ring. The nudges are sent after
+// @Description the nudge time of the app/org, outside the quiet hours and up to the weekly cap of the app/org.
+// @Tags Client-Rings
+// @ID UpdateUserRingNudges
+// @Accept json
+// @Param id path string true "id"
+// @Param data body ringNudgesRequestBody true "body json"
+// @Success 200 {object} model.Ring
+// @Security UserAuth
+// @Router /api/user/rings/{id}/nudges [put]
+func (h ApisHandler) UpdateUserRingNudges(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Error on reading the ring nudges - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("the request body cannot be read"))
+		return
+	}
+
+	var body ringNudgesRequestBody
+	err = json.Unmarshal(data, &body)
+	if err != nil {
+		log.Printf("Error on unmarshal the ring nudges request data - %s\n", err.Error())
+		WriteError(w, r, model.NewValidationError("invalid request body - "+err.Error()))
+		return
+	}
+
+	err = validateRequestBody(body)
+	if err != nil {
+		log.Printf("Error on validating the ring nudges request data - %s\n", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	ring, err := h.app.Services.UpdateRingNudges(claims.AppID, claims.OrgID, claims.Subject, id, *body.Enabled)
+	if err != nil {
+		log.Printf("Error on updating the user ring %s nudges - %s\n", id, err)
+		WriteError(w, r, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(ring)
+	if err != nil {
+		log.Printf("Error on marshal the user ring: %s", err)
+		WriteError(w, r, err)
 		return
 	}
 
@@ -1063,6 +2030,28 @@ func (h ApisHandler) GetUserData(claims *tokenauth.Claims, w http.ResponseWriter
 	w.Write(jsonData)
 }
 
+// GetConfig Retrieves the wellness settings of the user app/org
+// @Description Retrieves the wellness settings of the user app/org, eg. the backfill window of the rings records and the feature toggles. The empty settings fall back to the defaults.
+// @Tags Client
+// @ID GetConfig
+// @Success 200 {object} model.ConfigSettings
+// @Security UserAuth
+// @Router /api/config [get]
+func (h ApisHandler) GetConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	settings := h.app.Services.GetConfigSettings(claims.AppID, claims.OrgID)
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		log.Printf("Error on marshal the config settings: %s", err)
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // NewApisHandler creates new rest Handler instance
 func NewApisHandler(app *core.Application) ApisHandler {
 	return ApisHandler{app: app}
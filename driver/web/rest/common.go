@@ -15,10 +15,89 @@
 package rest
 
 import (
+	"context"
+	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
+	"wellness/core/model"
+
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	//longer request ids from the callers are replaced as they end up in the logs
+	requestIDMaxLength = 128
 )
 
+type requestIDKey struct{}
+
+var errorStatuses = map[model.ErrorType]int{
+	model.ErrorTypeNotFound:     http.StatusNotFound,
+	model.ErrorTypeValidation:   http.StatusBadRequest,
+	model.ErrorTypeConflict:     http.StatusConflict,
+	model.ErrorTypeForbidden:    http.StatusForbidden,
+	model.ErrorTypeUnauthorized: http.StatusUnauthorized,
+	model.ErrorTypeInternal:     http.StatusInternalServerError,
+}
+
+// WithRequestID gives the request with its id - the one sent by the caller or a new one. The id is sent back in the response header.
+func WithRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	requestID := r.Header.Get(requestIDHeader)
+	if requestID == "" || len(requestID) > requestIDMaxLength {
+		requestID = uuid.NewString()
+	}
+
+	w.Header().Set(requestIDHeader, requestID)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID))
+}
+
+func getRequestID(r *http.Request) string {
+	requestID, _ := r.Context().Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WriteError writes the error response with the status of the error type. The message of the internal errors is not given
+// as it may contain details of the storage or the other building blocks.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	typed := model.AsError(err)
+	if typed == nil || typed.Type == model.ErrorTypeInternal {
+		//keep the details in the logs only, the request id connects them to the response
+		log.Printf("request %s - internal error - %s", getRequestID(r), err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrorTypeInternal,
+			Message: http.StatusText(http.StatusInternalServerError)})
+		return
+	}
+	writeErrorResponse(w, r, errorStatuses[typed.Type], model.ErrorResponse{Code: typed.Type, Message: typed.Message, Fields: typed.Fields})
+}
+
+// WriteErrorStatus writes the error response for a status given by the authorization checks
+func WriteErrorStatus(w http.ResponseWriter, r *http.Request, status int) {
+	errorType := model.ErrorTypeInternal
+	for item, itemStatus := range errorStatuses {
+		if itemStatus == status {
+			errorType = item
+			break
+		}
+	}
+	writeErrorResponse(w, r, status, model.ErrorResponse{Code: errorType, Message: http.StatusText(status)})
+}
+
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, response model.ErrorResponse) {
+	response.RequestID = getRequestID(r)
+	data, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error on marshal the error response: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
 func getStringQueryParam(r *http.Request, paramName string) *string {
 	params, ok := r.URL.Query()[paramName]
 	if ok && len(params[0]) > 0 {
@@ -0,0 +1,128 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"wellness/core/model"
+	"wellness/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+)
+
+var requestValidator = newRequestValidator()
+
+func newRequestValidator() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	//report the fields by their json names
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	validate.RegisterValidation("notblank", validators.NotBlank)
+	return validate
+}
+
+// validateRequestBody validates the request data by its validate tags. It gives a validation error with the invalid fields.
+func validateRequestBody(item interface{}) error {
+	err := requestValidator.Struct(item)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := make([]model.FieldError, len(validationErrors))
+	for i, fieldError := range validationErrors {
+		fields[i] = model.FieldError{Field: fieldPath(fieldError), Message: fieldMessage(fieldError)}
+	}
+	return model.NewFieldsValidationError(fields...)
+}
+
+// applyMergePatch applies the JSON merge patch to the current request data and validates the patched data
+func applyMergePatch(current interface{}, patch []byte, patched interface{}) error {
+	document, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	document, err = utils.ApplyMergePatch(document, patch)
+	if err != nil {
+		return model.NewValidationError("invalid merge patch - " + err.Error())
+	}
+	err = json.Unmarshal(document, patched)
+	if err != nil {
+		return model.NewValidationError("invalid merge patch - " + err.Error())
+	}
+
+	return validateRequestBody(patched)
+}
+
+// fieldPath gives the path of the field without the name of the request data struct, e.g. work_days[1]
+func fieldPath(fieldError validator.FieldError) string {
+	namespace := fieldError.Namespace()
+	if index := strings.Index(namespace, "."); index >= 0 {
+		return namespace[index+1:]
+	}
+	return namespace
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required", "notblank":
+		return "is required"
+	case "hexcolor":
+		return "must be a hex color like #1A2B3C"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldError.Param())
+	case "gte":
+		return fmt.Sprintf("must be %s or greater", fieldError.Param())
+	case "datetime":
+		return fmt.Sprintf("must be a date like %s", fieldError.Param())
+	case "lte":
+		return fmt.Sprintf("must be %s or less", fieldError.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fieldError.Param())
+	case "nefield":
+		return fmt.Sprintf("must differ from %s", fieldError.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s long", fieldError.Param())
+	case "min":
+		return fmt.Sprintf("must be at least %s long", fieldError.Param())
+	case "bcp47_language_tag":
+		return "must be a language tag like es or zh-Hans"
+	case "timezone":
+		return "must be a time zone like America/Chicago"
+	case "required_without":
+		return fmt.Sprintf("is required when %s is not set", fieldError.Param())
+	case "excluded_with":
+		return fmt.Sprintf("must not be set with %s", fieldError.Param())
+	default:
+		return "is not valid"
+	}
+}
@@ -32,12 +32,20 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 	"wellness/core"
 	"wellness/core/model"
 	coreAdapter "wellness/driven/core"
+	"wellness/driven/fakes"
 	"wellness/driven/notifications"
 	storage "wellness/driven/storage"
 	driver "wellness/driver/web"
@@ -48,6 +56,11 @@ import (
 	"github.com/rokwire/rokwire-building-block-sdk-go/utils/logging/logs"
 )
 
+const (
+	//how long the in-flight requests and the running jobs are waited for on shutdown
+	defaultShutdownTimeout = 30 * time.Second
+)
+
 var (
 	// Version : version of this executable
 	Version string
@@ -64,6 +77,9 @@ func main() {
 
 	loggerOpts := logs.LoggerOpts{SuppressRequests: logs.NewStandardHealthCheckHTTPRequestProperties(serviceID + "/version")}
 	loggerOpts.SuppressRequests = append(loggerOpts.SuppressRequests, logs.NewStandardHealthCheckHTTPRequestProperties("wellness/version")...)
+	loggerOpts.SuppressRequests = append(loggerOpts.SuppressRequests, logs.NewStandardHealthCheckHTTPRequestProperties("wellness/health")...)
+	loggerOpts.SuppressRequests = append(loggerOpts.SuppressRequests, logs.NewStandardHealthCheckHTTPRequestProperties("wellness/ready")...)
+	loggerOpts.SuppressRequests = append(loggerOpts.SuppressRequests, logs.NewStandardHealthCheckHTTPRequestProperties("wellness/metrics")...)
 	logger := logs.NewLogger(serviceID, &loggerOpts)
 
 	port := getEnvKey("PORT", true)
@@ -75,19 +91,26 @@ func main() {
 	coreBBHost := getEnvKey("WELLNESS_CORE_BB_HOST", true)
 	serviceURL := getEnvKey("WELLNESS_SERVICE_URL", true)
 
-	//mongoDB adapter
-	mongoDBAuth := getEnvKey("WELLNESS_MONGO_AUTH", true)
-	mongoDBName := getEnvKey("WELLNESS_MONGO_DATABASE", true)
-	mongoTimeout := getEnvKey("WELLNESS_MONGO_TIMEOUT", false)
-	storageAdapter := storage.NewStorageAdapter(mongoDBAuth, mongoDBName, mongoTimeout)
-	err := storageAdapter.Start()
-	if err != nil {
-		log.Fatal("Cannot start the mongoDB adapter - " + err.Error())
-	}
-
 	mtAppID := getEnvKey("WELLNESS_MULTI_TENANCY_APP_ID", true)
 	mtOrgID := getEnvKey("WELLNESS_MULTI_TENANCY_ORG_ID", true)
-	//serviceAccountID := getEnvKey("WELLNESS_SERVICE_ACCOUNT_ID", false)
+
+	var storageAdapter core.Storage
+	if getEnvKey("WELLNESS_STORAGE", false) == "memory" {
+		//in-memory adapter - for local development, the data is lost on restart
+		log.Println("Using the in-memory storage")
+		storageAdapter = storage.NewMemoryAdapter()
+	} else {
+		//mongoDB adapter
+		mongoDBAuth := getEnvKey("WELLNESS_MONGO_AUTH", true)
+		mongoDBName := getEnvKey("WELLNESS_MONGO_DATABASE", true)
+		mongoTimeout := getEnvKey("WELLNESS_MONGO_TIMEOUT", false)
+		mongoAdapter := storage.NewStorageAdapter(mongoDBAuth, mongoDBName, mongoTimeout, mtAppID, mtOrgID, logger)
+		err := mongoAdapter.Start()
+		if err != nil {
+			log.Fatal("Cannot start the mongoDB adapter - " + err.Error())
+		}
+		storageAdapter = mongoAdapter
+	}
 
 	authService := auth.Service{
 		ServiceID:   serviceID,
@@ -96,52 +119,116 @@ func main() {
 		AuthBaseURL: coreBBHost,
 	}
 
-	serviceRegLoader, err := auth.NewRemoteServiceRegLoader(&authService, []string{"rewards"})
-	if err != nil {
-		log.Fatalf("Error initializing remote service registration loader: %v", err)
-	}
+	var serviceRegManager *auth.ServiceRegManager
+	var coreBB core.Core
+	var notificationsBB core.Notifications
+	var fakesHandler http.Handler
+	if getEnvKey("WELLNESS_FAKE_BBS", false) == "true" {
+		//in-process stand-ins for the Core and Notifications BBs - for local development and tests, nothing calls the Core BB
+		var deletedMemberships []model.DeletedUserData
+		deletedMembershipsRaw := getEnvKey("WELLNESS_FAKE_DELETED_MEMBERSHIPS", false)
+		if deletedMembershipsRaw != "" {
+			err := json.Unmarshal([]byte(deletedMembershipsRaw), &deletedMemberships)
+			if err != nil {
+				log.Fatalf("Error parsing the fake deleted memberships: %v", err)
+			}
+		}
 
-	serviceRegManager, err := auth.NewServiceRegManager(&authService, serviceRegLoader, true)
-	if err != nil {
-		log.Fatalf("Error initializing service registration manager: %v", err)
-	}
+		log.Println("Using the fake Core and Notifications BBs")
+		fakeAuth, err := fakes.NewAuthLoader(serviceID, serviceURL, coreBBHost)
+		if err != nil {
+			log.Fatalf("Error initializing the fake auth service: %v", err)
+		}
+		serviceRegManager, err = auth.NewServiceRegManager(&authService, fakeAuth, false)
+		if err != nil {
+			log.Fatalf("Error initializing service registration manager: %v", err)
+		}
 
-	serviceAccountID := getEnvKey("WELLNESS_SERVICE_ACCOUNT_ID", false)
-	privKeyRaw := getEnvKey("WELLNESS_PRIV_KEY", true)
+		fakeCore := fakes.NewCoreAdapter(deletedMemberships)
+		fakeNotifications := fakes.NewNotificationsAdapter()
+		coreBB = fakeCore
+		notificationsBB = fakeNotifications
+		fakesHandler = fakes.NewHandler(fakeNotifications, fakeCore, fakeAuth)
+	} else {
+		serviceRegLoader, err := auth.NewRemoteServiceRegLoader(&authService, []string{"rewards"})
+		if err != nil {
+			log.Fatalf("Error initializing remote service registration loader: %v", err)
+		}
 
-	privKeyRaw = strings.ReplaceAll(privKeyRaw, "\\n", "\n")
-	privKeyObj, err := keys.NewPrivKey(keys.RS256, privKeyRaw)
-	if err != nil {
-		log.Fatalf("Error parsing priv key: %v", err)
-	}
+		serviceRegManager, err = auth.NewServiceRegManager(&authService, serviceRegLoader, true)
+		if err != nil {
+			log.Fatalf("Error initializing service registration manager: %v", err)
+		}
 
-	signatureAuth, err := sigauth.NewSignatureAuth(privKeyObj, serviceRegManager, false, true)
-	if err != nil {
-		log.Fatalf("Error initializing signature auth: %v", err)
+		serviceAccountID := getEnvKey("WELLNESS_SERVICE_ACCOUNT_ID", false)
+		privKeyRaw := getEnvKey("WELLNESS_PRIV_KEY", true)
+
+		privKeyRaw = strings.ReplaceAll(privKeyRaw, "\\n", "\n")
+		privKeyObj, err := keys.NewPrivKey(keys.RS256, privKeyRaw)
+		if err != nil {
+			log.Fatalf("Error parsing priv key: %v", err)
+		}
+
+		signatureAuth, err := sigauth.NewSignatureAuth(privKeyObj, serviceRegManager, false, true)
+		if err != nil {
+			log.Fatalf("Error initializing signature auth: %v", err)
+		}
+
+		serviceAccountLoader, err := auth.NewRemoteServiceAccountLoader(&authService, serviceAccountID, signatureAuth)
+		if err != nil {
+			log.Fatalf("Error initializing remote service account loader: %v", err)
+		}
+
+		serviceAccountManager, err := auth.NewServiceAccountManager(&authService, serviceAccountLoader)
+		if err != nil {
+			log.Fatalf("Error initializing service account manager: %v", err)
+		}
+
+		// Core adapter
+		coreBB = coreAdapter.NewCoreAdapter(coreBBHost, serviceAccountManager)
+
+		// Notification adapter
+		notificationsBaseURL := getEnvKey("NOTIFICATIONS_BASE_URL", true)
+		notificationsBB = notifications.NewNotificationsAdapter(notificationsBaseURL, notificationsBaseURL, serviceAccountManager, mtAppID, mtOrgID)
 	}
 
-	serviceAccountLoader, err := auth.NewRemoteServiceAccountLoader(&authService, serviceAccountID, signatureAuth)
-	if err != nil {
-		log.Fatalf("Error initializing remote service account loader: %v", err)
+	// delete data logic
+	deleteDataConfig := model.DeleteDataConfig{
+		Schedule: getEnvKey("WELLNESS_DELETE_DATA_SCHEDULE", false),
+		Timezone: getEnvKey("WELLNESS_DELETE_DATA_TIMEZONE", false),
+		DryRun:   getEnvKey("WELLNESS_DELETE_DATA_DRY_RUN", false) == "true",
+	}
+	if deleteDataConfig.Schedule == "" {
+		deleteDataConfig.Schedule = "0 4 * * *"
+	}
+	if deleteDataConfig.Timezone == "" {
+		deleteDataConfig.Timezone = "America/Chicago"
 	}
 
-	serviceAccountManager, err := auth.NewServiceAccountManager(&authService, serviceAccountLoader)
-	if err != nil {
-		log.Fatalf("Error initializing service account manager: %v", err)
+	// daily digest
+	digestConfig := model.DigestConfig{Schedule: getEnvKey("WELLNESS_DIGEST_SCHEDULE", false)}
+	if digestConfig.Schedule == "" {
+		digestConfig.Schedule = "*/5 * * * *"
 	}
 
-	// Core adapter
-	coreAdapter := coreAdapter.NewCoreAdapter(coreBBHost, serviceAccountManager)
+	// ring nudges
+	ringNudgesConfig := model.RingNudgesConfig{Schedule: getEnvKey("WELLNESS_RING_NUDGES_SCHEDULE", false)}
+	if ringNudgesConfig.Schedule == "" {
+		ringNudgesConfig.Schedule = "*/15 * * * *"
+	}
 
-	// Notification adapter
-	notificationsBaseURL := getEnvKey("NOTIFICATIONS_BASE_URL", true)
-	notificationsAdapter := notifications.NewNotificationsAdapter(notificationsBaseURL, notificationsBaseURL, serviceAccountManager, mtAppID, mtOrgID)
-	if err != nil {
-		log.Fatalf("Error initializing notification adapter: %v", err)
+	// overdue todo entries sweeper
+	overdueConfig := model.OverdueConfig{Schedule: getEnvKey("WELLNESS_OVERDUE_SCHEDULE", false)}
+	if overdueConfig.Schedule == "" {
+		overdueConfig.Schedule = "*/5 * * * *"
 	}
 
+	migrationsDryRun := getEnvKey("WELLNESS_MIGRATIONS_DRY_RUN", false) == "true"
+	readinessProbeBBs := getEnvKey("WELLNESS_READINESS_PROBE_BBS", false) == "true"
+
 	// application
-	application := core.NewApplication(Version, Build, logger, storageAdapter, coreAdapter, notificationsAdapter, mtAppID, mtOrgID)
+	application := core.NewApplication(Version, Build, logger, storageAdapter, coreBB, notificationsBB, mtAppID, mtOrgID,
+		deleteDataConfig, digestConfig, ringNudgesConfig, overdueConfig, migrationsDryRun, readinessProbeBBs)
 	application.Start()
 
 	config := model.Config{
@@ -150,9 +237,43 @@ func main() {
 		InternalAPIKey: internalAPIKey,
 	}
 
-	webAdapter := driver.NewWebAdapter(host, port, application, config, serviceRegManager)
+	webAdapter := driver.NewWebAdapter(host, port, application, config, serviceRegManager, fakesHandler)
 
-	webAdapter.Start()
+	shutdownTimeout := defaultShutdownTimeout
+	shutdownTimeoutValue := getEnvKey("WELLNESS_SHUTDOWN_TIMEOUT", false)
+	if shutdownTimeoutValue != "" {
+		seconds, err := strconv.Atoi(shutdownTimeoutValue)
+		if err != nil || seconds <= 0 {
+			log.Fatalf("Invalid WELLNESS_SHUTDOWN_TIMEOUT value %s - it must be a positive number of seconds", shutdownTimeoutValue)
+		}
+		shutdownTimeout = time.Duration(seconds) * time.Second
+	}
+
+	go webAdapter.Start()
+
+	//wait for the termination signal - the deploys stop the old instances with SIGTERM
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-signalCtx.Done()
+	stopSignals()
+
+	logger.Infof("Shutting down, waiting up to %s", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	//drain the in-flight requests first, then the background jobs and finally release the database
+	err := webAdapter.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Errorf("error on shutting down the web server - %s", err)
+	}
+	err = application.Stop(shutdownCtx)
+	if err != nil {
+		logger.Errorf("error on stopping the application - %s", err)
+	}
+	err = storageAdapter.Stop(shutdownCtx)
+	if err != nil {
+		logger.Errorf("error on stopping the storage - %s", err)
+	}
+	logger.Info("Shut down")
 }
 
 func getEnvKeyAsList(key string, required bool) []string {
@@ -174,7 +295,7 @@ func getEnvKey(key string, required bool) string {
 		if required {
 			log.Fatal("No provided environment variable for " + key)
 		} else {
-			log.Printf("No provided environment variable for " + key)
+			log.Printf("No provided environment variable for %s", key)
 		}
 	}
 	return value
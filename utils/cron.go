@@ -0,0 +1,214 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dstWindow is the distance from a wall clock time within which a time zone transition changes its offset
+const dstWindow = 3 * time.Hour
+
+// CronSchedule represents a parsed cron-like expression in the standard five fields format:
+// minute hour day-of-month month day-of-week
+//
+// Every field supports "*", single values, ranges ("1-5"), steps ("*/15", "0-30/10") and lists ("1,15").
+// As in cron, when both day-of-month and day-of-week are restricted a day matching either of them is accepted.
+type CronSchedule struct {
+	expression string
+
+	minutes     [60]bool
+	hours       [24]bool
+	daysOfMonth [32]bool
+	months      [13]bool
+	daysOfWeek  [7]bool
+
+	daysOfMonthAny bool
+	daysOfWeekAny  bool
+}
+
+// ParseCronExpression parses a cron-like expression
+func ParseCronExpression(expression string) (*CronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expression, len(fields))
+	}
+
+	schedule := CronSchedule{expression: expression, daysOfMonthAny: fields[2] == "*", daysOfWeekAny: fields[4] == "*"}
+	err := parseCronField(fields[0], 0, 59, schedule.minutes[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid minute field: %s", err)
+	}
+	err = parseCronField(fields[1], 0, 23, schedule.hours[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hour field: %s", err)
+	}
+	err = parseCronField(fields[2], 1, 31, schedule.daysOfMonth[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %s", err)
+	}
+	err = parseCronField(fields[3], 1, 12, schedule.months[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid month field: %s", err)
+	}
+
+	//7 is accepted as Sunday too
+	var daysOfWeek [8]bool
+	err = parseCronField(fields[4], 0, 7, daysOfWeek[:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %s", err)
+	}
+	copy(schedule.daysOfWeek[:], daysOfWeek[:7])
+	schedule.daysOfWeek[0] = schedule.daysOfWeek[0] || daysOfWeek[7]
+
+	return &schedule, nil
+}
+
+// String gives the source expression
+func (s *CronSchedule) String() string {
+	return s.expression
+}
+
+// Next gives the first moment after t which matches the schedule. The result is in the location of t.
+// Zero time is returned when there is no such moment in the next five years (eg. "0 0 30 2 *").
+//
+// The schedule is matched against the wall clock of the location. A local time repeated when the clocks go back
+// matches once, at its first occurrence. A local time skipped when the clocks go forward matches right after the
+// change, moved by the length of the gap - eg. 2:30 is 3:30 on the day the clocks go from 2:00 to 3:00.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	//the wall clock times are kept in UTC, so every day has 24 hours
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	yearLimit := wall.Year() + 5
+	for wall.Year() <= yearLimit {
+		if !s.months[wall.Month()] {
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(wall) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.hours[wall.Hour()] {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.minutes[wall.Minute()] {
+			wall = wall.Add(time.Minute)
+			continue
+		}
+
+		//the first occurrence of a repeated local time may be before t
+		next := localTime(wall, loc)
+		if next.After(t) {
+			return next
+		}
+		wall = wall.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// localTime gives the moment of the wall clock time in the location - the first one when the time is repeated, and the
+// moment moved by the length of the gap when the time is skipped
+func localTime(wall time.Time, loc *time.Location) time.Time {
+	//the offsets of the location around the time, they differ only close to a transition
+	approximate := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+	_, offsetBefore := approximate.Add(-dstWindow).Zone()
+	_, offsetAfter := approximate.Add(dstWindow).Zone()
+
+	var valid []time.Time
+	candidates := []time.Time{
+		wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc),
+		wall.Add(-time.Duration(offsetAfter) * time.Second).In(loc),
+	}
+	for _, candidate := range candidates {
+		if candidate.Hour() == wall.Hour() && candidate.Minute() == wall.Minute() {
+			valid = append(valid, candidate)
+		}
+	}
+
+	switch {
+	case len(valid) == 2 && valid[1].Before(valid[0]):
+		return valid[1]
+	case len(valid) > 0:
+		return valid[0]
+	case candidates[1].After(candidates[0]):
+		return candidates[1]
+	default:
+		return candidates[0]
+	}
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.daysOfMonth[t.Day()]
+	dayOfWeek := s.daysOfWeek[t.Weekday()]
+
+	if s.daysOfMonthAny || s.daysOfWeekAny {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func parseCronField(field string, min int, max int, values []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart := part
+		step := 1
+		if index := strings.Index(part, "/"); index >= 0 {
+			rangePart = part[:index]
+			var err error
+			step, err = strconv.Atoi(part[index+1:])
+			if err != nil || step <= 0 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		start, end := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return fmt.Errorf("invalid range in %q", part)
+			}
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return fmt.Errorf("invalid range in %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return nil
+}
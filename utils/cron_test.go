@@ -0,0 +1,128 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{"every minute", "* * * * *", false},
+		{"steps", "*/15 */2 * * *", false},
+		{"range with step", "0-30/10 9-17 * * *", false},
+		{"lists", "0,30 8,20 1,15 1,7 *", false},
+		{"sunday as 7", "0 8 * * 7", false},
+		{"weekdays range up to 7", "0 8 * * 5-7", false},
+		{"extra spaces", " 0  8 * * 1 ", false},
+		{"too few fields", "* * * *", true},
+		{"too many fields", "* * * * * *", true},
+		{"minute out of range", "60 * * * *", true},
+		{"hour out of range", "* 24 * * *", true},
+		{"day of month zero", "* * 0 * *", true},
+		{"day of month out of range", "* * 32 * *", true},
+		{"month out of range", "* * * 13 *", true},
+		{"day of week out of range", "* * * * 8", true},
+		{"zero step", "*/0 * * * *", true},
+		{"negative step", "*/-5 * * * *", true},
+		{"reversed range", "30-10 * * * *", true},
+		{"not a number", "a * * * *", true},
+		{"incomplete range", "1- * * * *", true},
+		{"empty list item", "1,,2 * * * *", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCronExpression(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCronExpression(%q) error = %v, wantErr %v", tt.expression, err, tt.wantErr)
+			}
+			if err == nil && schedule.String() != tt.expression {
+				t.Errorf("String() = %q, want %q", schedule.String(), tt.expression)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("error loading the time zone - %s", err)
+	}
+	utc := func(year int, month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		from       time.Time
+		want       time.Time
+	}{
+		{"step", "*/15 * * * *", utc(2026, 1, 1, 10, 7).Add(30 * time.Second), utc(2026, 1, 1, 10, 15)},
+		{"strictly after a match", "*/15 * * * *", utc(2026, 1, 1, 10, 15), utc(2026, 1, 1, 10, 30)},
+		{"range with step", "0-30/10 9-17 * * *", utc(2026, 1, 1, 9, 5), utc(2026, 1, 1, 9, 10)},
+		{"range with step - next hour", "0-30/10 9-17 * * *", utc(2026, 1, 1, 9, 30), utc(2026, 1, 1, 10, 0)},
+		{"range with step - next day", "0-30/10 9-17 * * *", utc(2026, 1, 1, 17, 31), utc(2026, 1, 2, 9, 0)},
+		{"weekdays range", "0 9 * * 1-5", utc(2026, 1, 2, 10, 0), utc(2026, 1, 5, 9, 0)},
+		{"list of days of month", "0 0 1,15 * *", utc(2026, 1, 2, 0, 0), utc(2026, 1, 15, 0, 0)},
+		{"month step", "0 0 1 */3 *", utc(2026, 2, 10, 0, 0), utc(2026, 4, 1, 0, 0)},
+		{"next year", "0 0 1 1 *", utc(2026, 12, 31, 23, 59), utc(2027, 1, 1, 0, 0)},
+		{"31st skips the short months", "0 0 31 * *", utc(2026, 4, 1, 0, 0), utc(2026, 5, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", utc(2026, 3, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+		{"day of month or day of week - the day of week first", "0 0 13 * 5", utc(2026, 1, 1, 0, 0), utc(2026, 1, 2, 0, 0)},
+		{"day of month or day of week - the day of month first", "0 0 13 * 5", utc(2026, 1, 9, 12, 0), utc(2026, 1, 13, 0, 0)},
+		{"only day of week", "0 0 * * 5", utc(2026, 1, 9, 12, 0), utc(2026, 1, 16, 0, 0)},
+		{"only day of month", "0 0 13 * *", utc(2026, 1, 1, 0, 0), utc(2026, 1, 13, 0, 0)},
+		{"day of month and every day of week", "0 0 13 * *", utc(2026, 1, 13, 0, 0), utc(2026, 2, 13, 0, 0)},
+		{"sunday as 7", "0 8 * * 7", utc(2026, 1, 1, 0, 0), utc(2026, 1, 4, 8, 0)},
+		{"sunday as 0", "0 8 * * 0", utc(2026, 1, 1, 0, 0), utc(2026, 1, 4, 8, 0)},
+		{"range ending with 7", "0 8 * * 5-7", utc(2026, 1, 3, 9, 0), utc(2026, 1, 4, 8, 0)},
+		{"impossible date", "0 0 30 2 *", utc(2026, 1, 1, 0, 0), time.Time{}},
+		{"impossible dates list", "0 0 31 4,6,9,11 *", utc(2026, 1, 1, 0, 0), time.Time{}},
+
+		//the clocks go from 2:00 CST to 3:00 CDT on 2026-03-08
+		{"skipped local time runs after the gap", "30 2 * * *", time.Date(2026, 3, 7, 3, 0, 0, 0, chicago), utc(2026, 3, 8, 8, 30)},
+		{"local time after a skipped one", "30 2 * * *", utc(2026, 3, 8, 8, 30).In(chicago), utc(2026, 3, 9, 7, 30)},
+		{"step into the gap", "*/30 * * * *", time.Date(2026, 3, 8, 1, 45, 0, 0, chicago), utc(2026, 3, 8, 8, 0)},
+		{"hourly after the gap", "0 * * * *", utc(2026, 3, 8, 8, 0).In(chicago), utc(2026, 3, 8, 9, 0)},
+
+		//the clocks go from 2:00 CDT back to 1:00 CST on 2026-11-01
+		{"repeated local time runs at the first occurrence", "30 1 * * *", time.Date(2026, 11, 1, 0, 0, 0, 0, chicago), utc(2026, 11, 1, 6, 30)},
+		{"repeated local time runs once", "30 1 * * *", utc(2026, 11, 1, 6, 30).In(chicago), utc(2026, 11, 2, 7, 30)},
+		{"hourly over the repeated hour", "0 * * * *", utc(2026, 11, 1, 6, 0).In(chicago), utc(2026, 11, 1, 8, 0)},
+		{"step in the second occurrence", "*/15 * * * *", utc(2026, 11, 1, 7, 40).In(chicago), utc(2026, 11, 1, 8, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCronExpression(tt.expression)
+			if err != nil {
+				t.Fatalf("ParseCronExpression(%q) error = %v", tt.expression, err)
+			}
+
+			got := schedule.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want.In(tt.from.Location()))
+			}
+			if !got.IsZero() && got.Location() != tt.from.Location() {
+				t.Errorf("Next(%s) is in %s, want %s", tt.from, got.Location(), tt.from.Location())
+			}
+		})
+	}
+}
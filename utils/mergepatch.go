@@ -0,0 +1,69 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ApplyMergePatch applies a JSON merge patch (RFC 7396) to a JSON object. The fields set to null in the patch are removed,
+// the objects are merged recursively and every other value replaces the current one.
+func ApplyMergePatch(document []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	err := decodeJSON(document, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	var patchValue interface{}
+	err = decodeJSON(patch, &patchValue)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := patchValue.(map[string]interface{}); !ok {
+		return nil, errors.New("the merge patch must be a JSON object")
+	}
+
+	return json.Marshal(mergePatchValue(target, patchValue))
+}
+
+func mergePatchValue(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatchValue(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// decodeJSON keeps the numbers as they are so the patched document does not lose precision
+func decodeJSON(data []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(value)
+}
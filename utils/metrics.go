@@ -0,0 +1,119 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	//MetricsResultSuccess the observed operation passed
+	MetricsResultSuccess string = "success"
+	//MetricsResultFailure the observed operation failed
+	MetricsResultFailure string = "failure"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wellness",
+		Name:      "http_requests_total",
+		Help:      "The number of the handled API requests by route, method and response status.",
+	}, []string{"route", "method", "status"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "wellness",
+		Name:      "http_request_duration_seconds",
+		Help:      "The latency of the handled API requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	storageOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "wellness",
+		Name:      "storage_operation_duration_seconds",
+		Help:      "The latency of the database operations by collection and operation.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "operation"})
+	storageOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wellness",
+		Name:      "storage_operation_errors_total",
+		Help:      "The number of the failed database operations by collection and operation.",
+	}, []string{"collection", "operation"})
+
+	notificationOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wellness",
+		Name:      "notification_operations_total",
+		Help:      "The number of the Notifications BB calls by operation (send or delete) and result.",
+	}, []string{"operation", "result"})
+
+	deleteDataRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wellness",
+		Name:      "delete_data_runs_total",
+		Help:      "The number of the deleted users data processings of an app/org by result.",
+	}, []string{"result"})
+	deleteDataDocuments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wellness",
+		Name:      "delete_data_documents_total",
+		Help:      "The number of the deleted documents of the deleted users by collection.",
+	}, []string{"collection"})
+)
+
+// MetricsHandler gives the handler exposing the metrics in the Prometheus format
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest records a handled API request. The route is the path template, so the ids do not make separate series.
+func ObserveHTTPRequest(route string, method string, status int, started time.Time) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(route, method).Observe(time.Since(started).Seconds())
+}
+
+// ObserveStorageOperation records a database operation
+func ObserveStorageOperation(collection string, operation string, started time.Time, err error) {
+	storageOperationDuration.WithLabelValues(collection, operation).Observe(time.Since(started).Seconds())
+	if err != nil {
+		storageOperationErrors.WithLabelValues(collection, operation).Inc()
+	}
+}
+
+// ObserveNotificationOperation records a call to the Notifications BB
+func ObserveNotificationOperation(operation string, err error) {
+	notificationOperations.WithLabelValues(operation, metricsResult(err)).Inc()
+}
+
+// ObserveDeleteDataRun records the deleted users data processing of an app/org with the deleted documents by collection
+func ObserveDeleteDataRun(deletedCounts map[string]int64, failed bool) {
+	result := MetricsResultSuccess
+	if failed {
+		result = MetricsResultFailure
+	}
+	deleteDataRuns.WithLabelValues(result).Inc()
+
+	for collection, count := range deletedCounts {
+		deleteDataDocuments.WithLabelValues(collection).Add(float64(count))
+	}
+}
+
+func metricsResult(err error) string {
+	if err != nil {
+		return MetricsResultFailure
+	}
+	return MetricsResultSuccess
+}